@@ -0,0 +1,67 @@
+// Command loadgen drives the user service with a configurable read/write mix
+// and reports throughput and per-operation latency percentiles.
+//
+// By default it runs against an in-process service; with -url it targets a
+// running REST server instead (see -http-addr on the main command, whose
+// default rate limits will show up as rate_limited errors).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"property-based/internal/client/httpclient"
+	"property-based/internal/loadgen"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+)
+
+func main() {
+	cfg := loadgen.DefaultConfig
+	flag.DurationVar(&cfg.Duration, "duration", cfg.Duration, "how long to generate load (0 means until -requests operations have run)")
+	flag.IntVar(&cfg.Requests, "requests", 0, "stop after this many operations in total (0 means run for -duration)")
+	flag.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "number of concurrent workers")
+	flag.IntVar(&cfg.SeedUsers, "seed-users", cfg.SeedUsers, "users created before measuring starts")
+	flag.IntVar(&cfg.WritePercent, "writes", cfg.WritePercent, "percentage of operations that create, update or delete (0-100)")
+	flag.Int64Var(&cfg.RandSeed, "seed", cfg.RandSeed, "random seed for the operation mix")
+	repo := flag.String("repo", "inmemory", "in-process repository: inmemory or sharded")
+	url := flag.String("url", "", "base URL of a running REST server to target instead of an in-process service")
+	token := flag.String("token", "", "bearer token sent to the REST server given by -url (an admin token, since the mix writes)")
+	flag.Parse()
+
+	if cfg.WritePercent < 0 || cfg.WritePercent > 100 {
+		fail(fmt.Errorf("invalid -writes %d (want 0-100)", cfg.WritePercent))
+	}
+
+	var target loadgen.Target
+	if *url != "" {
+		clientCfg := httpclient.DefaultConfig
+		clientCfg.Token = *token
+		target = httpclient.New(*url, clientCfg)
+	} else {
+		var r repository.UserRepository
+		switch *repo {
+		case "inmemory":
+			r = repository.NewInMemoryUserRepository()
+		case "sharded":
+			r = repository.NewShardedUserRepository(repository.DefaultShardCount)
+		default:
+			fail(fmt.Errorf("invalid -repo %q (want inmemory or sharded)", *repo))
+		}
+		target = service.NewUserService(r)
+	}
+
+	report, err := loadgen.Run(target, cfg)
+	if err != nil {
+		fail(err)
+	}
+	if err := report.WriteText(os.Stdout); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "loadgen:", err)
+	os.Exit(1)
+}
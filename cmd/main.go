@@ -1,56 +1,266 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
+	"property-based/internal/auth"
+	"property-based/internal/domain"
+	"property-based/internal/metrics"
 	"property-based/internal/repository"
 	"property-based/internal/service"
+	"property-based/internal/transport/httpserver"
+	"property-based/internal/transport/rpcserver"
 )
 
 func main() {
-	repo := repository.NewInMemoryUserRepository()
-	svc := service.NewUserService(repo)
+	logLevel := flag.String("log-level", "info", "minimum log level: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "log format: text or json")
+	redactEmail := flag.String("log-redact-email", service.DefaultRedactionPolicy.Email.String(), "how emails appear in logs: hide, mask or none")
+	redactName := flag.String("log-redact-name", service.DefaultRedactionPolicy.Name.String(), "how names appear in logs: hide, mask or none")
+	metricsAddr := flag.String("metrics-addr", "", "serve Prometheus metrics at http://<addr>/metrics after the demo (e.g. :9090)")
+	httpAddr := flag.String("http-addr", "", "serve the user REST API at http://<addr>/users after the demo (e.g. :8080)")
+	rpcAddr := flag.String("rpc-addr", "", "serve the user service over JSON-RPC at <addr> after the demo (e.g. :9091)")
+	restorePath := flag.String("restore", "", "load users from a snapshot file at startup instead of running the demo")
+	policyPath := flag.String("validation-policy", "", "load user validation rules from this JSON file; omitted fields keep their defaults")
+	tokensPath := flag.String("tokens", "", "JSON file mapping bearer tokens to principals; required to serve the REST API or JSON-RPC")
+	backupPath := flag.String("backup", "", "write a snapshot of all users to this file on exit (after the demo, or on SIGINT/SIGTERM when serving)")
+	flag.Parse()
 
+	logger, err := newLogger(*logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	var policy service.RedactionPolicy
+	if policy.Email, err = service.ParseRedaction(*redactEmail); err != nil {
+		fatal("Invalid -log-redact-email", err)
+	}
+	if policy.Name, err = service.ParseRedaction(*redactName); err != nil {
+		fatal("Invalid -log-redact-name", err)
+	}
+
+	validation := domain.DefaultValidationPolicy
+	if *policyPath != "" {
+		if validation, err = loadValidationPolicy(*policyPath); err != nil {
+			fatal("Error loading validation policy", err)
+		}
+		slog.Info("Loaded validation policy", "path", *policyPath)
+	}
+
+	var authenticator *auth.TokenAuthenticator
+	if *tokensPath != "" {
+		if authenticator, err = loadTokens(*tokensPath); err != nil {
+			fatal("Error loading tokens", err)
+		}
+		slog.Info("Loaded tokens", "path", *tokensPath)
+	} else if *httpAddr != "" || *rpcAddr != "" {
+		fatal("Invalid flags", errors.New("-http-addr and -rpc-addr require -tokens"))
+	}
+
+	store := repository.NewInMemoryUserRepository()
+	if *restorePath != "" {
+		if err := restore(store, *restorePath); err != nil {
+			fatal("Error restoring snapshot", err)
+		}
+		slog.Info("Restored snapshot", "path", *restorePath, "users", store.Count())
+	}
+
+	reg := metrics.NewRegistry()
+	repo := repository.NewInstrumentedUserRepository(
+		store,
+		metrics.NewOperations(reg, "user_repository"),
+	)
+	svc := service.NewLoggingUserService(
+		service.NewInstrumentedUserService(
+			service.NewUserService(repo, service.WithValidationPolicy(validation)),
+			metrics.NewOperations(reg, "user_service"),
+		),
+		logger,
+		policy,
+	)
+
+	if *restorePath == "" {
+		runDemo(svc)
+	}
+
+	if *httpAddr != "" {
+		slog.Info("Serving REST API", "addr", *httpAddr, "path", "/users")
+		go func() {
+			creator := service.NewIdempotentCreator(svc, repository.NewInMemoryIdempotencyStore(), service.DefaultIdempotencyConfig)
+			handler := httpserver.NewHandler(svc,
+				httpserver.WithAuthenticator(authenticator),
+				httpserver.WithIdempotency(creator),
+				httpserver.WithRateLimits(service.NewRateLimits(service.DefaultRateLimitConfig), nil),
+				httpserver.WithChangeFeed(store),
+			)
+			fatal("REST server stopped", http.ListenAndServe(*httpAddr, handler))
+		}()
+	}
+
+	if *rpcAddr != "" {
+		l, err := net.Listen("tcp", *rpcAddr)
+		if err != nil {
+			fatal("Error listening for JSON-RPC", err)
+		}
+		slog.Info("Serving JSON-RPC", "addr", l.Addr().String(), "service", rpcserver.ServiceName)
+		go func() {
+			fatal("JSON-RPC server stopped", rpcserver.Serve(rpcserver.NewServer(svc, rpcserver.WithAuthenticator(authenticator)), l))
+		}()
+	}
+
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", reg)
+		slog.Info("Serving metrics", "addr", *metricsAddr, "path", "/metrics")
+		go func() {
+			fatal("Metrics server stopped", http.ListenAndServe(*metricsAddr, mux))
+		}()
+	}
+	if *httpAddr != "" || *rpcAddr != "" || *metricsAddr != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		<-ctx.Done()
+		stop()
+	}
+
+	if *backupPath != "" {
+		snap := store.Snapshot()
+		if err := backup(snap, *backupPath); err != nil {
+			fatal("Error writing snapshot", err)
+		}
+		slog.Info("Wrote snapshot", "path", *backupPath, "users", snap.Len())
+	}
+}
+
+func runDemo(svc service.UserOperations) {
 	user1, err := svc.CreateUser("John Doe", "john@example.com", 30)
 	if err != nil {
-		log.Fatalf("Error creating user1: %v", err)
+		fatal("Error creating user1", err)
 	}
-	fmt.Printf("Created user: %+v\n", user1)
 	user2, err := svc.CreateUser("Jane Smith", "jane@example.com", 25)
 	if err != nil {
-		log.Fatalf("Error creating user2: %v", err)
+		fatal("Error creating user2", err)
+	}
+
+	if _, err := svc.GetAllUsers(); err != nil {
+		fatal("Error getting users", err)
+	}
+
+	if _, err := svc.GetUserByEmail("john@example.com"); err != nil {
+		fatal("Error finding user", err)
+	}
+
+	if _, err := svc.UpdateUser(user1.ID, "John Updated", "john.updated@example.com", 31); err != nil {
+		fatal("Error updating user", err)
+	}
+
+	if err := svc.DeleteUser(user2.ID); err != nil {
+		fatal("Error deleting user", err)
+	}
+
+	svc.CountUsers()
+}
+
+func loadValidationPolicy(path string) (*domain.ValidationPolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return domain.LoadValidationPolicy(f)
+}
+
+func loadTokens(path string) (*auth.TokenAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
-	fmt.Printf("Created user: %+v\n", user2)
+	defer f.Close()
+	return auth.LoadTokens(f)
+}
 
-	users, err := svc.GetAllUsers()
+func restore(store *repository.InMemoryUserRepository, path string) error {
+	f, err := os.Open(path)
 	if err != nil {
-		log.Fatalf("Error getting users: %v", err)
+		return err
 	}
+	defer f.Close()
 
-	fmt.Println("All users:")
-	for _, u := range users {
-		fmt.Printf("  - %s (%s)\n", u.Name, u.Email)
+	snap, err := repository.ReadSnapshot(f)
+	if err != nil {
+		return err
 	}
+	return store.Restore(snap)
+}
 
-	found, err := svc.GetUserByEmail("john@example.com")
+// backup writes through a temporary file in the same directory so that an
+// interrupted write never replaces a good snapshot with a partial one. The
+// file is synced before the rename and the directory after it, so that a
+// crash cannot leave the new name pointing at data that never reached disk.
+func backup(snap *repository.Snapshot, path string) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
 	if err != nil {
-		log.Fatalf("Error finding user: %v", err)
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := snap.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
 	}
-	fmt.Printf("Found user by email: %+v\n", found)
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
 
-	updated, err := svc.UpdateUser(user1.ID, "John Updated", "john.updated@example.com", 31)
+// syncDir makes a rename in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
 	if err != nil {
-		log.Fatalf("Error updating user: %v", err)
+		return err
 	}
-	fmt.Printf("Updated user: %+v\n", updated)
+	defer d.Close()
+	return d.Sync()
+}
 
-	if err := svc.DeleteUser(user2.ID); err != nil {
-		log.Fatalf("Error deleting user: %v", err)
+func newLogger(level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid -log-level %q: %w", level, err)
 	}
-	fmt.Println("User deleted successfully")
 
-	count := svc.CountUsers()
-	fmt.Printf("Final user count: %d\n", count)
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid -log-format %q (want text or json)", format)
+	}
+}
 
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"property-based/internal/domain"
+)
+
+type Authenticator interface {
+	Authenticate(token string) (Principal, error)
+}
+
+// TokenAuthenticator maps opaque bearer tokens to principals.
+type TokenAuthenticator struct {
+	mu     sync.RWMutex
+	tokens map[string]Principal
+}
+
+func NewTokenAuthenticator() *TokenAuthenticator {
+	return &TokenAuthenticator{tokens: make(map[string]Principal)}
+}
+
+func (a *TokenAuthenticator) Register(token string, p Principal) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.tokens[token] = p
+}
+
+func (a *TokenAuthenticator) Revoke(token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.tokens, token)
+}
+
+func (a *TokenAuthenticator) Authenticate(token string) (Principal, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if token == "" {
+		return Principal{}, domain.ErrUnauthenticated
+	}
+	for known, p := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			return p, nil
+		}
+	}
+	return Principal{}, domain.ErrUnauthenticated
+}
+
+// LoadTokens reads a JSON object mapping bearer tokens to principals into a
+// TokenAuthenticator:
+//
+//	{"<token>": {"role": "admin"}, "<token>": {"role": "user", "user_id": "<id>"}}
+//
+// Empty tokens, unknown roles and user principals without a user ID are
+// errors.
+func LoadTokens(r io.Reader) (*TokenAuthenticator, error) {
+	var tokens map[string]Principal
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("invalid tokens: %w", err)
+	}
+
+	a := NewTokenAuthenticator()
+	for token, p := range tokens {
+		switch {
+		case token == "":
+			return nil, fmt.Errorf("invalid tokens: empty token")
+		case !p.Role.valid():
+			return nil, fmt.Errorf("invalid tokens: unknown role %q", p.Role)
+		case p.Role == RoleUser && p.UserID == "":
+			return nil, fmt.Errorf("invalid tokens: role %q needs a user_id", p.Role)
+		}
+		a.Register(token, p)
+	}
+	return a, nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"property-based/internal/domain"
+)
+
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleUser    Role = "user"
+	RoleAuditor Role = "auditor"
+)
+
+// Principal is the authenticated caller. UserID links self-service callers
+// to their own user record.
+type Principal struct {
+	UserID string `json:"user_id,omitempty"`
+	Role   Role   `json:"role"`
+}
+
+func (r Role) valid() bool {
+	return r == RoleAdmin || r == RoleUser || r == RoleAuditor
+}
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionRead   Action = "read"
+	ActionList   Action = "list"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Authorize decides whether p may perform action on the user identified by
+// targetID (empty for actions that do not target a single user).
+//
+//	admin:   everything
+//	auditor: read and list
+//	user:    read and update their own record only
+func Authorize(p Principal, action Action, targetID string) error {
+	switch p.Role {
+	case RoleAdmin:
+		return nil
+	case RoleAuditor:
+		if action == ActionRead || action == ActionList {
+			return nil
+		}
+	case RoleUser:
+		if (action == ActionRead || action == ActionUpdate) && targetID != "" && targetID == p.UserID {
+			return nil
+		}
+	}
+	return domain.ErrForbidden
+}
@@ -0,0 +1,276 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"property-based/internal/domain"
+	"property-based/internal/transport/httpserver"
+)
+
+type Config struct {
+	HTTPClient *http.Client
+	// MaxRetries is how many times an idempotent call is retried after a
+	// transport error or a 5xx response. Zero disables retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry; it doubles on each
+	// further attempt.
+	RetryBackoff time.Duration
+	Sleep        func(time.Duration)
+	// Locale is sent as Accept-Language so that APIError messages come back
+	// in that language. Empty leaves the choice to the server.
+	Locale domain.Locale
+	// Token is sent as a bearer token in the Authorization header, for
+	// servers built with httpserver.WithAuthenticator. Empty sends none.
+	Token string
+}
+
+var DefaultConfig = Config{
+	MaxRetries:   2,
+	RetryBackoff: 50 * time.Millisecond,
+}
+
+// APIError is a non-2xx response. It unwraps to the domain sentinel for
+// Code, so errors.Is(err, domain.ErrNotFound) works as it does in-process.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	// RetryAfter is taken from the Retry-After header of 429 and 503
+	// responses; zero when absent.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return domain.ErrorForCode(e.Code)
+}
+
+// Client mirrors the UserService method set over the REST API served by
+// httpserver.Handler. GET and PUT calls are retried per Config; POST, PATCH
+// and DELETE are not, since repeating them after a lost response would
+// create a duplicate or report a spurious error. CreateUserWithKey is the
+// exception: the idempotency key makes its POST safe to retry.
+type Client struct {
+	baseURL string
+	cfg     Config
+}
+
+func New(baseURL string, cfg Config) *Client {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Sleep == nil {
+		cfg.Sleep = time.Sleep
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), cfg: cfg}
+}
+
+func (c *Client) CreateUser(name, email string, age int) (*domain.User, error) {
+	return c.CreateUserWithAttributes(name, email, age, nil)
+}
+
+func (c *Client) CreateUserWithAttributes(name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+	var user httpserver.User
+	req := httpserver.CreateUserRequestFromDomain(name, email, age, attrs)
+	if err := c.do(http.MethodPost, "/users", req, &user); err != nil {
+		return nil, err
+	}
+	return user.Domain(), nil
+}
+
+// CreateUserWithKey creates a user under an idempotency key, retrying like
+// an idempotent call. The server must be built with
+// httpserver.WithIdempotency for the key to have any effect.
+func (c *Client) CreateUserWithKey(key, name, email string, age int) (*domain.User, error) {
+	return c.CreateUserWithKeyAndAttributes(key, name, email, age, nil)
+}
+
+// CreateUserWithKeyAndAttributes is CreateUserWithKey for a user that starts
+// with custom attributes; a retry must send the same ones.
+func (c *Client) CreateUserWithKeyAndAttributes(key, name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+	var user httpserver.User
+	req := httpserver.CreateUserRequestFromDomain(name, email, age, attrs)
+	if err := c.send(http.MethodPost, "/users", key, req, &user); err != nil {
+		return nil, err
+	}
+	return user.Domain(), nil
+}
+
+func (c *Client) GetUser(id string) (*domain.User, error) {
+	var user httpserver.User
+	if err := c.do(http.MethodGet, "/users/"+url.PathEscape(id), nil, &user); err != nil {
+		return nil, err
+	}
+	return user.Domain(), nil
+}
+
+func (c *Client) GetUserByEmail(email string) (*domain.User, error) {
+	var user httpserver.User
+	if err := c.do(http.MethodGet, "/users/by-email/"+url.PathEscape(email), nil, &user); err != nil {
+		return nil, err
+	}
+	return user.Domain(), nil
+}
+
+func (c *Client) GetAllUsers() ([]*domain.User, error) {
+	return c.listUsers("/users")
+}
+
+// FindUsersByAttributes lists the users matching every filter.
+func (c *Client) FindUsersByAttributes(filters ...domain.AttributeFilter) ([]*domain.User, error) {
+	query := url.Values{}
+	for _, f := range filters {
+		query.Add("attribute", f.String())
+	}
+	return c.listUsers("/users?" + query.Encode())
+}
+
+func (c *Client) listUsers(path string) ([]*domain.User, error) {
+	var resp httpserver.UserListResponse
+	if err := c.do(http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	users := make([]*domain.User, 0, len(resp.Users))
+	for _, u := range resp.Users {
+		users = append(users, u.Domain())
+	}
+	return users, nil
+}
+
+func (c *Client) UpdateUser(id, name, email string, age int) (*domain.User, error) {
+	var user httpserver.User
+	req := httpserver.UpdateUserRequest{Name: name, Email: email, Age: age}
+	if err := c.do(http.MethodPut, "/users/"+url.PathEscape(id), req, &user); err != nil {
+		return nil, err
+	}
+	return user.Domain(), nil
+}
+
+func (c *Client) PatchUser(id string, patch domain.UserPatch) (*domain.User, error) {
+	var user httpserver.User
+	req := httpserver.PatchUserRequestFromDomain(patch)
+	if err := c.do(http.MethodPatch, "/users/"+url.PathEscape(id), req, &user); err != nil {
+		return nil, err
+	}
+	return user.Domain(), nil
+}
+
+func (c *Client) DeleteUser(id string) error {
+	return c.do(http.MethodDelete, "/users/"+url.PathEscape(id), nil, nil)
+}
+
+// CountUsers reads GET /users/count, retried like any other GET. On failure
+// it returns 0 with the error of the last attempt.
+func (c *Client) CountUsers() (int, error) {
+	var resp httpserver.CountResponse
+	if err := c.do(http.MethodGet, "/users/count", nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut
+}
+
+func (c *Client) do(method, path string, body, dst any) error {
+	return c.send(method, path, "", body, dst)
+}
+
+// send performs a call, retrying it per Config when the method is idempotent
+// or an idempotency key is given.
+func (c *Client) send(method, path, key string, body, dst any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	attempts := 1
+	if idempotent(method) || key != "" {
+		attempts += max(c.cfg.MaxRetries, 0)
+	}
+	backoff := c.cfg.RetryBackoff
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			c.cfg.Sleep(backoff)
+			backoff *= 2
+		}
+		var retry bool
+		retry, err = c.once(method, path, key, payload, dst)
+		if !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// once performs a single request and reports whether a failure is worth
+// retrying.
+func (c *Client) once(method, path, key string, payload []byte, dst any) (bool, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.Locale != "" {
+		req.Header.Set(httpserver.AcceptLanguageHeader, string(c.cfg.Locale))
+	}
+	if key != "" {
+		req.Header.Set(httpserver.IdempotencyKeyHeader, key)
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if dst == nil {
+			return false, nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+			return false, fmt.Errorf("decode %s %s response: %w", method, path, err)
+		}
+		return false, nil
+	}
+	return resp.StatusCode >= 500, decodeError(resp)
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Code: domain.CodeInternal}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body httpserver.ErrorResponse
+	if err := json.Unmarshal(raw, &body); err != nil || body.Code == "" {
+		apiErr.Message = strings.TrimSpace(string(raw))
+		return apiErr
+	}
+	apiErr.Code, apiErr.Message = body.Code, body.Message
+	return apiErr
+}
@@ -0,0 +1,121 @@
+package rpcclient
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+
+	"property-based/internal/domain"
+	"property-based/internal/transport/rpcserver"
+)
+
+// Client is a typed JSON-RPC client for the user service. Domain errors
+// returned by the server come back as *rpcserver.RemoteError, which unwraps
+// to the matching sentinel, so errors.Is(err, domain.ErrNotFound) works as
+// it does in-process. Transport failures are returned unchanged.
+type Client struct {
+	rpc  *rpc.Client
+	auth rpcserver.Auth
+}
+
+type Option func(*Client)
+
+// WithToken sends token with every call, for servers that require
+// authentication.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.auth.Token = token
+	}
+}
+
+// Dial connects to a JSON-RPC user service at address.
+func Dial(network, address string, opts ...Option) (*Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, opts...), nil
+}
+
+// NewClient speaks JSON-RPC over an established connection.
+func NewClient(conn io.ReadWriteCloser, opts ...Option) *Client {
+	c := &Client{rpc: rpc.NewClientWithCodec(jsonrpc.NewClientCodec(conn))}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+func (c *Client) CreateUser(name, email string, age int) (*domain.User, error) {
+	return c.CreateUserWithAttributes(name, email, age, nil)
+}
+
+func (c *Client) CreateUserWithAttributes(name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+	var reply rpcserver.UserReply
+	err := c.call("CreateUser", rpcserver.CreateUserArgs{Auth: c.auth, Name: name, Email: email, Age: age, Attributes: attrs}, &reply)
+	return reply.User, err
+}
+
+func (c *Client) GetUser(id string) (*domain.User, error) {
+	var reply rpcserver.UserReply
+	err := c.call("GetUser", rpcserver.GetUserArgs{Auth: c.auth, ID: id}, &reply)
+	return reply.User, err
+}
+
+func (c *Client) GetUserByEmail(email string) (*domain.User, error) {
+	var reply rpcserver.UserReply
+	err := c.call("GetUserByEmail", rpcserver.GetUserByEmailArgs{Auth: c.auth, Email: email}, &reply)
+	return reply.User, err
+}
+
+func (c *Client) GetAllUsers() ([]*domain.User, error) {
+	var reply rpcserver.UsersReply
+	err := c.call("GetAllUsers", rpcserver.GetAllUsersArgs{Auth: c.auth}, &reply)
+	return reply.Users, err
+}
+
+// FindUsersByAttributes lists the users matching every filter.
+func (c *Client) FindUsersByAttributes(filters ...domain.AttributeFilter) ([]*domain.User, error) {
+	var reply rpcserver.UsersReply
+	err := c.call("FindUsersByAttributes", rpcserver.FindUsersByAttributesArgs{Auth: c.auth, Filters: filters}, &reply)
+	return reply.Users, err
+}
+
+func (c *Client) UpdateUser(id, name, email string, age int) (*domain.User, error) {
+	var reply rpcserver.UserReply
+	err := c.call("UpdateUser", rpcserver.UpdateUserArgs{Auth: c.auth, ID: id, Name: name, Email: email, Age: age}, &reply)
+	return reply.User, err
+}
+
+func (c *Client) PatchUser(id string, patch domain.UserPatch) (*domain.User, error) {
+	var reply rpcserver.UserReply
+	err := c.call("PatchUser", rpcserver.PatchUserArgs{Auth: c.auth, ID: id, Patch: patch}, &reply)
+	return reply.User, err
+}
+
+func (c *Client) DeleteUser(id string) error {
+	return c.call("DeleteUser", rpcserver.DeleteUserArgs{Auth: c.auth, ID: id}, &rpcserver.DeleteUserReply{})
+}
+
+// CountUsers returns 0 with the error when the connection or the server
+// fails the call.
+func (c *Client) CountUsers() (int, error) {
+	var reply rpcserver.CountUsersReply
+	err := c.call("CountUsers", rpcserver.CountUsersArgs{Auth: c.auth}, &reply)
+	return reply.Count, err
+}
+
+func (c *Client) call(method string, args, reply any) error {
+	err := c.rpc.Call(rpcserver.ServiceName+"."+method, args, reply)
+	var serverErr rpc.ServerError
+	if errors.As(err, &serverErr) {
+		return rpcserver.ParseRemoteError(string(serverErr))
+	}
+	return err
+}
@@ -0,0 +1,388 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttributeType is the type a deployment declares for a custom attribute.
+type AttributeType string
+
+const (
+	AttributeString AttributeType = "string"
+	AttributeNumber AttributeType = "number"
+	AttributeBool   AttributeType = "bool"
+	AttributeDate   AttributeType = "date"
+)
+
+// AttributeDateLayout is how dates are written in JSON and filters.
+const AttributeDateLayout = "2006-01-02"
+
+func (t AttributeType) valid() bool {
+	switch t {
+	case AttributeString, AttributeNumber, AttributeBool, AttributeDate:
+		return true
+	}
+	return false
+}
+
+// AttributeValue is the value of one custom attribute together with its
+// type. The zero value has no type and is not a valid attribute. Values are
+// immutable, so copying one copies it deeply.
+type AttributeValue struct {
+	typ AttributeType
+	str string
+	num float64
+	b   bool
+	// date is midnight UTC of the day.
+	date time.Time
+}
+
+func StringAttribute(s string) AttributeValue {
+	return AttributeValue{typ: AttributeString, str: s}
+}
+
+func NumberAttribute(n float64) AttributeValue {
+	return AttributeValue{typ: AttributeNumber, num: n}
+}
+
+func BoolAttribute(b bool) AttributeValue {
+	return AttributeValue{typ: AttributeBool, b: b}
+}
+
+// DateAttribute keeps only the calendar day of t, taken in UTC.
+func DateAttribute(t time.Time) AttributeValue {
+	y, m, d := t.UTC().Date()
+	return AttributeValue{typ: AttributeDate, date: time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseAttribute builds a value of type t from its JSON form as decoded into
+// an any: a string, a float64, a bool, or a string in AttributeDateLayout.
+// Numbers must be finite.
+func ParseAttribute(t AttributeType, v any) (AttributeValue, error) {
+	switch t {
+	case AttributeString:
+		if s, ok := v.(string); ok {
+			return StringAttribute(s), nil
+		}
+	case AttributeNumber:
+		if n, ok := v.(float64); ok && !math.IsNaN(n) && !math.IsInf(n, 0) {
+			return NumberAttribute(n), nil
+		}
+	case AttributeBool:
+		if b, ok := v.(bool); ok {
+			return BoolAttribute(b), nil
+		}
+	case AttributeDate:
+		if s, ok := v.(string); ok {
+			if d, err := time.Parse(AttributeDateLayout, s); err == nil {
+				return DateAttribute(d), nil
+			}
+		}
+	default:
+		return AttributeValue{}, fmt.Errorf("unknown attribute type %q", t)
+	}
+	return AttributeValue{}, fmt.Errorf("%v is not a valid %s attribute value", v, t)
+}
+
+// parseAttributeText reads s as a value of type t, the way values are
+// written in filters.
+func parseAttributeText(t AttributeType, s string) (AttributeValue, error) {
+	switch t {
+	case AttributeNumber:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return AttributeValue{}, err
+		}
+		return ParseAttribute(t, n)
+	case AttributeBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return AttributeValue{}, err
+		}
+		return BoolAttribute(b), nil
+	}
+	return ParseAttribute(t, s)
+}
+
+func (v AttributeValue) Type() AttributeType {
+	return v.typ
+}
+
+// Value returns the JSON form of v: a string, a float64, a bool, or for
+// dates a string in AttributeDateLayout. ParseAttribute(v.Type(), v.Value())
+// gives v back.
+func (v AttributeValue) Value() any {
+	switch v.typ {
+	case AttributeString:
+		return v.str
+	case AttributeNumber:
+		return v.num
+	case AttributeBool:
+		return v.b
+	case AttributeDate:
+		return v.date.Format(AttributeDateLayout)
+	}
+	return nil
+}
+
+// String is the text form used in filters and history diffs.
+func (v AttributeValue) String() string {
+	switch v.typ {
+	case AttributeNumber:
+		return strconv.FormatFloat(v.num, 'g', -1, 64)
+	case AttributeBool:
+		return strconv.FormatBool(v.b)
+	case AttributeDate:
+		return v.date.Format(AttributeDateLayout)
+	}
+	return v.str
+}
+
+func (v AttributeValue) Equal(o AttributeValue) bool {
+	return v.typ == o.typ && v.str == o.str && v.num == o.num && v.b == o.b && v.date.Equal(o.date)
+}
+
+// compare orders two values of the same type: numbers and dates by
+// magnitude, strings bytewise and false before true.
+func (v AttributeValue) compare(o AttributeValue) int {
+	switch v.typ {
+	case AttributeNumber:
+		return cmpOrdered(v.num, o.num)
+	case AttributeBool:
+		return cmpOrdered(boolRank(v.b), boolRank(o.b))
+	case AttributeDate:
+		return v.date.Compare(o.date)
+	}
+	return strings.Compare(v.str, o.str)
+}
+
+func cmpOrdered[T int | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type attributeJSON struct {
+	Type  AttributeType `json:"type"`
+	Value any           `json:"value"`
+}
+
+// MarshalJSON writes v with its type, {"type": "date", "value":
+// "2026-01-31"}, so that it decodes to the same value without a schema.
+func (v AttributeValue) MarshalJSON() ([]byte, error) {
+	if !v.typ.valid() {
+		return nil, fmt.Errorf("attribute value has no type")
+	}
+	return json.Marshal(attributeJSON{Type: v.typ, Value: v.Value()})
+}
+
+func (v *AttributeValue) UnmarshalJSON(data []byte) error {
+	var raw attributeJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := ParseAttribute(raw.Type, raw.Value)
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// Attributes are the custom attributes of a user by name. A nil map and an
+// empty one are equivalent.
+type Attributes map[string]AttributeValue
+
+// Clone copies a; the values themselves are immutable.
+func (a Attributes) Clone() Attributes {
+	if len(a) == 0 {
+		return nil
+	}
+	c := make(Attributes, len(a))
+	for name, v := range a {
+		c[name] = v
+	}
+	return c
+}
+
+func (a Attributes) Equal(o Attributes) bool {
+	if len(a) != len(o) {
+		return false
+	}
+	for name, v := range a {
+		if w, ok := o[name]; !ok || !v.Equal(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// Names returns the attribute names in ascending order.
+func (a Attributes) Names() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var attributeName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// AttributeSchema declares, per deployment, the custom attributes users may
+// have and their types. Attributes are optional; undeclared ones are
+// rejected.
+type AttributeSchema map[string]AttributeType
+
+func (s AttributeSchema) check() error {
+	for name, t := range s {
+		if !attributeName.MatchString(name) {
+			return fmt.Errorf("attribute name %q must match %s", name, attributeName)
+		}
+		if !t.valid() {
+			return fmt.Errorf("attribute %q has unknown type %q", name, t)
+		}
+	}
+	return nil
+}
+
+// Validate checks every attribute in a against the schema, in name order so
+// that the reported one is deterministic.
+func (s AttributeSchema) Validate(a Attributes) error {
+	for _, name := range a.Names() {
+		declared, ok := s[name]
+		if !ok || a[name].Type() != declared {
+			return &AttributeError{Name: name, Type: declared, err: ErrInvalidAttribute}
+		}
+	}
+	return nil
+}
+
+// AttributeError reports an attribute that is not declared in the schema
+// (Type is empty) or whose value is not of the declared Type. It matches
+// ErrInvalidAttribute under errors.Is.
+type AttributeError struct {
+	Name string
+	Type AttributeType
+
+	err error
+}
+
+func (e *AttributeError) Error() string {
+	return e.message(DefaultLocale)
+}
+
+func (e *AttributeError) message(loc Locale) string {
+	if e.Type == "" {
+		return fmt.Sprintf(attributeMessages[loc].undeclared, e.Name)
+	}
+	return fmt.Sprintf(attributeMessages[loc].mistyped, e.Name, e.Type)
+}
+
+func (e *AttributeError) Unwrap() error {
+	return e.err
+}
+
+// FilterOp compares an attribute with the value of an AttributeFilter.
+type FilterOp string
+
+const (
+	FilterExists       FilterOp = ""
+	FilterEqual        FilterOp = "="
+	FilterNotEqual     FilterOp = "!="
+	FilterLess         FilterOp = "<"
+	FilterLessEqual    FilterOp = "<="
+	FilterGreater      FilterOp = ">"
+	FilterGreaterEqual FilterOp = ">="
+)
+
+// filterOps is in matching order: two-character operators first.
+var filterOps = []FilterOp{FilterNotEqual, FilterLessEqual, FilterGreaterEqual, FilterEqual, FilterLess, FilterGreater}
+
+// AttributeFilter selects users by one custom attribute. Value is read with
+// the type of each user's own attribute, so filters need no schema: a user
+// without the attribute, or whose attribute cannot be compared with Value,
+// does not match.
+type AttributeFilter struct {
+	Name  string
+	Op    FilterOp
+	Value string
+}
+
+// ParseAttributeFilter reads the String form of a filter: a bare name
+// ("beta") matches users that have the attribute, and "name<op>value" with
+// op one of = != < <= > >= compares it ("seats>=10", "renewal<2027-01-01").
+func ParseAttributeFilter(s string) (AttributeFilter, error) {
+	end := strings.IndexAny(s, "=!<>")
+	if end < 0 {
+		end = len(s)
+	}
+	f := AttributeFilter{Name: s[:end]}
+	if !attributeName.MatchString(f.Name) {
+		return AttributeFilter{}, fmt.Errorf("attribute filter %q: name must match %s", s, attributeName)
+	}
+	rest := s[end:]
+	if rest == "" {
+		return f, nil
+	}
+	for _, op := range filterOps {
+		if value, ok := strings.CutPrefix(rest, string(op)); ok {
+			f.Op, f.Value = op, value
+			return f, nil
+		}
+	}
+	return AttributeFilter{}, fmt.Errorf("attribute filter %q: unknown operator", s)
+}
+
+func (f AttributeFilter) String() string {
+	return f.Name + string(f.Op) + f.Value
+}
+
+func (f AttributeFilter) Matches(u *User) bool {
+	v, ok := u.Attributes[f.Name]
+	if !ok {
+		return false
+	}
+	if f.Op == FilterExists {
+		return true
+	}
+	want, err := parseAttributeText(v.Type(), f.Value)
+	if err != nil {
+		return false
+	}
+
+	c := v.compare(want)
+	switch f.Op {
+	case FilterEqual:
+		return c == 0
+	case FilterNotEqual:
+		return c != 0
+	case FilterLess:
+		return c < 0
+	case FilterLessEqual:
+		return c <= 0
+	case FilterGreater:
+		return c > 0
+	case FilterGreaterEqual:
+		return c >= 0
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+type ChangeOp string
+
+const (
+	ChangeCreated ChangeOp = "created"
+	ChangeUpdated ChangeOp = "updated"
+	ChangeDeleted ChangeOp = "deleted"
+)
+
+// UserChange is one committed write, as seen by watchers. Seq numbers are
+// assigned in commit order, start at 1 and never repeat. For deletions User
+// is the last known state.
+type UserChange struct {
+	Seq  uint64
+	Op   ChangeOp
+	At   time.Time
+	User *User
+}
+
+func (c UserChange) Clone() UserChange {
+	c.User = c.User.Clone()
+	return c
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Credential is kept apart from User so password hashes never travel with
+// the user records returned by the service.
+type Credential struct {
+	UserID         string
+	PasswordHash   string
+	FailedAttempts int
+	LockedUntil    time.Time
+	UpdatedAt      time.Time
+}
+
+const (
+	PasswordMinLength = 8
+	PasswordMaxLength = 128
+)
+
+// ValidatePassword requires PasswordMinLength to PasswordMaxLength runes with
+// at least one letter and one digit. Failures are *ValidationError values
+// matching ErrWeakPassword.
+func ValidatePassword(password string) error {
+	if n := utf8.RuneCountInString(password); n < PasswordMinLength || n > PasswordMaxLength {
+		return passwordError()
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		hasLetter = hasLetter || unicode.IsLetter(r)
+		hasDigit = hasDigit || unicode.IsDigit(r)
+	}
+	if !hasLetter || !hasDigit {
+		return passwordError()
+	}
+
+	return nil
+}
+
+func passwordError() error {
+	return &ValidationError{Field: FieldPassword, Min: PasswordMinLength, Max: PasswordMaxLength, err: ErrWeakPassword}
+}
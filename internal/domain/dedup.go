@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// providersIgnoringDots are mailbox providers that deliver "j.doe" and
+// "jdoe" to the same inbox. googlemail.com is an alias of gmail.com.
+var providersIgnoringDots = map[string]string{
+	"gmail.com":      "gmail.com",
+	"googlemail.com": "gmail.com",
+}
+
+// NormalizeEmail returns the mailbox an address delivers to, for duplicate
+// detection only: lower-cased, without a "+tag" suffix, and without dots in
+// the local part for providers that ignore them. It is never stored.
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	local, host, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+	local, _, _ = strings.Cut(local, "+")
+	if canonical, ok := providersIgnoringDots[host]; ok {
+		local = strings.ReplaceAll(local, ".", "")
+		host = canonical
+	}
+	return local + "@" + host
+}
+
+// NormalizeName lower-cases name and collapses runs of spaces.
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// NameSimilarity scores two names between 0 and 1 as one minus their edit
+// distance over the longer normalized name, so "Jon Doe" and "John Doe"
+// score 0.875. Identical names score 1.
+func NameSimilarity(a, b string) float64 {
+	a, b = NormalizeName(a), NormalizeName(b)
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+// editDistance is the Levenshtein distance between a and b in bytes; names
+// are ASCII letters and spaces.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// MergeRecord is the audit entry left by merging Merged into Kept. Both
+// users are kept as they were just before the merge; Result is the
+// surviving user afterwards.
+type MergeRecord struct {
+	KeptID   string
+	MergedID string
+	Kept     *User
+	Merged   *User
+	Result   *User
+	MergedAt time.Time
+}
+
+func (r MergeRecord) Clone() MergeRecord {
+	r.Kept = r.Kept.Clone()
+	r.Merged = r.Merged.Clone()
+	r.Result = r.Result.Clone()
+	return r
+}
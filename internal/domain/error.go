@@ -1,14 +1,144 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
+// The validation sentinels do not state any bound: the rule that was broken
+// travels in a *ValidationError that wraps them.
 var (
-	ErrInvalidUserName  = errors.New("user name must be between 2 and 50 characters and contain only letters and spaces")
-	ErrInvalidUserEmail = errors.New("user email must be a valid email address")
-	ErrInvalidUserAge   = errors.New("user age must be between 0 and 150")
+	ErrInvalidUserName  = errors.New("invalid user name")
+	ErrInvalidUserEmail = errors.New("invalid user email")
+	ErrInvalidUserAge   = errors.New("invalid user age")
 )
 
 var (
 	ErrNotFound      = errors.New("entity not found")
 	ErrAlreadyExists = errors.New("entity already exists")
+	ErrInvalidTenant = errors.New("tenant id must not be empty")
+)
+
+var (
+	ErrUnauthenticated = errors.New("caller is not authenticated")
+	ErrForbidden       = errors.New("operation not permitted for caller")
+)
+
+var (
+	ErrWeakPassword       = errors.New("password is too weak")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account temporarily locked after repeated failed logins")
+)
+
+var (
+	ErrInvalidToken         = errors.New("verification token is invalid or already used")
+	ErrTokenExpired         = errors.New("verification token has expired")
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+)
+
+var (
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+	ErrRateLimited          = errors.New("rate limit exceeded")
 )
+
+var ErrChangesUnavailable = errors.New("requested changes are no longer retained; resubscribe from the current sequence")
+
+var ErrInvalidMerge = errors.New("a user cannot be merged into itself")
+
+var ErrInvalidAttribute = errors.New("invalid custom attribute")
+
+// RateLimitError is returned when a caller runs out of budget. It matches
+// ErrRateLimited under errors.Is and says when the next call may succeed.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return Message(e, DefaultLocale)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+const (
+	CodeInvalidUserName  = "invalid_user_name"
+	CodeInvalidUserEmail = "invalid_user_email"
+	CodeInvalidUserAge   = "invalid_user_age"
+	CodeNotFound         = "not_found"
+	CodeAlreadyExists    = "already_exists"
+	CodeInvalidTenant    = "invalid_tenant"
+	CodeUnauthenticated  = "unauthenticated"
+	CodeForbidden        = "forbidden"
+	CodeWeakPassword     = "weak_password"
+	CodeInvalidCreds     = "invalid_credentials"
+	CodeAccountLocked    = "account_locked"
+	CodeInvalidToken     = "invalid_token"
+	CodeTokenExpired     = "token_expired"
+	CodeAlreadyVerified  = "email_already_verified"
+	CodeIdempotencyKey   = "idempotency_key_reused"
+	CodeRateLimited      = "rate_limited"
+	CodeChangesGone      = "changes_unavailable"
+	CodeInvalidMerge     = "invalid_merge"
+	CodeInvalidAttribute = "invalid_attribute"
+	CodeInternal         = "internal"
+)
+
+var errorCodes = []struct {
+	code string
+	err  error
+}{
+	{CodeInvalidUserName, ErrInvalidUserName},
+	{CodeInvalidUserEmail, ErrInvalidUserEmail},
+	{CodeInvalidUserAge, ErrInvalidUserAge},
+	{CodeNotFound, ErrNotFound},
+	{CodeAlreadyExists, ErrAlreadyExists},
+	{CodeInvalidTenant, ErrInvalidTenant},
+	{CodeUnauthenticated, ErrUnauthenticated},
+	{CodeForbidden, ErrForbidden},
+	{CodeWeakPassword, ErrWeakPassword},
+	{CodeInvalidCreds, ErrInvalidCredentials},
+	{CodeAccountLocked, ErrAccountLocked},
+	{CodeInvalidToken, ErrInvalidToken},
+	{CodeTokenExpired, ErrTokenExpired},
+	{CodeAlreadyVerified, ErrEmailAlreadyVerified},
+	{CodeIdempotencyKey, ErrIdempotencyKeyReused},
+	{CodeRateLimited, ErrRateLimited},
+	{CodeChangesGone, ErrChangesUnavailable},
+	{CodeInvalidMerge, ErrInvalidMerge},
+	{CodeInvalidAttribute, ErrInvalidAttribute},
+}
+
+// ErrorCode returns a stable, machine-readable code for err. Errors that are
+// not domain errors map to CodeInternal; nil maps to "".
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return CodeInternal
+}
+
+// ErrorCodes lists the code of every domain error, CodeInternal excluded.
+func ErrorCodes() []string {
+	codes := make([]string, len(errorCodes))
+	for i, c := range errorCodes {
+		codes[i] = c.code
+	}
+	return codes
+}
+
+// ErrorForCode is the inverse of ErrorCode: it returns the sentinel error
+// for a stable code, or nil when the code is unknown or CodeInternal.
+func ErrorForCode(code string) error {
+	for _, c := range errorCodes {
+		if c.code == code {
+			return c.err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"strconv"
+	"time"
+)
+
+// UserVersion is one entry in a user's change history. Deleted versions are
+// tombstones that keep the last known state of the user.
+type UserVersion struct {
+	Version    int
+	RecordedAt time.Time
+	Deleted    bool
+	User       *User
+}
+
+func (v UserVersion) Clone() UserVersion {
+	v.User = v.User.Clone()
+	return v
+}
+
+type FieldChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+func DiffVersions(from, to UserVersion) []FieldChange {
+	var changes []FieldChange
+	add := func(field, a, b string) {
+		if a != b {
+			changes = append(changes, FieldChange{Field: field, From: a, To: b})
+		}
+	}
+
+	add("name", from.User.Name, to.User.Name)
+	add("email", from.User.Email, to.User.Email)
+	add("email_verified", strconv.FormatBool(from.User.EmailVerified), strconv.FormatBool(to.User.EmailVerified))
+	add("age", strconv.Itoa(from.User.Age), strconv.Itoa(to.User.Age))
+	for _, name := range attributeNames(from.User.Attributes, to.User.Attributes) {
+		var a, b string
+		if v, ok := from.User.Attributes[name]; ok {
+			a = v.String()
+		}
+		if v, ok := to.User.Attributes[name]; ok {
+			b = v.String()
+		}
+		add("attributes."+name, a, b)
+	}
+	add("deleted", strconv.FormatBool(from.Deleted), strconv.FormatBool(to.Deleted))
+
+	return changes
+}
+
+// attributeNames is the sorted union of the names in a and b.
+func attributeNames(a, b Attributes) []string {
+	merged := a.Clone()
+	if merged == nil {
+		merged = Attributes{}
+	}
+	for name, v := range b {
+		merged[name] = v
+	}
+	return merged.Names()
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord remembers the result of a keyed request so a repeat of
+// the same request returns it instead of doing the work again. Fingerprint
+// identifies the request payload; a repeat with another fingerprint is a
+// client bug, not a retry.
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string
+	User        *User
+	ExpiresAt   time.Time
+}
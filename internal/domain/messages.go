@@ -0,0 +1,234 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Locale selects the language of the messages shown to callers.
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en"
+	LocaleSpanish Locale = "es"
+)
+
+// DefaultLocale is the language of Error() and the fallback for locales
+// without a catalog.
+const DefaultLocale = LocaleEnglish
+
+// Locales lists every locale with a catalog, DefaultLocale first.
+func Locales() []Locale {
+	return []Locale{LocaleEnglish, LocaleSpanish}
+}
+
+// Supported reports whether l has a catalog.
+func (l Locale) Supported() bool {
+	_, ok := internalMessages[l]
+	return ok
+}
+
+// Message is the text of err for a caller in loc: the catalog entry for its
+// error code, rendered with the rule of a *ValidationError and the wait of a
+// *RateLimitError. Errors without a domain code get a generic message so that
+// internal details never reach the caller. English entries are the
+// sentinels' own text, so Message(err, DefaultLocale) needs no catalog of
+// its own.
+func Message(err error, loc Locale) string {
+	if err == nil {
+		return ""
+	}
+	if !loc.Supported() {
+		loc = DefaultLocale
+	}
+
+	code := ErrorCode(err)
+	if code == CodeInternal {
+		return internalMessages[loc]
+	}
+
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.message(loc)
+	}
+	var attribute *AttributeError
+	if errors.As(err, &attribute) {
+		return attribute.message(loc)
+	}
+
+	msg := ErrorForCode(code).Error()
+	if loc != LocaleEnglish {
+		msg = messages[loc][code]
+	}
+
+	var limited *RateLimitError
+	if errors.As(err, &limited) {
+		msg = fmt.Sprintf(retryAfterMessages[loc], msg, limited.RetryAfter)
+	}
+	return msg
+}
+
+// LocalizedError carries the message of an error for one locale while still
+// matching the original error under errors.Is and errors.As.
+type LocalizedError struct {
+	Locale Locale
+	err    error
+}
+
+// Localize returns err with its message in loc, or nil when err is nil.
+func Localize(err error, loc Locale) error {
+	if err == nil {
+		return nil
+	}
+	if !loc.Supported() {
+		loc = DefaultLocale
+	}
+	return &LocalizedError{Locale: loc, err: err}
+}
+
+func (e *LocalizedError) Error() string {
+	return Message(e.err, e.Locale)
+}
+
+func (e *LocalizedError) Unwrap() error {
+	return e.err
+}
+
+var internalMessages = map[Locale]string{
+	LocaleEnglish: "internal error",
+	LocaleSpanish: "error interno",
+}
+
+// retryAfterMessages take the message of ErrRateLimited and the wait.
+var retryAfterMessages = map[Locale]string{
+	LocaleEnglish: "%s; retry after %v",
+	LocaleSpanish: "%s; reintente en %v",
+}
+
+// validationMessages take ValidationError.Min, Max and the pattern in words
+// (see patternRule) as arguments 1, 2 and 3; each template uses only the
+// ones its rule has.
+var validationMessages = map[Locale]map[string]string{
+	LocaleEnglish: {
+		FieldName:     "user name must be between %[1]d and %[2]d characters and %[3]s",
+		FieldEmail:    "user email must be a valid email address %[3]s",
+		FieldAge:      "user age must be between %[1]d and %[2]d",
+		FieldPassword: "password must be between %[1]d and %[2]d characters and contain a letter and a digit",
+	},
+	LocaleSpanish: {
+		FieldName:     "el nombre de usuario debe tener entre %[1]d y %[2]d caracteres y %[3]s",
+		FieldEmail:    "el email de usuario debe ser una dirección válida %[3]s",
+		FieldAge:      "la edad de usuario debe estar entre %[1]d y %[2]d",
+		FieldPassword: "la contraseña debe tener entre %[1]d y %[2]d caracteres e incluir una letra y un dígito",
+	},
+}
+
+// MalformedInput names the part of a request that could not be parsed.
+type MalformedInput string
+
+const (
+	MalformedBody   MalformedInput = "body"
+	MalformedFilter MalformedInput = "filter"
+	MalformedCursor MalformedInput = "cursor"
+)
+
+// malformedMessages take the parser's own description of the problem,
+// which is not translated.
+var malformedMessages = map[Locale]map[MalformedInput]string{
+	LocaleEnglish: {
+		MalformedBody:   "malformed request body: %s",
+		MalformedFilter: "malformed attribute filter: %s",
+		MalformedCursor: "malformed change cursor: %s",
+	},
+	LocaleSpanish: {
+		MalformedBody:   "cuerpo de la petición mal formado: %s",
+		MalformedFilter: "filtro de atributo mal formado: %s",
+		MalformedCursor: "cursor de cambios mal formado: %s",
+	},
+}
+
+// MalformedMessage is the text for a caller in loc whose input could not be
+// parsed: a localized prefix naming the input, then detail as the parser
+// wrote it.
+func MalformedMessage(input MalformedInput, detail string, loc Locale) string {
+	if !loc.Supported() {
+		loc = DefaultLocale
+	}
+	return fmt.Sprintf(malformedMessages[loc][input], detail)
+}
+
+// patternRules describe the patterns of DefaultValidationPolicy in words,
+// by locale, field and pattern, so that end users never see a regular
+// expression. Patterns of custom policies get the generic rule under "".
+var patternRules = map[Locale]map[string]map[string]string{
+	LocaleEnglish: {
+		FieldName: {
+			defaultNamePattern: "contain only letters and spaces, starting and ending with a letter",
+			"":                 "use only the characters this service accepts",
+		},
+		FieldEmail: {
+			defaultEmailPattern: "with letters, digits or ._%+- before the @ and a domain ending in a dot and at least two letters",
+			"":                  "in the format this service accepts",
+		},
+	},
+	LocaleSpanish: {
+		FieldName: {
+			defaultNamePattern: "contener solo letras y espacios, empezando y terminando por una letra",
+			"":                 "usar solo los caracteres que admite este servicio",
+		},
+		FieldEmail: {
+			defaultEmailPattern: "con letras, dígitos o ._%+- antes de la @ y un dominio que termine en un punto y al menos dos letras",
+			"":                  "con el formato que admite este servicio",
+		},
+	},
+}
+
+// patternRule is pattern in words for field, or "" for fields without one.
+func patternRule(field, pattern string, loc Locale) string {
+	rules, ok := patternRules[loc][field]
+	if !ok {
+		return ""
+	}
+	if rule, ok := rules[pattern]; ok {
+		return rule
+	}
+	return rules[""]
+}
+
+// attributeMessages take the attribute name and, for mistyped, its
+// declared type.
+var attributeMessages = map[Locale]struct{ undeclared, mistyped string }{
+	LocaleEnglish: {
+		undeclared: "custom attribute %q is not declared in the schema",
+		mistyped:   "custom attribute %q must be of type %s",
+	},
+	LocaleSpanish: {
+		undeclared: "el atributo personalizado %q no está declarado en el esquema",
+		mistyped:   "el atributo personalizado %q debe ser de tipo %s",
+	},
+}
+
+// messages holds the catalog of every locale but English, by error code.
+var messages = map[Locale]map[string]string{
+	LocaleSpanish: {
+		CodeInvalidUserName:  "nombre de usuario no válido",
+		CodeInvalidUserEmail: "email de usuario no válido",
+		CodeInvalidUserAge:   "edad de usuario no válida",
+		CodeNotFound:         "entidad no encontrada",
+		CodeAlreadyExists:    "la entidad ya existe",
+		CodeInvalidTenant:    "el id de tenant no puede estar vacío",
+		CodeUnauthenticated:  "el llamante no está autenticado",
+		CodeForbidden:        "operación no permitida para el llamante",
+		CodeWeakPassword:     "la contraseña es demasiado débil",
+		CodeInvalidCreds:     "credenciales no válidas",
+		CodeAccountLocked:    "cuenta bloqueada temporalmente tras varios inicios de sesión fallidos",
+		CodeInvalidToken:     "el token de verificación no es válido o ya se usó",
+		CodeTokenExpired:     "el token de verificación ha caducado",
+		CodeAlreadyVerified:  "la dirección de email ya está verificada",
+		CodeIdempotencyKey:   "la clave de idempotencia ya se usó con una petición distinta",
+		CodeRateLimited:      "límite de peticiones superado",
+		CodeChangesGone:      "los cambios solicitados ya no se conservan; vuelva a suscribirse desde la secuencia actual",
+		CodeInvalidMerge:     "un usuario no se puede fusionar consigo mismo",
+		CodeInvalidAttribute: "atributo personalizado no válido",
+	},
+}
@@ -1,70 +1,79 @@
 package domain
 
-import (
-	"regexp"
-	"strings"
-	"time"
-)
+import "time"
 
 type User struct {
-	ID        string
-	Name      string
-	Email     string
-	Age       int
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID            string
+	Name          string
+	Email         string
+	Age           int
+	EmailVerified bool
+	// Attributes are the custom attributes declared by the deployment's
+	// AttributeSchema.
+	Attributes Attributes
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
-var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-var nameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z ]{0,48}[A-Za-z]$`)
-
+// Validate checks u against DefaultValidationPolicy; see
+// ValidationPolicy.Validate.
 func (u *User) Validate() error {
-	u.Name = strings.TrimSpace(u.Name)
-
-	if len(u.Name) < 2 || len(u.Name) > 50 {
-		return ErrInvalidUserName
-	}
-	if !nameRegex.MatchString(u.Name) {
-		return ErrInvalidUserName
-	}
-
-	u.Email = strings.TrimSpace(strings.ToLower(u.Email))
-	if !emailRegex.MatchString(u.Email) {
-		return ErrInvalidUserEmail
-	}
-
-	if u.Age < 1 || u.Age > 150 {
-		return ErrInvalidUserAge
-	}
-
-	return nil
+	return DefaultValidationPolicy.Validate(u)
 }
 
 func NewUser(id, name, email string, age int) (*User, error) {
-	now := time.Now().UTC()
-	user := &User{
-		ID:        id,
-		Name:      name,
-		Email:     email,
-		Age:       age,
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
+	return DefaultValidationPolicy.NewUser(id, name, email, age)
+}
 
-	if err := user.Validate(); err != nil {
-		return nil, err
+func (u *User) Clone() *User {
+	return &User{
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		Age:           u.Age,
+		EmailVerified: u.EmailVerified,
+		Attributes:    u.Attributes.Clone(),
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
 	}
+}
 
-	return user, nil
+// UserPatch carries the fields a partial update should change; nil fields
+// are left as they are. Attributes lists only the attributes to change: a
+// nil value removes the attribute, others are set.
+type UserPatch struct {
+	Name       *string
+	Email      *string
+	Age        *int
+	Attributes map[string]*AttributeValue
 }
 
-func (u *User) Clone() *User {
-	return &User{
-		ID:        u.ID,
-		Name:      u.Name,
-		Email:     u.Email,
-		Age:       u.Age,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+func (p UserPatch) ApplyTo(u *User) {
+	if p.Name != nil {
+		u.Name = *p.Name
+	}
+	if p.Email != nil {
+		u.Email = *p.Email
+	}
+	if p.Age != nil {
+		u.Age = *p.Age
+	}
+	if len(p.Attributes) > 0 {
+		// A new map, so that copies of u taken before the patch keep theirs.
+		attrs := u.Attributes.Clone()
+		if attrs == nil {
+			attrs = Attributes{}
+		}
+		for name, v := range p.Attributes {
+			if v == nil {
+				delete(attrs, name)
+			} else {
+				attrs[name] = *v
+			}
+		}
+		if len(attrs) == 0 {
+			attrs = nil
+		}
+		u.Attributes = attrs
 	}
 }
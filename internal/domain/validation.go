@@ -0,0 +1,203 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ValidationPolicy holds the rules a user must satisfy. The name pattern is
+// matched after trimming and the email pattern after trimming and
+// lower-casing, which is also how both are stored. Attributes declares the
+// custom attributes of the deployment; the default declares none. Build
+// policies with NewValidationPolicy or LoadValidationPolicy so the patterns
+// are compiled.
+type ValidationPolicy struct {
+	NameMinLength int             `json:"name_min_length"`
+	NameMaxLength int             `json:"name_max_length"`
+	NamePattern   string          `json:"name_pattern"`
+	EmailPattern  string          `json:"email_pattern"`
+	AgeMin        int             `json:"age_min"`
+	AgeMax        int             `json:"age_max"`
+	Attributes    AttributeSchema `json:"attributes"`
+
+	name  *regexp.Regexp
+	email *regexp.Regexp
+}
+
+const (
+	defaultNamePattern  = `^[A-Za-z]([A-Za-z ]*[A-Za-z])?$`
+	defaultEmailPattern = `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
+)
+
+// DefaultValidationPolicy is the policy User.Validate and NewUser apply.
+var DefaultValidationPolicy = mustValidationPolicy(ValidationPolicy{
+	NameMinLength: 2,
+	NameMaxLength: 50,
+	NamePattern:   defaultNamePattern,
+	EmailPattern:  defaultEmailPattern,
+	AgeMin:        1,
+	AgeMax:        150,
+})
+
+func mustValidationPolicy(p ValidationPolicy) *ValidationPolicy {
+	policy, err := NewValidationPolicy(p)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// NewValidationPolicy checks that the bounds of p make sense and compiles
+// its patterns.
+func NewValidationPolicy(p ValidationPolicy) (*ValidationPolicy, error) {
+	if p.NameMinLength < 1 || p.NameMaxLength < p.NameMinLength {
+		return nil, fmt.Errorf("invalid validation policy: name length must satisfy 1 <= min <= max, got %d..%d", p.NameMinLength, p.NameMaxLength)
+	}
+	if p.AgeMax < p.AgeMin {
+		return nil, fmt.Errorf("invalid validation policy: age must satisfy min <= max, got %d..%d", p.AgeMin, p.AgeMax)
+	}
+
+	if err := p.Attributes.check(); err != nil {
+		return nil, fmt.Errorf("invalid validation policy: %w", err)
+	}
+	p.Attributes = maps.Clone(p.Attributes)
+
+	var err error
+	if p.name, err = regexp.Compile(p.NamePattern); err != nil {
+		return nil, fmt.Errorf("invalid validation policy: name pattern: %w", err)
+	}
+	if p.email, err = regexp.Compile(p.EmailPattern); err != nil {
+		return nil, fmt.Errorf("invalid validation policy: email pattern: %w", err)
+	}
+	return &p, nil
+}
+
+// LoadValidationPolicy reads a JSON policy. Fields left out keep the value
+// of DefaultValidationPolicy, so {"age_min": 18} only raises the minimum age.
+func LoadValidationPolicy(r io.Reader) (*ValidationPolicy, error) {
+	p := *DefaultValidationPolicy
+	// Decoding into the shared map would change the default policy.
+	p.Attributes = maps.Clone(p.Attributes)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid validation policy: %w", err)
+	}
+	return NewValidationPolicy(p)
+}
+
+// Validate normalizes the name and email of u in place and checks u against
+// the policy.
+func (p *ValidationPolicy) Validate(u *User) error {
+	u.Name = strings.TrimSpace(u.Name)
+	if err := p.CheckName(u.Name); err != nil {
+		return err
+	}
+
+	u.Email = strings.TrimSpace(strings.ToLower(u.Email))
+	if err := p.CheckEmail(u.Email); err != nil {
+		return err
+	}
+
+	if err := p.CheckAge(u.Age); err != nil {
+		return err
+	}
+	return p.Attributes.Validate(u.Attributes)
+}
+
+// CheckName checks an already trimmed name.
+func (p *ValidationPolicy) CheckName(name string) error {
+	if len(name) < p.NameMinLength || len(name) > p.NameMaxLength || !p.name.MatchString(name) {
+		return &ValidationError{Field: FieldName, Min: p.NameMinLength, Max: p.NameMaxLength, Pattern: p.NamePattern, err: ErrInvalidUserName}
+	}
+	return nil
+}
+
+// CheckEmail checks an already trimmed, lower-cased email.
+func (p *ValidationPolicy) CheckEmail(email string) error {
+	if !p.email.MatchString(email) {
+		return &ValidationError{Field: FieldEmail, Pattern: p.EmailPattern, err: ErrInvalidUserEmail}
+	}
+	return nil
+}
+
+// CheckAge checks that age lies within the policy bounds.
+func (p *ValidationPolicy) CheckAge(age int) error {
+	if age < p.AgeMin || age > p.AgeMax {
+		return &ValidationError{Field: FieldAge, Min: p.AgeMin, Max: p.AgeMax, err: ErrInvalidUserAge}
+	}
+	return nil
+}
+
+const (
+	FieldName     = "name"
+	FieldEmail    = "email"
+	FieldAge      = "age"
+	FieldPassword = "password"
+)
+
+// ValidationError reports the rule a field broke. Its message is written
+// from the very bounds that were checked, so it cannot drift from the rule,
+// and states the pattern in words rather than as a regular expression. It
+// matches the field's sentinel (ErrInvalidUserName, ErrInvalidUserEmail,
+// ErrInvalidUserAge or ErrWeakPassword) under errors.Is.
+type ValidationError struct {
+	Field string
+	// Min and Max are inclusive: lengths for names and passwords, years for
+	// ages. Both are zero for emails.
+	Min, Max int
+	// Pattern is the regular expression names and emails must match. It is
+	// for programs; messages describe it in words.
+	Pattern string
+
+	err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.message(DefaultLocale)
+}
+
+// message renders the rule from the catalog template for the field, which
+// takes Min, Max and the pattern in words as arguments 1, 2 and 3.
+func (e *ValidationError) message(loc Locale) string {
+	template, ok := validationMessages[loc][e.Field]
+	if !ok {
+		return Message(e.err, loc)
+	}
+	return fmt.Sprintf(template, e.Min, e.Max, patternRule(e.Field, e.Pattern, loc))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// NewUser builds a user created now and validates it against the policy.
+func (p *ValidationPolicy) NewUser(id, name, email string, age int) (*User, error) {
+	return p.NewUserWithAttributes(id, name, email, age, nil)
+}
+
+// NewUserWithAttributes is NewUser for a user that starts with custom
+// attributes; attrs is copied.
+func (p *ValidationPolicy) NewUserWithAttributes(id, name, email string, age int, attrs Attributes) (*User, error) {
+	now := time.Now().UTC()
+	user := &User{
+		ID:         id,
+		Name:       name,
+		Email:      email,
+		Age:        age,
+		Attributes: attrs.Clone(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := p.Validate(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// VerificationToken is stored by hash; the raw token only exists in the
+// message sent to the user.
+type VerificationToken struct {
+	Hash      string
+	UserID    string
+	Email     string
+	ExpiresAt time.Time
+	Used      bool
+}
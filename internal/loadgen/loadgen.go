@@ -0,0 +1,289 @@
+package loadgen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"property-based/internal/domain"
+)
+
+// Target is the part of the user API the generator drives. Both
+// service.UserOperations and the transport clients satisfy it.
+type Target interface {
+	CreateUser(name, email string, age int) (*domain.User, error)
+	GetUser(id string) (*domain.User, error)
+	GetUserByEmail(email string) (*domain.User, error)
+	GetAllUsers() ([]*domain.User, error)
+	UpdateUser(id, name, email string, age int) (*domain.User, error)
+	DeleteUser(id string) error
+}
+
+const (
+	OpCreateUser     = "CreateUser"
+	OpGetUser        = "GetUser"
+	OpGetUserByEmail = "GetUserByEmail"
+	OpGetAllUsers    = "GetAllUsers"
+	OpUpdateUser     = "UpdateUser"
+	OpDeleteUser     = "DeleteUser"
+)
+
+// ErrUnbounded is returned by Run for a Config with neither a Duration nor
+// a Requests limit, which would never stop.
+var ErrUnbounded = errors.New("load run needs a positive duration or request count")
+
+type Config struct {
+	// Duration bounds the run, or not at all when zero; Requests, when
+	// positive, stops it after that many operations in total. At least one
+	// of the two must be set.
+	Duration    time.Duration
+	Requests    int
+	Concurrency int
+	// SeedUsers are created before the clock starts, split between workers.
+	SeedUsers int
+	// WritePercent is the share of operations that create, update or
+	// delete; the rest read.
+	WritePercent int
+	// RandSeed makes the operation mix reproducible.
+	RandSeed int64
+}
+
+var DefaultConfig = Config{
+	Duration:     10 * time.Second,
+	Concurrency:  8,
+	SeedUsers:    1000,
+	WritePercent: 20,
+	RandSeed:     1,
+}
+
+// OpStats summarizes one operation. Latencies include failed calls.
+type OpStats struct {
+	Count  int
+	Errors int
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+type Report struct {
+	Elapsed time.Duration
+	Total   int
+	Ops     map[string]OpStats
+}
+
+// Throughput is operations per second over the whole run.
+func (r Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Total) / r.Elapsed.Seconds()
+}
+
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "operation\tcount\terrors\tp50\tp90\tp99\tmax\t\n")
+	names := make([]string, 0, len(r.Ops))
+	for name := range r.Ops {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		s := r.Ops[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\t%v\t%v\t\n", name, s.Count, s.Errors, s.P50, s.P90, s.P99, s.Max)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d operations in %v (%.0f ops/s)\n", r.Total, r.Elapsed.Round(time.Millisecond), r.Throughput())
+	return err
+}
+
+// Percentile returns the nearest-rank p-th percentile of sorted latencies.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+type sample struct {
+	op      string
+	latency time.Duration
+	failed  bool
+}
+
+// worker owns the users it created, so workers never update or delete each
+// other's users and errors mean the target misbehaved.
+type worker struct {
+	id     int
+	target Target
+	rng    *rand.Rand
+	users  []*domain.User
+	serial int
+}
+
+func (w *worker) nextEmail() string {
+	w.serial++
+	return fmt.Sprintf("load-%d-%d@example.com", w.id, w.serial)
+}
+
+// Run seeds the target and drives it from cfg.Concurrency workers until
+// the duration elapses or cfg.Requests operations have run.
+func Run(target Target, cfg Config) (Report, error) {
+	if cfg.Duration <= 0 && cfg.Requests <= 0 {
+		return Report{}, ErrUnbounded
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	workers := make([]*worker, cfg.Concurrency)
+	for i := range workers {
+		workers[i] = &worker{id: i, target: target, rng: rand.New(rand.NewSource(cfg.RandSeed + int64(i)))}
+	}
+	for i := 0; i < cfg.SeedUsers; i++ {
+		w := workers[i%len(workers)]
+		user, err := target.CreateUser("Load User", w.nextEmail(), 18+i%60)
+		if err != nil {
+			return Report{}, fmt.Errorf("seed user %d: %w", i, err)
+		}
+		w.users = append(w.users, user)
+	}
+
+	var budget chan struct{}
+	if cfg.Requests > 0 {
+		budget = make(chan struct{}, cfg.Requests)
+		for i := 0; i < cfg.Requests; i++ {
+			budget <- struct{}{}
+		}
+		close(budget)
+	}
+
+	deadline := time.Now().Add(cfg.Duration)
+	results := make([][]sample, len(workers))
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, w := range workers {
+		wg.Add(1)
+		go func(i int, w *worker) {
+			defer wg.Done()
+			for cfg.Duration <= 0 || time.Now().Before(deadline) {
+				if budget != nil {
+					if _, ok := <-budget; !ok {
+						return
+					}
+				}
+				results[i] = append(results[i], w.step(cfg.WritePercent))
+			}
+		}(i, w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	return summarize(results, elapsed), nil
+}
+
+func (w *worker) step(writePercent int) sample {
+	if w.rng.Intn(100) < writePercent {
+		return w.write()
+	}
+	return w.read()
+}
+
+func (w *worker) read() sample {
+	roll := w.rng.Intn(100)
+	if len(w.users) == 0 || roll == 0 {
+		return timed(OpGetAllUsers, func() error {
+			_, err := w.target.GetAllUsers()
+			return err
+		})
+	}
+	user := w.users[w.rng.Intn(len(w.users))]
+	if roll < 70 {
+		return timed(OpGetUser, func() error {
+			_, err := w.target.GetUser(user.ID)
+			return err
+		})
+	}
+	return timed(OpGetUserByEmail, func() error {
+		_, err := w.target.GetUserByEmail(user.Email)
+		return err
+	})
+}
+
+func (w *worker) write() sample {
+	roll := w.rng.Intn(100)
+	if len(w.users) == 0 || roll < 40 {
+		email := w.nextEmail()
+		return timed(OpCreateUser, func() error {
+			user, err := w.target.CreateUser("Load User", email, 18+w.rng.Intn(60))
+			if err == nil {
+				w.users = append(w.users, user)
+			}
+			return err
+		})
+	}
+
+	i := w.rng.Intn(len(w.users))
+	user := w.users[i]
+	if roll < 80 {
+		age := 18 + w.rng.Intn(60)
+		return timed(OpUpdateUser, func() error {
+			updated, err := w.target.UpdateUser(user.ID, user.Name, user.Email, age)
+			if err == nil {
+				w.users[i] = updated
+			}
+			return err
+		})
+	}
+	return timed(OpDeleteUser, func() error {
+		err := w.target.DeleteUser(user.ID)
+		if err == nil {
+			w.users[i] = w.users[len(w.users)-1]
+			w.users = w.users[:len(w.users)-1]
+		}
+		return err
+	})
+}
+
+func timed(op string, call func() error) sample {
+	start := time.Now()
+	err := call()
+	return sample{op: op, latency: time.Since(start), failed: err != nil}
+}
+
+func summarize(results [][]sample, elapsed time.Duration) Report {
+	latencies := map[string][]time.Duration{}
+	errors := map[string]int{}
+	total := 0
+	for _, samples := range results {
+		for _, s := range samples {
+			latencies[s.op] = append(latencies[s.op], s.latency)
+			if s.failed {
+				errors[s.op]++
+			}
+			total++
+		}
+	}
+
+	report := Report{Elapsed: elapsed, Total: total, Ops: make(map[string]OpStats, len(latencies))}
+	for op, ls := range latencies {
+		slices.Sort(ls)
+		report.Ops[op] = OpStats{
+			Count:  len(ls),
+			Errors: errors[op],
+			P50:    Percentile(ls, 50),
+			P90:    Percentile(ls, 90),
+			P99:    Percentile(ls, 99),
+			Max:    ls[len(ls)-1],
+		}
+	}
+	return report
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"time"
+
+	"property-based/internal/domain"
+)
+
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// Operations records, for a family of named operations, how many calls ended
+// in each outcome, which domain error caused each failure and how long calls
+// took.
+type Operations struct {
+	calls    *CounterVec
+	errors   *CounterVec
+	duration *HistogramVec
+}
+
+func NewOperations(reg *Registry, prefix string) *Operations {
+	return &Operations{
+		calls:    reg.Counter(prefix+"_operations_total", "Completed operations by name and outcome.", "operation", "outcome"),
+		errors:   reg.Counter(prefix+"_errors_total", "Failed operations by name and domain error code.", "operation", "code"),
+		duration: reg.Histogram(prefix+"_operation_duration_seconds", "Operation latency in seconds.", DefaultBuckets, "operation"),
+	}
+}
+
+func (o *Operations) Observe(operation string, start time.Time, err error) {
+	o.duration.Observe(time.Since(start).Seconds(), operation)
+	if err != nil {
+		o.calls.Inc(operation, OutcomeError)
+		o.errors.Inc(operation, domain.ErrorCode(err))
+		return
+	}
+	o.calls.Inc(operation, OutcomeOK)
+}
+
+func (o *Operations) Calls(operation, outcome string) float64 {
+	return o.calls.Value(operation, outcome)
+}
+
+func (o *Operations) Errors(operation, code string) float64 {
+	return o.errors.Value(operation, code)
+}
+
+func (o *Operations) Observations(operation string) uint64 {
+	return o.duration.Count(operation)
+}
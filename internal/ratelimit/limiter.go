@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: it holds up to Burst tokens and refills at Rate
+// tokens per second. The zero Limit allows everything.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) unlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps one token bucket per key. Buckets that have refilled
+// completely carry no state and are dropped as the map grows.
+type Limiter struct {
+	limit Limit
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweepAt int
+}
+
+const minSweep = 1024
+
+// epsilon absorbs float rounding so that waiting exactly the reported
+// duration always yields a token.
+const epsilon = 1e-9
+
+func NewLimiter(limit Limit, now func() time.Time) *Limiter {
+	if now == nil {
+		now = time.Now
+	}
+	return &Limiter{limit: limit, now: now, buckets: make(map[string]*bucket), sweepAt: minSweep}
+}
+
+// Take spends one token from key's bucket. When the bucket is empty it
+// spends nothing and reports how long until a token becomes available.
+func (l *Limiter) Take(key string) (bool, time.Duration) {
+	if l.limit.unlimited() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		l.sweep(now)
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+
+	if b.tokens >= 1-epsilon {
+		b.tokens = math.Max(b.tokens-1, 0)
+		return true, 0
+	}
+	wait := time.Duration(math.Ceil((1 - b.tokens) / l.limit.Rate * float64(time.Second)))
+	return false, wait
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(l.limit.Burst), b.tokens+elapsed.Seconds()*l.limit.Rate)
+		b.last = now
+	}
+}
+
+// sweep drops full buckets once the map has doubled since the last sweep,
+// keeping memory proportional to the keys that are actually being limited.
+func (l *Limiter) sweep(now time.Time) {
+	if len(l.buckets) < l.sweepAt {
+		return
+	}
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.sweepAt = max(2*len(l.buckets), minSweep)
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"property-based/internal/domain"
+)
+
+type CacheConfig struct {
+	Capacity int
+	TTL      time.Duration
+	Now      func() time.Time
+}
+
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+}
+
+type cacheEntry struct {
+	user      *domain.User
+	expiresAt time.Time
+}
+
+// CachingUserRepository is a read-through cache in front of another
+// repository. Every entry is reachable by ID and by email; writes always go
+// to the wrapped repository first and then drop whatever the cache held for
+// the affected user. The optional UserModifier, UserQuerier, UserHistory and
+// UserWatcher methods go straight to the wrapped repository and fail with
+// errors.ErrUnsupported when it lacks them.
+type CachingUserRepository struct {
+	inner UserRepository
+	cfg   CacheConfig
+
+	mu      sync.Mutex
+	lru     *list.List
+	byID    map[string]*list.Element
+	byEmail map[string]*list.Element
+	version uint64
+	stats   CacheStats
+}
+
+func NewCachingUserRepository(inner UserRepository, cfg CacheConfig) *CachingUserRepository {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &CachingUserRepository{
+		inner:   inner,
+		cfg:     cfg,
+		lru:     list.New(),
+		byID:    make(map[string]*list.Element),
+		byEmail: make(map[string]*list.Element),
+	}
+}
+
+func (r *CachingUserRepository) Stats() CacheStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.stats
+}
+
+func (r *CachingUserRepository) Create(user *domain.User) error {
+	err := r.inner.Create(user)
+	r.invalidate(user.ID, user.Email)
+	return err
+}
+
+func (r *CachingUserRepository) GetByID(id string) (*domain.User, error) {
+	return r.lookup(r.byID, id, func() (*domain.User, error) {
+		return r.inner.GetByID(id)
+	})
+}
+
+func (r *CachingUserRepository) GetByEmail(email string) (*domain.User, error) {
+	return r.lookup(r.byEmail, email, func() (*domain.User, error) {
+		return r.inner.GetByEmail(email)
+	})
+}
+
+func (r *CachingUserRepository) GetAll() ([]*domain.User, error) {
+	return r.inner.GetAll()
+}
+
+func (r *CachingUserRepository) Update(user *domain.User) error {
+	err := r.inner.Update(user)
+	r.invalidate(user.ID, user.Email)
+	return err
+}
+
+func (r *CachingUserRepository) Delete(id string) error {
+	err := r.inner.Delete(id)
+	r.invalidate(id, "")
+	return err
+}
+
+func (r *CachingUserRepository) Count() int {
+	return r.inner.Count()
+}
+
+// Modify drops the cached user whether or not fn changed it, since a failed
+// write may still have been applied.
+func (r *CachingUserRepository) Modify(id string, fn func(user *domain.User) (bool, error)) (*domain.User, error) {
+	modifier, ok := r.inner.(UserModifier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	user, err := modifier.Modify(id, fn)
+	email := ""
+	if user != nil {
+		email = user.Email
+	}
+	r.invalidate(id, email)
+	return user, err
+}
+
+func (r *CachingUserRepository) FindByAgeRange(min, max int) ([]*domain.User, error) {
+	q, ok := r.inner.(UserQuerier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return q.FindByAgeRange(min, max)
+}
+
+func (r *CachingUserRepository) FindByNamePrefix(prefix string) ([]*domain.User, error) {
+	q, ok := r.inner.(UserQuerier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return q.FindByNamePrefix(prefix)
+}
+
+func (r *CachingUserRepository) Versions(id string) ([]domain.UserVersion, error) {
+	h, ok := r.inner.(UserHistory)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return h.Versions(id)
+}
+
+func (r *CachingUserRepository) Watch(after uint64) (*Subscription, error) {
+	w, ok := r.inner.(UserWatcher)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return w.Watch(after)
+}
+
+func (r *CachingUserRepository) Head() uint64 {
+	if w, ok := r.inner.(UserWatcher); ok {
+		return w.Head()
+	}
+	return 0
+}
+
+func (r *CachingUserRepository) lookup(index map[string]*list.Element, key string, load func() (*domain.User, error)) (*domain.User, error) {
+	r.mu.Lock()
+	if elem, ok := index[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if r.cfg.TTL <= 0 || r.cfg.Now().Before(entry.expiresAt) {
+			r.lru.MoveToFront(elem)
+			r.stats.Hits++
+			user := entry.user.Clone()
+			r.mu.Unlock()
+			return user, nil
+		}
+		r.removeElement(elem)
+		r.stats.Expirations++
+	}
+	r.stats.Misses++
+	version := r.version
+	r.mu.Unlock()
+
+	user, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A write that raced with the load may have made user stale.
+	if r.version == version {
+		r.store(user)
+	}
+	return user, nil
+}
+
+func (r *CachingUserRepository) store(user *domain.User) {
+	if r.cfg.Capacity < 1 {
+		return
+	}
+	if elem, ok := r.byID[user.ID]; ok {
+		r.removeElement(elem)
+	}
+	if elem, ok := r.byEmail[user.Email]; ok {
+		r.removeElement(elem)
+	}
+
+	entry := &cacheEntry{user: user.Clone(), expiresAt: r.cfg.Now().Add(r.cfg.TTL)}
+	elem := r.lru.PushFront(entry)
+	r.byID[user.ID] = elem
+	r.byEmail[user.Email] = elem
+
+	for r.lru.Len() > r.cfg.Capacity {
+		r.removeElement(r.lru.Back())
+		r.stats.Evictions++
+	}
+}
+
+// invalidate drops the cached user under id, which also removes its previous
+// email key, plus anything still cached under email.
+func (r *CachingUserRepository) invalidate(id, email string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.version++
+	if elem, ok := r.byID[id]; ok {
+		r.removeElement(elem)
+	}
+	if elem, ok := r.byEmail[email]; ok {
+		r.removeElement(elem)
+	}
+}
+
+func (r *CachingUserRepository) removeElement(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	delete(r.byID, entry.user.ID)
+	delete(r.byEmail, entry.user.Email)
+	r.lru.Remove(elem)
+}
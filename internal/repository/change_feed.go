@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"property-based/internal/domain"
+)
+
+const DefaultChangeRetention = 1024
+
+// UserWatcher is implemented by repositories that publish their writes as
+// an ordered change feed.
+type UserWatcher interface {
+	// Watch streams every change with a sequence number above after, first
+	// the retained backlog and then new changes as they commit. Pass Head()
+	// to follow only new changes. It fails with domain.ErrChangesUnavailable
+	// when after is older than the retained backlog or was never issued.
+	Watch(after uint64) (*Subscription, error)
+	// Head is the sequence number of the latest change, 0 if none.
+	Head() uint64
+}
+
+// WithChangeRetention bounds how many changes are kept for resuming
+// watchers. It is also the buffer shared by every subscriber: one that falls
+// further behind is dropped rather than slowing writers down. Values below 1
+// are treated as 1.
+func WithChangeRetention(n int) Option {
+	return func(r *InMemoryUserRepository) {
+		r.changeRetention = n
+	}
+}
+
+// changeFeed is a bounded log of committed changes. Publishing never
+// blocks: each subscriber reads the log at its own pace from its own
+// goroutine.
+type changeFeed struct {
+	mu sync.Mutex
+	// log holds every change after logBase. Only those after base are
+	// retained; the stale prefix is dropped in one copy once the log
+	// reaches twice the retention, so a publish costs amortized O(1)
+	// instead of copying the whole backlog.
+	log       []domain.UserChange
+	logBase   uint64
+	retention int
+	seq       uint64
+	// base is the sequence number of the oldest change that can no longer
+	// be resumed from; every change above it is retained.
+	base uint64
+	// changed is closed and replaced on every publish and reset.
+	changed chan struct{}
+}
+
+func newChangeFeed(retention int) *changeFeed {
+	return &changeFeed{retention: max(retention, 1), changed: make(chan struct{})}
+}
+
+func (f *changeFeed) publish(op domain.ChangeOp, user *domain.User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	f.log = append(f.log, domain.UserChange{Seq: f.seq, Op: op, At: time.Now().UTC(), User: user.Clone()})
+	if f.seq-f.base > uint64(f.retention) {
+		f.base = f.seq - uint64(f.retention)
+	}
+	if len(f.log) >= 2*f.retention {
+		n := copy(f.log, f.log[f.base-f.logBase:])
+		clear(f.log[n:])
+		f.log = f.log[:n]
+		f.logBase = f.base
+	}
+	f.wake()
+}
+
+// reset drops the backlog after the repository contents were replaced
+// wholesale. A sequence number is skipped so that no cursor issued before
+// the reset can resume, and live subscribers end with ErrChangesUnavailable.
+func (f *changeFeed) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	f.base = f.seq
+	f.logBase = f.seq
+	f.log = nil
+	f.wake()
+}
+
+// wake must be called with f.mu held.
+func (f *changeFeed) wake() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *changeFeed) head() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq
+}
+
+// since returns the changes after cursor and a channel that is closed by the
+// next publish, read together so that no wake-up is missed.
+func (f *changeFeed) since(after uint64) ([]domain.UserChange, <-chan struct{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if after < f.base || after > f.seq {
+		return nil, nil, domain.ErrChangesUnavailable
+	}
+	return append([]domain.UserChange(nil), f.log[after-f.logBase:]...), f.changed, nil
+}
+
+func (f *changeFeed) watch(after uint64) (*Subscription, error) {
+	if _, _, err := f.since(after); err != nil {
+		return nil, err
+	}
+	s := &Subscription{c: make(chan domain.UserChange), done: make(chan struct{})}
+	go s.run(f, after)
+	return s, nil
+}
+
+// Subscription delivers changes in sequence order on C until it is closed
+// or falls behind the retained backlog.
+type Subscription struct {
+	c    chan domain.UserChange
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+func (s *Subscription) C() <-chan domain.UserChange {
+	return s.c
+}
+
+// Close stops the subscription; C is closed shortly after.
+func (s *Subscription) Close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// Err reports why C was closed: nil after Close, domain.ErrChangesUnavailable
+// when the subscriber fell behind or the repository was restored. It is only
+// meaningful once C is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+func (s *Subscription) run(f *changeFeed, cursor uint64) {
+	defer close(s.c)
+	for {
+		batch, changed, err := f.since(cursor)
+		if err != nil {
+			s.err = err
+			return
+		}
+		for _, change := range batch {
+			select {
+			case s.c <- change.Clone():
+				cursor = change.Seq
+			case <-s.done:
+				return
+			}
+		}
+		if len(batch) > 0 {
+			continue
+		}
+		select {
+		case <-changed:
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"sync"
+
+	"property-based/internal/domain"
+)
+
+type CredentialRepository interface {
+	Get(userID string) (*domain.Credential, error)
+	Save(cred *domain.Credential) error
+	Update(userID string, fn func(cred *domain.Credential)) (*domain.Credential, error)
+	Delete(userID string) error
+}
+
+type InMemoryCredentialRepository struct {
+	mu    sync.Mutex
+	creds map[string]domain.Credential
+}
+
+func NewInMemoryCredentialRepository() *InMemoryCredentialRepository {
+	return &InMemoryCredentialRepository{creds: make(map[string]domain.Credential)}
+}
+
+func (r *InMemoryCredentialRepository) Get(userID string) (*domain.Credential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cred, exists := r.creds[userID]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	return &cred, nil
+}
+
+func (r *InMemoryCredentialRepository) Save(cred *domain.Credential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.creds[cred.UserID] = *cred
+	return nil
+}
+
+func (r *InMemoryCredentialRepository) Update(userID string, fn func(cred *domain.Credential)) (*domain.Credential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cred, exists := r.creds[userID]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	fn(&cred)
+	cred.UserID = userID
+	r.creds[userID] = cred
+	return &cred, nil
+}
+
+func (r *InMemoryCredentialRepository) Delete(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.creds[userID]; !exists {
+		return domain.ErrNotFound
+	}
+	delete(r.creds, userID)
+	return nil
+}
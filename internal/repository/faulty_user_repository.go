@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"property-based/internal/domain"
+)
+
+var ErrInjectedFault = errors.New("injected repository fault")
+
+type Operation string
+
+const (
+	OpCreate     Operation = "Create"
+	OpGetByID    Operation = "GetByID"
+	OpGetByEmail Operation = "GetByEmail"
+	OpGetAll     Operation = "GetAll"
+	OpUpdate     Operation = "Update"
+	OpDelete     Operation = "Delete"
+	OpCount      Operation = "Count"
+
+	OpModify           Operation = "Modify"
+	OpFindByAgeRange   Operation = "FindByAgeRange"
+	OpFindByNamePrefix Operation = "FindByNamePrefix"
+	OpWatch            Operation = "Watch"
+	OpVersions         Operation = "Versions"
+)
+
+// Operations are the calls FaultyUserRepository injects faults into. Modify
+// draws the fault of the Update it replaces; the optional query, watch and
+// history methods pass through untouched.
+var Operations = []Operation{OpCreate, OpGetByID, OpGetByEmail, OpGetAll, OpUpdate, OpDelete, OpCount}
+
+// Fault describes what happens to a single repository call. The zero value
+// lets the call through untouched. Partial faults run the call against the
+// wrapped repository and then report Err anyway, like a lost acknowledgement.
+type Fault struct {
+	Err     error
+	Latency time.Duration
+	Partial bool
+}
+
+type FaultSchedule interface {
+	Next(op Operation) Fault
+}
+
+type FaultFunc func(op Operation) Fault
+
+func (f FaultFunc) Next(op Operation) Fault {
+	return f(op)
+}
+
+type ScriptedFaults struct {
+	mu     sync.Mutex
+	script map[Operation][]Fault
+}
+
+func NewScriptedFaults(script map[Operation][]Fault) *ScriptedFaults {
+	copied := make(map[Operation][]Fault, len(script))
+	for op, faults := range script {
+		copied[op] = append([]Fault(nil), faults...)
+	}
+	return &ScriptedFaults{script: copied}
+}
+
+func (s *ScriptedFaults) Next(op Operation) Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	faults := s.script[op]
+	if len(faults) == 0 {
+		return Fault{}
+	}
+	s.script[op] = faults[1:]
+	return faults[0]
+}
+
+type FaultyUserRepository struct {
+	inner    UserRepository
+	schedule FaultSchedule
+}
+
+func NewFaultyUserRepository(inner UserRepository, schedule FaultSchedule) *FaultyUserRepository {
+	return &FaultyUserRepository{inner: inner, schedule: schedule}
+}
+
+func (r *FaultyUserRepository) next(op Operation) Fault {
+	fault := r.schedule.Next(op)
+	if fault.Latency > 0 {
+		time.Sleep(fault.Latency)
+	}
+	return fault
+}
+
+func (r *FaultyUserRepository) Create(user *domain.User) error {
+	fault := r.next(OpCreate)
+	if fault.Err != nil && !fault.Partial {
+		return fault.Err
+	}
+	if err := r.inner.Create(user); err != nil {
+		return err
+	}
+	return fault.Err
+}
+
+func (r *FaultyUserRepository) GetByID(id string) (*domain.User, error) {
+	fault := r.next(OpGetByID)
+	if fault.Err != nil && !fault.Partial {
+		return nil, fault.Err
+	}
+	user, err := r.inner.GetByID(id)
+	if fault.Err != nil {
+		return nil, fault.Err
+	}
+	return user, err
+}
+
+func (r *FaultyUserRepository) GetByEmail(email string) (*domain.User, error) {
+	fault := r.next(OpGetByEmail)
+	if fault.Err != nil && !fault.Partial {
+		return nil, fault.Err
+	}
+	user, err := r.inner.GetByEmail(email)
+	if fault.Err != nil {
+		return nil, fault.Err
+	}
+	return user, err
+}
+
+func (r *FaultyUserRepository) GetAll() ([]*domain.User, error) {
+	fault := r.next(OpGetAll)
+	if fault.Err != nil && !fault.Partial {
+		return nil, fault.Err
+	}
+	users, err := r.inner.GetAll()
+	if fault.Err != nil {
+		return nil, fault.Err
+	}
+	return users, err
+}
+
+func (r *FaultyUserRepository) Update(user *domain.User) error {
+	fault := r.next(OpUpdate)
+	if fault.Err != nil && !fault.Partial {
+		return fault.Err
+	}
+	if err := r.inner.Update(user); err != nil {
+		return err
+	}
+	return fault.Err
+}
+
+func (r *FaultyUserRepository) Delete(id string) error {
+	fault := r.next(OpDelete)
+	if fault.Err != nil && !fault.Partial {
+		return fault.Err
+	}
+	if err := r.inner.Delete(id); err != nil {
+		return err
+	}
+	return fault.Err
+}
+
+// Count has no error channel, so only latency is injected.
+func (r *FaultyUserRepository) Count() int {
+	r.next(OpCount)
+	return r.inner.Count()
+}
+
+// Modify fails with errors.ErrUnsupported when the wrapped repository is
+// not a UserModifier.
+func (r *FaultyUserRepository) Modify(id string, fn func(user *domain.User) (bool, error)) (*domain.User, error) {
+	modifier, ok := r.inner.(UserModifier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	fault := r.next(OpUpdate)
+	if fault.Err != nil && !fault.Partial {
+		return nil, fault.Err
+	}
+	user, err := modifier.Modify(id, fn)
+	if err != nil {
+		return nil, err
+	}
+	if fault.Err != nil {
+		return nil, fault.Err
+	}
+	return user, nil
+}
+
+func (r *FaultyUserRepository) FindByAgeRange(min, max int) ([]*domain.User, error) {
+	q, ok := r.inner.(UserQuerier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return q.FindByAgeRange(min, max)
+}
+
+func (r *FaultyUserRepository) FindByNamePrefix(prefix string) ([]*domain.User, error) {
+	q, ok := r.inner.(UserQuerier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return q.FindByNamePrefix(prefix)
+}
+
+func (r *FaultyUserRepository) Versions(id string) ([]domain.UserVersion, error) {
+	h, ok := r.inner.(UserHistory)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return h.Versions(id)
+}
+
+func (r *FaultyUserRepository) Watch(after uint64) (*Subscription, error) {
+	w, ok := r.inner.(UserWatcher)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return w.Watch(after)
+}
+
+func (r *FaultyUserRepository) Head() uint64 {
+	if w, ok := r.inner.(UserWatcher); ok {
+		return w.Head()
+	}
+	return 0
+}
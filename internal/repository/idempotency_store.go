@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"property-based/internal/domain"
+)
+
+type IdempotencyStore interface {
+	// Get returns the record for key, or domain.ErrNotFound if there is none
+	// or it expired at or before now.
+	Get(key string, now time.Time) (*domain.IdempotencyRecord, error)
+	// Save stores rec, replacing any record with the same key.
+	Save(rec *domain.IdempotencyRecord) error
+	// Purge drops every record that expired at or before now and returns
+	// how many were removed.
+	Purge(now time.Time) int
+}
+
+type InMemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyRecord
+}
+
+func NewInMemoryIdempotencyStore() *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{records: make(map[string]domain.IdempotencyRecord)}
+}
+
+func (s *InMemoryIdempotencyStore) Get(key string, now time.Time) (*domain.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, exists := s.records[key]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	if !now.Before(rec.ExpiresAt) {
+		delete(s.records, key)
+		return nil, domain.ErrNotFound
+	}
+	rec.User = rec.User.Clone()
+	return &rec, nil
+}
+
+func (s *InMemoryIdempotencyStore) Save(rec *domain.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *rec
+	stored.User = rec.User.Clone()
+	s.records[rec.Key] = stored
+	return nil
+}
+
+func (s *InMemoryIdempotencyStore) Purge(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, key)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"property-based/internal/domain"
+	"property-based/internal/metrics"
+)
+
+// InstrumentedUserRepository records call counts, error codes and latency for
+// every call. For the in-memory backend the latency is dominated by the time
+// spent waiting for and holding its lock. It forwards the optional
+// UserModifier, UserQuerier, UserHistory and UserWatcher methods, which fail with
+// errors.ErrUnsupported when the wrapped repository lacks them.
+type InstrumentedUserRepository struct {
+	inner UserRepository
+	ops   *metrics.Operations
+}
+
+func NewInstrumentedUserRepository(inner UserRepository, ops *metrics.Operations) *InstrumentedUserRepository {
+	return &InstrumentedUserRepository{inner: inner, ops: ops}
+}
+
+func (r *InstrumentedUserRepository) Create(user *domain.User) error {
+	start := time.Now()
+	err := r.inner.Create(user)
+	r.ops.Observe(string(OpCreate), start, err)
+	return err
+}
+
+func (r *InstrumentedUserRepository) GetByID(id string) (*domain.User, error) {
+	start := time.Now()
+	user, err := r.inner.GetByID(id)
+	r.ops.Observe(string(OpGetByID), start, err)
+	return user, err
+}
+
+func (r *InstrumentedUserRepository) GetByEmail(email string) (*domain.User, error) {
+	start := time.Now()
+	user, err := r.inner.GetByEmail(email)
+	r.ops.Observe(string(OpGetByEmail), start, err)
+	return user, err
+}
+
+func (r *InstrumentedUserRepository) GetAll() ([]*domain.User, error) {
+	start := time.Now()
+	users, err := r.inner.GetAll()
+	r.ops.Observe(string(OpGetAll), start, err)
+	return users, err
+}
+
+func (r *InstrumentedUserRepository) Update(user *domain.User) error {
+	start := time.Now()
+	err := r.inner.Update(user)
+	r.ops.Observe(string(OpUpdate), start, err)
+	return err
+}
+
+func (r *InstrumentedUserRepository) Delete(id string) error {
+	start := time.Now()
+	err := r.inner.Delete(id)
+	r.ops.Observe(string(OpDelete), start, err)
+	return err
+}
+
+func (r *InstrumentedUserRepository) Count() int {
+	start := time.Now()
+	count := r.inner.Count()
+	r.ops.Observe(string(OpCount), start, nil)
+	return count
+}
+
+func (r *InstrumentedUserRepository) Modify(id string, fn func(user *domain.User) (bool, error)) (*domain.User, error) {
+	modifier, ok := r.inner.(UserModifier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	start := time.Now()
+	user, err := modifier.Modify(id, fn)
+	r.ops.Observe(string(OpModify), start, err)
+	return user, err
+}
+
+func (r *InstrumentedUserRepository) FindByAgeRange(min, max int) ([]*domain.User, error) {
+	q, ok := r.inner.(UserQuerier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	start := time.Now()
+	users, err := q.FindByAgeRange(min, max)
+	r.ops.Observe(string(OpFindByAgeRange), start, err)
+	return users, err
+}
+
+func (r *InstrumentedUserRepository) FindByNamePrefix(prefix string) ([]*domain.User, error) {
+	q, ok := r.inner.(UserQuerier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	start := time.Now()
+	users, err := q.FindByNamePrefix(prefix)
+	r.ops.Observe(string(OpFindByNamePrefix), start, err)
+	return users, err
+}
+
+func (r *InstrumentedUserRepository) Versions(id string) ([]domain.UserVersion, error) {
+	h, ok := r.inner.(UserHistory)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	start := time.Now()
+	versions, err := h.Versions(id)
+	r.ops.Observe(string(OpVersions), start, err)
+	return versions, err
+}
+
+func (r *InstrumentedUserRepository) Watch(after uint64) (*Subscription, error) {
+	w, ok := r.inner.(UserWatcher)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	start := time.Now()
+	sub, err := w.Watch(after)
+	r.ops.Observe(string(OpWatch), start, err)
+	return sub, err
+}
+
+func (r *InstrumentedUserRepository) Head() uint64 {
+	if w, ok := r.inner.(UserWatcher); ok {
+		return w.Head()
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"sync"
+
+	"property-based/internal/domain"
+)
+
+// MergeReservation identifies a pending audit record.
+type MergeReservation uint64
+
+// MergeAuditRepository records merges in two steps so that a merge can be
+// refused before it touches any user: Reserve before mutating, then Commit
+// once the merge is applied or Cancel if it was rolled back.
+type MergeAuditRepository interface {
+	// Reserve stores record as pending. Pending records are not returned
+	// by ForUser.
+	Reserve(record *domain.MergeRecord) (MergeReservation, error)
+	// Commit replaces the pending record with record and makes it visible.
+	Commit(reservation MergeReservation, record *domain.MergeRecord) error
+	// Cancel drops a pending record.
+	Cancel(reservation MergeReservation) error
+	// ForUser returns the committed merges userID took part in, on either
+	// side, oldest first.
+	ForUser(userID string) ([]domain.MergeRecord, error)
+}
+
+type InMemoryMergeAuditRepository struct {
+	mu      sync.RWMutex
+	records []domain.MergeRecord
+	pending map[MergeReservation]domain.MergeRecord
+	next    MergeReservation
+}
+
+func NewInMemoryMergeAuditRepository() *InMemoryMergeAuditRepository {
+	return &InMemoryMergeAuditRepository{pending: make(map[MergeReservation]domain.MergeRecord)}
+}
+
+func (r *InMemoryMergeAuditRepository) Reserve(record *domain.MergeRecord) (MergeReservation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next++
+	r.pending[r.next] = record.Clone()
+	return r.next, nil
+}
+
+func (r *InMemoryMergeAuditRepository) Commit(reservation MergeReservation, record *domain.MergeRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pending[reservation]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.pending, reservation)
+	r.records = append(r.records, record.Clone())
+	return nil
+}
+
+func (r *InMemoryMergeAuditRepository) Cancel(reservation MergeReservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pending[reservation]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.pending, reservation)
+	return nil
+}
+
+func (r *InMemoryMergeAuditRepository) ForUser(userID string) ([]domain.MergeRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := make([]domain.MergeRecord, 0)
+	for _, rec := range r.records {
+		if rec.KeptID == userID || rec.MergedID == userID {
+			records = append(records, rec.Clone())
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"property-based/internal/domain"
+)
+
+const DefaultShardCount = 32
+
+type userShard struct {
+	mu    sync.RWMutex
+	users map[string]*domain.User
+}
+
+type emailShard struct {
+	mu     sync.RWMutex
+	emails map[string]string
+}
+
+// ShardedUserRepository spreads users over shards by a hash of their ID so
+// writes to different users rarely contend. The email index is sharded the
+// same way by email; uniqueness holds globally because an email can only be
+// claimed under its email shard's lock.
+//
+// Writers always lock the user shard first and then the email shards in
+// index order, which rules out deadlocks. Readers never hold two locks at
+// once. GetAll and Count visit the shards one at a time, so under
+// concurrent writes they are not a single point-in-time snapshot.
+//
+// It implements UserModifier but not UserQuerier or UserHistory; the
+// service falls back to scanning for queries.
+type ShardedUserRepository struct {
+	users  []userShard
+	emails []emailShard
+}
+
+// NewShardedUserRepository returns a repository with the given number of
+// shards; values below 1 use DefaultShardCount.
+func NewShardedUserRepository(shards int) *ShardedUserRepository {
+	if shards < 1 {
+		shards = DefaultShardCount
+	}
+	r := &ShardedUserRepository{
+		users:  make([]userShard, shards),
+		emails: make([]emailShard, shards),
+	}
+	for i := range r.users {
+		r.users[i].users = make(map[string]*domain.User)
+		r.emails[i].emails = make(map[string]string)
+	}
+	return r
+}
+
+func (r *ShardedUserRepository) shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(r.users)))
+}
+
+func (r *ShardedUserRepository) userShard(id string) *userShard {
+	return &r.users[r.shardIndex(id)]
+}
+
+func (r *ShardedUserRepository) emailShard(email string) *emailShard {
+	return &r.emails[r.shardIndex(email)]
+}
+
+// lockEmails write-locks the shards of both emails in index order and
+// returns the matching unlock.
+func (r *ShardedUserRepository) lockEmails(a, b string) func() {
+	i, j := r.shardIndex(a), r.shardIndex(b)
+	if i == j {
+		r.emails[i].mu.Lock()
+		return r.emails[i].mu.Unlock
+	}
+	if i > j {
+		i, j = j, i
+	}
+	r.emails[i].mu.Lock()
+	r.emails[j].mu.Lock()
+	return func() {
+		r.emails[j].mu.Unlock()
+		r.emails[i].mu.Unlock()
+	}
+}
+
+func (r *ShardedUserRepository) Create(user *domain.User) error {
+	us := r.userShard(user.ID)
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	if _, exists := us.users[user.ID]; exists {
+		return domain.ErrAlreadyExists
+	}
+
+	es := r.emailShard(user.Email)
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if _, exists := es.emails[user.Email]; exists {
+		return domain.ErrAlreadyExists
+	}
+	es.emails[user.Email] = user.ID
+	us.users[user.ID] = user.Clone()
+	return nil
+}
+
+func (r *ShardedUserRepository) GetByID(id string) (*domain.User, error) {
+	us := r.userShard(id)
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	user, exists := us.users[id]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	return user.Clone(), nil
+}
+
+// GetByEmail resolves the email and then reads the owner without holding
+// both locks. If the owner's email changed in between, the email was free
+// at some instant during the call, so ErrNotFound is a valid answer.
+func (r *ShardedUserRepository) GetByEmail(email string) (*domain.User, error) {
+	es := r.emailShard(email)
+	es.mu.RLock()
+	id, exists := es.emails[email]
+	es.mu.RUnlock()
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	user, err := r.GetByID(id)
+	if err != nil || user.Email != email {
+		return nil, domain.ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *ShardedUserRepository) GetAll() ([]*domain.User, error) {
+	users := make([]*domain.User, 0)
+	for i := range r.users {
+		us := &r.users[i]
+		us.mu.RLock()
+		for _, user := range us.users {
+			users = append(users, user.Clone())
+		}
+		us.mu.RUnlock()
+	}
+	return users, nil
+}
+
+func (r *ShardedUserRepository) Update(user *domain.User) error {
+	us := r.userShard(user.ID)
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	oldUser, exists := us.users[user.ID]
+	if !exists {
+		return domain.ErrNotFound
+	}
+	if err := r.moveEmail(user.ID, oldUser.Email, user.Email); err != nil {
+		return err
+	}
+	us.users[user.ID] = user.Clone()
+	return nil
+}
+
+func (r *ShardedUserRepository) Modify(id string, fn func(user *domain.User) (bool, error)) (*domain.User, error) {
+	us := r.userShard(id)
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	oldUser, exists := us.users[id]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	user := oldUser.Clone()
+	changed, err := fn(user)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return oldUser.Clone(), nil
+	}
+	user.ID = id
+
+	if err := r.moveEmail(id, oldUser.Email, user.Email); err != nil {
+		return nil, err
+	}
+	us.users[id] = user.Clone()
+	return user, nil
+}
+
+// moveEmail reassigns id's email from one address to another. It must be
+// called with id's user shard locked for writing.
+func (r *ShardedUserRepository) moveEmail(id, from, to string) error {
+	if from == to {
+		return nil
+	}
+	unlock := r.lockEmails(from, to)
+	defer unlock()
+
+	target := r.emailShard(to)
+	if owner, exists := target.emails[to]; exists && owner != id {
+		return domain.ErrAlreadyExists
+	}
+	delete(r.emailShard(from).emails, from)
+	target.emails[to] = id
+	return nil
+}
+
+func (r *ShardedUserRepository) Delete(id string) error {
+	us := r.userShard(id)
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	user, exists := us.users[id]
+	if !exists {
+		return domain.ErrNotFound
+	}
+
+	es := r.emailShard(user.Email)
+	es.mu.Lock()
+	delete(es.emails, user.Email)
+	es.mu.Unlock()
+
+	delete(us.users, id)
+	return nil
+}
+
+func (r *ShardedUserRepository) Count() int {
+	count := 0
+	for i := range r.users {
+		us := &r.users[i]
+		us.mu.RLock()
+		count += len(us.users)
+		us.mu.RUnlock()
+	}
+	return count
+}
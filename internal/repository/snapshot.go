@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"property-based/internal/domain"
+)
+
+// SnapshotVersion is the version WriteTo writes. Version 2 added custom
+// attributes; ReadSnapshot still accepts version 1 files.
+const (
+	SnapshotFormat  = "user-repository-snapshot"
+	SnapshotVersion = 2
+)
+
+var (
+	ErrSnapshotCorrupt     = errors.New("snapshot is corrupt")
+	ErrSnapshotUnsupported = errors.New("snapshot format or version is not supported")
+)
+
+// Snapshot is a point-in-time copy of an InMemoryUserRepository: its users
+// and the email index derived from them.
+type Snapshot struct {
+	takenAt time.Time
+	users   []*domain.User
+	emails  map[string]string
+}
+
+func (s *Snapshot) TakenAt() time.Time {
+	return s.takenAt
+}
+
+func (s *Snapshot) Len() int {
+	return len(s.users)
+}
+
+// Users returns copies of the users in the snapshot, ordered by ID.
+func (s *Snapshot) Users() []*domain.User {
+	users := make([]*domain.User, len(s.users))
+	for i, u := range s.users {
+		users[i] = u.Clone()
+	}
+	return users
+}
+
+// Snapshot captures the repository as of one instant. Stored users are
+// replaced on write, never mutated, so only the references are copied under
+// the read lock; cloning and encoding happen after it is released, and
+// writers wait for a map copy rather than for the whole dump.
+func (r *InMemoryUserRepository) Snapshot() *Snapshot {
+	r.mu.RLock()
+	users := make([]*domain.User, 0, len(r.users))
+	for _, u := range r.users {
+		users = append(users, u)
+	}
+	emails := make(map[string]string, len(r.emails))
+	for email, id := range r.emails {
+		emails[email] = id
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return &Snapshot{takenAt: time.Now().UTC(), users: users, emails: emails}
+}
+
+// Restore replaces the whole contents of the repository with s. The snapshot
+// is checked first, so an inconsistent one leaves the repository untouched.
+// History starts over: every restored user gets a single fresh version, and
+// watchers are cut off since the change feed cannot describe the jump.
+func (r *InMemoryUserRepository) Restore(s *Snapshot) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+
+	users := make(map[string]*domain.User, len(s.users))
+	emails := make(map[string]string, len(s.users))
+	for _, u := range s.users {
+		users[u.ID] = u.Clone()
+		emails[u.Email] = u.ID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users = users
+	r.emails = emails
+	r.ages = nil
+	r.names = nil
+	r.history = make(map[string][]domain.UserVersion, len(users))
+	for _, u := range s.users {
+		r.index(u)
+		r.record(u, false)
+	}
+	r.changes.reset()
+	return nil
+}
+
+// check verifies that the email index is exactly the one derived from the
+// users, which also rules out duplicate IDs and emails.
+func (s *Snapshot) check() error {
+	ids := make(map[string]bool, len(s.users))
+	for _, u := range s.users {
+		if u == nil || u.ID == "" {
+			return fmt.Errorf("%w: user without ID", ErrSnapshotCorrupt)
+		}
+		if ids[u.ID] {
+			return fmt.Errorf("%w: duplicate user ID %q", ErrSnapshotCorrupt, u.ID)
+		}
+		ids[u.ID] = true
+		if s.emails[u.Email] != u.ID {
+			return fmt.Errorf("%w: email index does not map %q to user %q", ErrSnapshotCorrupt, u.Email, u.ID)
+		}
+	}
+	if len(s.emails) != len(s.users) {
+		return fmt.Errorf("%w: email index has %d entries for %d users", ErrSnapshotCorrupt, len(s.emails), len(s.users))
+	}
+	return nil
+}
+
+// snapshotUser is the on-disk form of a user, decoupled from domain.User so
+// that renaming a field does not silently change the format.
+type snapshotUser struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	Age           int       `json:"age"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// Attributes are written with their types, so reading them back does
+	// not depend on the attribute schema in force.
+	Attributes domain.Attributes `json:"attributes,omitempty"`
+}
+
+type snapshotPayload struct {
+	TakenAt time.Time         `json:"taken_at"`
+	Users   []snapshotUser    `json:"users"`
+	Emails  map[string]string `json:"emails"`
+}
+
+// snapshotEnvelope wraps the payload with what is needed to reject files
+// from another format or version and to detect corruption. The checksum
+// covers the payload bytes exactly as written.
+type snapshotEnvelope struct {
+	Format   string          `json:"format"`
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// WriteTo encodes s as versioned, checksummed JSON.
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	payload := snapshotPayload{TakenAt: s.takenAt, Users: make([]snapshotUser, len(s.users)), Emails: s.emails}
+	for i, u := range s.users {
+		payload.Users[i] = snapshotUser{
+			ID: u.ID, Name: u.Name, Email: u.Email, Age: u.Age,
+			EmailVerified: u.EmailVerified, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt,
+			Attributes: u.Attributes,
+		}
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	env, err := json.Marshal(snapshotEnvelope{
+		Format:   SnapshotFormat,
+		Version:  SnapshotVersion,
+		Checksum: checksum(raw),
+		Payload:  raw,
+	})
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(env, '\n'))
+	return int64(n), err
+}
+
+// ReadSnapshot decodes a snapshot written by WriteTo, rejecting unknown
+// formats or versions and payloads that do not match their checksum.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var env snapshotEnvelope
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if env.Format != SnapshotFormat || env.Version < 1 || env.Version > SnapshotVersion {
+		return nil, fmt.Errorf("%w: %q version %d", ErrSnapshotUnsupported, env.Format, env.Version)
+	}
+	if env.Checksum != checksum(env.Payload) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+
+	var payload snapshotPayload
+	dec := json.NewDecoder(bytes.NewReader(env.Payload))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	s := &Snapshot{takenAt: payload.TakenAt, users: make([]*domain.User, len(payload.Users)), emails: payload.Emails}
+	for i, u := range payload.Users {
+		s.users[i] = &domain.User{
+			ID: u.ID, Name: u.Name, Email: u.Email, Age: u.Age,
+			EmailVerified: u.EmailVerified, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt,
+			Attributes: u.Attributes.Clone(),
+		}
+	}
+	if s.emails == nil {
+		s.emails = map[string]string{}
+	}
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
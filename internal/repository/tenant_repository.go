@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"property-based/internal/domain"
+)
+
+// TenantRepositories gives every tenant its own UserRepository, so users,
+// the email index, counts and listings never cross tenant boundaries.
+// Repositories are created by OpenTenant, on the first write that adds a
+// user; lookups with ForTenant never allocate one.
+type TenantRepositories struct {
+	mu      sync.Mutex
+	factory func() UserRepository
+	tenants map[string]UserRepository
+}
+
+func NewTenantRepositories(factory func() UserRepository) *TenantRepositories {
+	return &TenantRepositories{
+		factory: factory,
+		tenants: make(map[string]UserRepository),
+	}
+}
+
+// TenantKey is the ID a tenant is stored under: tenantID without
+// surrounding whitespace. It fails with domain.ErrInvalidTenant for a blank
+// ID. Anything keyed by tenant alongside the repositories should use it.
+func TenantKey(tenantID string) (string, error) {
+	tenantID = strings.TrimSpace(tenantID)
+	if tenantID == "" {
+		return "", domain.ErrInvalidTenant
+	}
+	return tenantID, nil
+}
+
+// ForTenant returns the repository of an existing tenant, or
+// domain.ErrNotFound when tenantID has never been opened.
+func (t *TenantRepositories) ForTenant(tenantID string) (UserRepository, error) {
+	tenantID, err := TenantKey(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	repo, exists := t.tenants[tenantID]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	return repo, nil
+}
+
+// OpenTenant returns the repository of tenantID, creating it on first use.
+func (t *TenantRepositories) OpenTenant(tenantID string) (UserRepository, error) {
+	tenantID, err := TenantKey(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	repo, exists := t.tenants[tenantID]
+	if !exists {
+		repo = t.factory()
+		t.tenants[tenantID] = repo
+	}
+	return repo, nil
+}
+
+func (t *TenantRepositories) Tenants() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ids := make([]string, 0, len(t.tenants))
+	for id := range t.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"property-based/internal/domain"
 )
@@ -16,17 +19,75 @@ type UserRepository interface {
 	Count() int
 }
 
+// UserQuerier is implemented by repositories that keep secondary indexes.
+// Results are ordered by the index: age then ID, or lower-cased name then ID.
+//
+// Decorators implement the optional interfaces whatever they wrap, and fail
+// with errors.ErrUnsupported when the wrapped repository does not; callers
+// fall back as if the interface were missing.
+type UserQuerier interface {
+	FindByAgeRange(min, max int) ([]*domain.User, error)
+	FindByNamePrefix(prefix string) ([]*domain.User, error)
+}
+
+// UserModifier is implemented by repositories that can apply a
+// read-modify-write atomically. fn receives a copy of the stored user and
+// reports whether it changed anything; unchanged users are not written.
+type UserModifier interface {
+	Modify(id string, fn func(user *domain.User) (bool, error)) (*domain.User, error)
+}
+
+// UserHistory is implemented by repositories that keep past versions of
+// users, oldest first, including a tombstone for deletions.
+type UserHistory interface {
+	Versions(id string) ([]domain.UserVersion, error)
+}
+
+const DefaultHistoryLimit = 100
+
+type Option func(*InMemoryUserRepository)
+
+// WithHistoryLimit bounds how many versions are retained per user; older ones
+// are dropped first. A limit below 1 retains every version.
+func WithHistoryLimit(limit int) Option {
+	return func(r *InMemoryUserRepository) {
+		r.historyLimit = limit
+	}
+}
+
+type indexEntry struct {
+	age  int
+	name string
+	id   string
+}
+
 type InMemoryUserRepository struct {
 	mu     sync.RWMutex
 	users  map[string]*domain.User
 	emails map[string]string
+	ages   []indexEntry
+	names  []indexEntry
+
+	history      map[string][]domain.UserVersion
+	historyLimit int
+
+	changes         *changeFeed
+	changeRetention int
 }
 
-func NewInMemoryUserRepository() *InMemoryUserRepository {
-	return &InMemoryUserRepository{
-		users:  make(map[string]*domain.User),
-		emails: make(map[string]string),
+func NewInMemoryUserRepository(opts ...Option) *InMemoryUserRepository {
+	r := &InMemoryUserRepository{
+		users:           make(map[string]*domain.User),
+		emails:          make(map[string]string),
+		history:         make(map[string][]domain.UserVersion),
+		historyLimit:    DefaultHistoryLimit,
+		changeRetention: DefaultChangeRetention,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	r.changes = newChangeFeed(r.changeRetention)
+	return r
 }
 
 func (r *InMemoryUserRepository) Create(user *domain.User) error {
@@ -40,6 +101,9 @@ func (r *InMemoryUserRepository) Create(user *domain.User) error {
 	}
 	r.users[user.ID] = user.Clone()
 	r.emails[user.Email] = user.ID
+	r.index(user)
+	r.record(user, false)
+	r.changes.publish(domain.ChangeCreated, user)
 
 	return nil
 }
@@ -99,10 +163,50 @@ func (r *InMemoryUserRepository) Update(user *domain.User) error {
 		r.emails[user.Email] = user.ID
 	}
 
+	r.unindex(oldUser)
 	r.users[user.ID] = user.Clone()
+	r.index(user)
+	r.record(user, false)
+	r.changes.publish(domain.ChangeUpdated, user)
 	return nil
 }
 
+func (r *InMemoryUserRepository) Modify(id string, fn func(user *domain.User) (bool, error)) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldUser, exists := r.users[id]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	user := oldUser.Clone()
+	changed, err := fn(user)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return oldUser.Clone(), nil
+	}
+	user.ID = id
+
+	if oldUser.Email != user.Email {
+		if existingUserID, exists := r.emails[user.Email]; exists && existingUserID != id {
+			return nil, domain.ErrAlreadyExists
+		}
+
+		delete(r.emails, oldUser.Email)
+		r.emails[user.Email] = id
+	}
+
+	r.unindex(oldUser)
+	r.users[id] = user.Clone()
+	r.index(user)
+	r.record(user, false)
+	r.changes.publish(domain.ChangeUpdated, user)
+	return user, nil
+}
+
 func (r *InMemoryUserRepository) Delete(id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -114,6 +218,9 @@ func (r *InMemoryUserRepository) Delete(id string) error {
 
 	delete(r.users, id)
 	delete(r.emails, user.Email)
+	r.unindex(user)
+	r.record(user, true)
+	r.changes.publish(domain.ChangeDeleted, user)
 
 	return nil
 }
@@ -124,3 +231,127 @@ func (r *InMemoryUserRepository) Count() int {
 
 	return len(r.users)
 }
+
+func (r *InMemoryUserRepository) FindByAgeRange(min, max int) ([]*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	start := sort.Search(len(r.ages), func(i int) bool { return r.ages[i].age >= min })
+	users := make([]*domain.User, 0)
+	for _, entry := range r.ages[start:] {
+		if entry.age > max {
+			break
+		}
+		users = append(users, r.users[entry.id].Clone())
+	}
+
+	return users, nil
+}
+
+func (r *InMemoryUserRepository) FindByNamePrefix(prefix string) ([]*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prefix = strings.ToLower(prefix)
+	start := sort.Search(len(r.names), func(i int) bool { return r.names[i].name >= prefix })
+	users := make([]*domain.User, 0)
+	for _, entry := range r.names[start:] {
+		if !strings.HasPrefix(entry.name, prefix) {
+			break
+		}
+		users = append(users, r.users[entry.id].Clone())
+	}
+
+	return users, nil
+}
+
+func (r *InMemoryUserRepository) Watch(after uint64) (*Subscription, error) {
+	return r.changes.watch(after)
+}
+
+func (r *InMemoryUserRepository) Head() uint64 {
+	return r.changes.head()
+}
+
+func (r *InMemoryUserRepository) Versions(id string) ([]domain.UserVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions, exists := r.history[id]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	out := make([]domain.UserVersion, len(versions))
+	for i, v := range versions {
+		out[i] = v.Clone()
+	}
+	return out, nil
+}
+
+// record must be called with r.mu held for writing.
+func (r *InMemoryUserRepository) record(user *domain.User, deleted bool) {
+	versions := r.history[user.ID]
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1].Version + 1
+	}
+
+	versions = append(versions, domain.UserVersion{
+		Version:    next,
+		RecordedAt: time.Now().UTC(),
+		Deleted:    deleted,
+		User:       user.Clone(),
+	})
+	if r.historyLimit > 0 && len(versions) > r.historyLimit {
+		versions = append([]domain.UserVersion(nil), versions[len(versions)-r.historyLimit:]...)
+	}
+	r.history[user.ID] = versions
+}
+
+func ageLess(a, b indexEntry) bool {
+	if a.age != b.age {
+		return a.age < b.age
+	}
+	return a.id < b.id
+}
+
+func nameLess(a, b indexEntry) bool {
+	if a.name != b.name {
+		return a.name < b.name
+	}
+	return a.id < b.id
+}
+
+func indexEntryFor(user *domain.User) indexEntry {
+	return indexEntry{age: user.Age, name: strings.ToLower(user.Name), id: user.ID}
+}
+
+func insertSorted(entries []indexEntry, entry indexEntry, less func(a, b indexEntry) bool) []indexEntry {
+	i := sort.Search(len(entries), func(i int) bool { return !less(entries[i], entry) })
+	entries = append(entries, indexEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+	return entries
+}
+
+func removeSorted(entries []indexEntry, entry indexEntry, less func(a, b indexEntry) bool) []indexEntry {
+	i := sort.Search(len(entries), func(i int) bool { return !less(entries[i], entry) })
+	if i < len(entries) && entries[i] == entry {
+		entries = append(entries[:i], entries[i+1:]...)
+	}
+	return entries
+}
+
+// index and unindex must be called with r.mu held for writing.
+func (r *InMemoryUserRepository) index(user *domain.User) {
+	entry := indexEntryFor(user)
+	r.ages = insertSorted(r.ages, entry, ageLess)
+	r.names = insertSorted(r.names, entry, nameLess)
+}
+
+func (r *InMemoryUserRepository) unindex(user *domain.User) {
+	entry := indexEntryFor(user)
+	r.ages = removeSorted(r.ages, entry, ageLess)
+	r.names = removeSorted(r.names, entry, nameLess)
+}
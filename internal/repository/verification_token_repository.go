@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"property-based/internal/domain"
+)
+
+type VerificationTokenRepository interface {
+	Save(token *domain.VerificationToken) error
+	Consume(hash string) (*domain.VerificationToken, error)
+	// Purge drops every token that expired at or before now and returns
+	// how many were removed.
+	Purge(now time.Time) int
+}
+
+type InMemoryVerificationTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]domain.VerificationToken
+}
+
+func NewInMemoryVerificationTokenRepository() *InMemoryVerificationTokenRepository {
+	return &InMemoryVerificationTokenRepository{tokens: make(map[string]domain.VerificationToken)}
+}
+
+func (r *InMemoryVerificationTokenRepository) Save(token *domain.VerificationToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tokens[token.Hash]; exists {
+		return domain.ErrAlreadyExists
+	}
+	r.tokens[token.Hash] = *token
+	return nil
+}
+
+// Consume marks the token as used and returns it as it was before, so only
+// one caller ever sees Used == false.
+func (r *InMemoryVerificationTokenRepository) Consume(hash string) (*domain.VerificationToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, exists := r.tokens[hash]
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	consumed := token
+	consumed.Used = true
+	r.tokens[hash] = consumed
+	return &token, nil
+}
+
+func (r *InMemoryVerificationTokenRepository) Purge(now time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	for hash, token := range r.tokens {
+		if !now.Before(token.ExpiresAt) {
+			delete(r.tokens, hash)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+
+	"property-based/internal/auth"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+)
+
+// AuthorizedUserService enforces auth.Authorize for one principal before
+// delegating. Transports build one per request.
+type AuthorizedUserService struct {
+	inner     UserOperations
+	principal auth.Principal
+}
+
+func NewAuthorizedUserService(inner UserOperations, principal auth.Principal) *AuthorizedUserService {
+	return &AuthorizedUserService{inner: inner, principal: principal}
+}
+
+func (s *AuthorizedUserService) CreateUser(name, email string, age int) (*domain.User, error) {
+	if err := auth.Authorize(s.principal, auth.ActionCreate, ""); err != nil {
+		return nil, err
+	}
+	return s.inner.CreateUser(name, email, age)
+}
+
+func (s *AuthorizedUserService) CreateUserWithAttributes(name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+	if err := auth.Authorize(s.principal, auth.ActionCreate, ""); err != nil {
+		return nil, err
+	}
+	return s.inner.CreateUserWithAttributes(name, email, age, attrs)
+}
+
+func (s *AuthorizedUserService) GetUser(id string) (*domain.User, error) {
+	if err := auth.Authorize(s.principal, auth.ActionRead, id); err != nil {
+		return nil, err
+	}
+	return s.inner.GetUser(id)
+}
+
+// GetUserByEmail only learns the target after the lookup, so callers who may
+// not read the result get ErrForbidden whether or not the email exists.
+func (s *AuthorizedUserService) GetUserByEmail(email string) (*domain.User, error) {
+	if err := auth.Authorize(s.principal, auth.ActionList, ""); err == nil {
+		return s.inner.GetUserByEmail(email)
+	}
+
+	user, err := s.inner.GetUserByEmail(email)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	if user == nil {
+		return nil, domain.ErrForbidden
+	}
+	if err := auth.Authorize(s.principal, auth.ActionRead, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *AuthorizedUserService) GetAllUsers() ([]*domain.User, error) {
+	if err := auth.Authorize(s.principal, auth.ActionList, ""); err != nil {
+		return nil, err
+	}
+	return s.inner.GetAllUsers()
+}
+
+func (s *AuthorizedUserService) FindUsersByAttributes(filters ...domain.AttributeFilter) ([]*domain.User, error) {
+	if err := auth.Authorize(s.principal, auth.ActionList, ""); err != nil {
+		return nil, err
+	}
+	return s.inner.FindUsersByAttributes(filters...)
+}
+
+func (s *AuthorizedUserService) UpdateUser(id, name, email string, age int) (*domain.User, error) {
+	if err := auth.Authorize(s.principal, auth.ActionUpdate, id); err != nil {
+		return nil, err
+	}
+	return s.inner.UpdateUser(id, name, email, age)
+}
+
+func (s *AuthorizedUserService) PatchUser(id string, patch domain.UserPatch) (*domain.User, error) {
+	if err := auth.Authorize(s.principal, auth.ActionUpdate, id); err != nil {
+		return nil, err
+	}
+	return s.inner.PatchUser(id, patch)
+}
+
+func (s *AuthorizedUserService) DeleteUser(id string) error {
+	if err := auth.Authorize(s.principal, auth.ActionDelete, id); err != nil {
+		return err
+	}
+	return s.inner.DeleteUser(id)
+}
+
+// CountUsers has no error channel and reveals no user data, so every
+// principal may call it.
+func (s *AuthorizedUserService) CountUsers() int {
+	return s.inner.CountUsers()
+}
+
+// AuthorizedUserWatcher guards a change feed like AuthorizedUserService
+// guards reads: every event carries a full user record, so only principals
+// allowed to list users may watch.
+type AuthorizedUserWatcher struct {
+	inner     repository.UserWatcher
+	principal auth.Principal
+}
+
+func NewAuthorizedUserWatcher(inner repository.UserWatcher, principal auth.Principal) *AuthorizedUserWatcher {
+	return &AuthorizedUserWatcher{inner: inner, principal: principal}
+}
+
+func (w *AuthorizedUserWatcher) Watch(after uint64) (*repository.Subscription, error) {
+	if err := auth.Authorize(w.principal, auth.ActionList, ""); err != nil {
+		return nil, err
+	}
+	return w.inner.Watch(after)
+}
+
+// Head reveals no more than CountUsers, so every principal may call it.
+func (w *AuthorizedUserWatcher) Head() uint64 {
+	return w.inner.Head()
+}
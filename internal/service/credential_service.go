@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"property-based/internal/auth"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+)
+
+type CredentialConfig struct {
+	Iterations        int
+	MaxFailedAttempts int
+	LockoutDuration   time.Duration
+	Now               func() time.Time
+}
+
+var DefaultCredentialConfig = CredentialConfig{
+	Iterations:        auth.DefaultPasswordIterations,
+	MaxFailedAttempts: 5,
+	LockoutDuration:   15 * time.Minute,
+}
+
+// CredentialService manages passwords for existing users. Hashes live in
+// their own repository and never appear on domain.User.
+type CredentialService struct {
+	users repository.UserRepository
+	creds repository.CredentialRepository
+	cfg   CredentialConfig
+	locks keyLocks
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+func NewCredentialService(users repository.UserRepository, creds repository.CredentialRepository, cfg CredentialConfig) *CredentialService {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Iterations < 1 {
+		cfg.Iterations = auth.DefaultPasswordIterations
+	}
+	return &CredentialService{users: users, creds: creds, cfg: cfg}
+}
+
+// SetPassword replaces the user's password and clears any lockout.
+func (s *CredentialService) SetPassword(userID, password string) error {
+	if err := domain.ValidatePassword(password); err != nil {
+		return err
+	}
+	if _, err := s.users.GetByID(userID); err != nil {
+		return err
+	}
+
+	hash, err := auth.HashPassword(password, s.cfg.Iterations)
+	if err != nil {
+		return err
+	}
+	return s.creds.Save(&domain.Credential{
+		UserID:       userID,
+		PasswordHash: hash,
+		UpdatedAt:    s.cfg.Now().UTC(),
+	})
+}
+
+// VerifyPassword returns nil for a correct password, ErrInvalidCredentials
+// for a wrong one or an unknown user, and ErrAccountLocked while the account
+// is locked, even if the password is correct.
+//
+// Guesses for the same user are checked one at a time, so concurrent ones
+// cannot all be evaluated against the state from before the lockout.
+func (s *CredentialService) VerifyPassword(userID, password string) error {
+	if _, err := s.users.GetByID(userID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return s.rejectUnknown(password)
+		}
+		return err
+	}
+
+	unlock := s.locks.lock(userID)
+	defer unlock()
+
+	cred, err := s.creds.Get(userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return s.rejectUnknown(password)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := s.cfg.Now()
+	if now.Before(cred.LockedUntil) {
+		return domain.ErrAccountLocked
+	}
+
+	ok, err := auth.CheckPassword(cred.PasswordHash, password)
+	if err != nil {
+		return err
+	}
+
+	locked := false
+	_, err = s.creds.Update(userID, func(c *domain.Credential) {
+		// Another service sharing the repository may have locked the
+		// account while the password was being checked.
+		if now.Before(c.LockedUntil) {
+			locked = true
+			return
+		}
+		if ok {
+			c.FailedAttempts = 0
+			return
+		}
+		c.FailedAttempts++
+		if s.cfg.MaxFailedAttempts > 0 && c.FailedAttempts >= s.cfg.MaxFailedAttempts {
+			c.FailedAttempts = 0
+			c.LockedUntil = now.Add(s.cfg.LockoutDuration)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if locked {
+		return domain.ErrAccountLocked
+	}
+	if !ok {
+		return domain.ErrInvalidCredentials
+	}
+	return nil
+}
+
+func (s *CredentialService) Login(email, password string) (*domain.User, error) {
+	user, err := s.users.GetByEmail(email)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, s.rejectUnknown(password)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.VerifyPassword(user.ID, password); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *CredentialService) RemovePassword(userID string) error {
+	return s.creds.Delete(userID)
+}
+
+// rejectUnknown checks password against a fixed hash of the configured cost
+// before failing, so that a user without a password, or no user at all,
+// takes as long to reject as a wrong password and response times do not
+// reveal which emails are registered.
+func (s *CredentialService) rejectUnknown(password string) error {
+	s.dummyOnce.Do(func() {
+		s.dummyHash, _ = auth.HashPassword("unregistered-user-0", s.cfg.Iterations)
+	})
+	if s.dummyHash != "" {
+		_, _ = auth.CheckPassword(s.dummyHash, password)
+	}
+	return domain.ErrInvalidCredentials
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+)
+
+type DedupConfig struct {
+	// NameThreshold is the lowest domain.NameSimilarity at which two users
+	// are reported as likely duplicates on their names alone.
+	NameThreshold float64
+	Now           func() time.Time
+	// Credentials, when set, is where the password of a merged user is
+	// deleted.
+	Credentials repository.CredentialRepository
+}
+
+var DefaultDedupConfig = DedupConfig{
+	NameThreshold: 0.85,
+}
+
+type MatchReason string
+
+const (
+	// MatchEmail means both addresses normalize to the same mailbox.
+	MatchEmail MatchReason = "email"
+	// MatchName means the names are at least NameThreshold similar.
+	MatchName MatchReason = "name"
+)
+
+// DuplicateMatch is one pair of users suspected to be the same person, with
+// A < B. Similarity is their name similarity whatever the reason.
+type DuplicateMatch struct {
+	A, B       string
+	Reason     MatchReason
+	Similarity float64
+}
+
+// DuplicateCluster groups users connected by matches, directly or through
+// other members. UserIDs are sorted.
+type DuplicateCluster struct {
+	UserIDs []string
+	Matches []DuplicateMatch
+}
+
+type DedupService struct {
+	users repository.UserRepository
+	audit repository.MergeAuditRepository
+	cfg   DedupConfig
+}
+
+func NewDedupService(users repository.UserRepository, audit repository.MergeAuditRepository, cfg DedupConfig) *DedupService {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.NameThreshold <= 0 || cfg.NameThreshold > 1 {
+		cfg.NameThreshold = DefaultDedupConfig.NameThreshold
+	}
+	return &DedupService{users: users, audit: audit, cfg: cfg}
+}
+
+// FindDuplicates compares every pair of users and reports the clusters of
+// likely duplicates, ordered by their first ID. It is quadratic in the
+// number of users and meant for offline passes after imports.
+func (s *DedupService) FindDuplicates() ([]DuplicateCluster, error) {
+	users, err := s.users.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	mailboxes := make([]string, len(users))
+	names := make([]string, len(users))
+	for i, u := range users {
+		mailboxes[i] = domain.NormalizeEmail(u.Email)
+		names[i] = domain.NormalizeName(u.Name)
+	}
+
+	parent := make([]int, len(users))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	var matches []DuplicateMatch
+	var matchFrom []int
+	for i := range users {
+		for j := i + 1; j < len(users); j++ {
+			reason := MatchEmail
+			if mailboxes[i] != mailboxes[j] {
+				// An edit distance of at least the length difference rules
+				// the pair out without computing it.
+				longest := max(len(names[i]), len(names[j]))
+				if float64(abs(len(names[i])-len(names[j]))) > (1-s.cfg.NameThreshold)*float64(longest) {
+					continue
+				}
+				reason = MatchName
+			}
+			similarity := domain.NameSimilarity(names[i], names[j])
+			if reason == MatchName && similarity < s.cfg.NameThreshold {
+				continue
+			}
+			matches = append(matches, DuplicateMatch{A: users[i].ID, B: users[j].ID, Reason: reason, Similarity: similarity})
+			parent[find(j)] = find(i)
+			matchFrom = append(matchFrom, i)
+		}
+	}
+
+	byRoot := map[int]*DuplicateCluster{}
+	var roots []int
+	for i, u := range users {
+		root := find(i)
+		if byRoot[root] == nil {
+			byRoot[root] = &DuplicateCluster{}
+			roots = append(roots, root)
+		}
+		byRoot[root].UserIDs = append(byRoot[root].UserIDs, u.ID)
+	}
+	for k, m := range matches {
+		c := byRoot[find(matchFrom[k])]
+		c.Matches = append(c.Matches, m)
+	}
+
+	clusters := make([]DuplicateCluster, 0)
+	for _, root := range roots {
+		if c := byRoot[root]; len(c.UserIDs) > 1 {
+			clusters = append(clusters, *c)
+		}
+	}
+	return clusters, nil
+}
+
+// MergeUsers folds the user mergeID into keepID and deletes it, which frees
+// its email. The kept user keeps its own name, email, age and verification,
+// takes the earlier CreatedAt of the two, and gains the custom attributes
+// only the merged user had. Both users as they were and the result are
+// appended to the audit trail. Credentials and tokens of the merged user are
+// not carried over, and its credentials are deleted with it.
+//
+// The merge is all or nothing: both users are read and the audit record
+// reserved before anything is written, and if a later step fails the steps
+// already applied are undone before the error is returned.
+func (s *DedupService) MergeUsers(keepID, mergeID string) (*domain.User, error) {
+	if keepID == mergeID {
+		return nil, domain.ErrInvalidMerge
+	}
+	merged, err := s.users.GetByID(mergeID)
+	if err != nil {
+		return nil, err
+	}
+	kept, err := s.users.GetByID(keepID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.cfg.Now().UTC()
+	planned := kept.Clone()
+	mergeInto(planned, merged, now)
+	record := &domain.MergeRecord{
+		KeptID:   keepID,
+		MergedID: mergeID,
+		Kept:     kept,
+		Merged:   merged,
+		Result:   planned,
+		MergedAt: now,
+	}
+	reservation, err := s.audit.Reserve(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var undo []func() error
+	fail := func(err error) (*domain.User, error) {
+		for i := len(undo) - 1; i >= 0; i-- {
+			err = errors.Join(err, undo[i]())
+		}
+		return nil, errors.Join(err, s.audit.Cancel(reservation))
+	}
+
+	// The kept user is read again inside the write, so a concurrent update
+	// since the first read is neither lost nor audited wrongly.
+	var before *domain.User
+	combine := func(user *domain.User) (bool, error) {
+		before = user.Clone()
+		return mergeInto(user, merged, now), nil
+	}
+	result, err := modify(s.users, keepID, combine)
+	// A failed write may still have been applied, so the kept user is
+	// restored whenever the write got as far as reading it.
+	if before != nil {
+		undo = append(undo, func() error { return s.restore(before) })
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	undo = append(undo, func() error { return s.recreate(merged) })
+	if err := s.users.Delete(mergeID); err != nil {
+		return fail(err)
+	}
+	if err := deleteCredentials(s.cfg.Credentials, mergeID); err != nil {
+		return fail(err)
+	}
+
+	record.Kept, record.Result = before, result
+	if err := s.audit.Commit(reservation, record); err != nil {
+		return fail(err)
+	}
+	return result, nil
+}
+
+// mergeInto applies the merge rules of MergeUsers to user and reports
+// whether it changed.
+func mergeInto(user, merged *domain.User, now time.Time) bool {
+	changed := false
+	if merged.CreatedAt.Before(user.CreatedAt) {
+		user.CreatedAt = merged.CreatedAt
+		changed = true
+	}
+	for name, v := range merged.Attributes {
+		if _, ok := user.Attributes[name]; !ok {
+			if user.Attributes == nil {
+				user.Attributes = domain.Attributes{}
+			}
+			user.Attributes[name] = v
+			changed = true
+		}
+	}
+	if changed {
+		user.UpdatedAt = now
+	}
+	return changed
+}
+
+// restore writes user back as it was before a failed merge.
+func (s *DedupService) restore(user *domain.User) error {
+	_, err := modify(s.users, user.ID, func(current *domain.User) (bool, error) {
+		*current = *user.Clone()
+		return true, nil
+	})
+	return err
+}
+
+// recreate brings back a merged user whose deletion went through even
+// though the merge failed.
+func (s *DedupService) recreate(user *domain.User) error {
+	_, err := s.users.GetByID(user.ID)
+	if !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	return s.users.Create(user.Clone())
+}
+
+// MergeHistory returns the merges userID took part in, oldest first.
+func (s *DedupService) MergeHistory(userID string) ([]domain.MergeRecord, error) {
+	return s.audit.ForUser(userID)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"property-based/internal/auth"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+)
+
+type IdempotencyConfig struct {
+	TTL time.Duration
+	Now func() time.Time
+}
+
+var DefaultIdempotencyConfig = IdempotencyConfig{
+	TTL: 24 * time.Hour,
+}
+
+// IdempotentCreator makes CreateUser safe to retry. A create carrying a key
+// is performed at most once per key while its record lives: repeating it
+// with the same payload returns the user from the first call, and reusing
+// the key for a different payload fails with
+// domain.ErrIdempotencyKeyReused. Only successful creates are recorded, so
+// a failed attempt can be retried with the same key.
+type IdempotentCreator struct {
+	inner UserOperations
+	store repository.IdempotencyStore
+	cfg   IdempotencyConfig
+	locks keyLocks
+}
+
+func NewIdempotentCreator(inner UserOperations, store repository.IdempotencyStore, cfg IdempotencyConfig) *IdempotentCreator {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultIdempotencyConfig.TTL
+	}
+	return &IdempotentCreator{inner: inner, store: store, cfg: cfg}
+}
+
+// CreateUser creates a user under key. An empty key disables the check and
+// behaves like a plain CreateUser.
+func (c *IdempotentCreator) CreateUser(key, name, email string, age int) (*domain.User, error) {
+	return c.CreateUserWithAttributes(key, name, email, age, nil)
+}
+
+// CreateUserAs is CreateUserWithAttributes on behalf of p, who must be
+// allowed to create users. Keys are scoped to the principal, so two callers
+// that pick the same key neither replay nor block each other's creates.
+func (c *IdempotentCreator) CreateUserAs(p auth.Principal, key, name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+	if err := auth.Authorize(p, auth.ActionCreate, ""); err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return c.inner.CreateUserWithAttributes(name, email, age, attrs)
+	}
+	return c.CreateUserWithAttributes(string(p.Role)+"\x00"+p.UserID+"\x00"+key, name, email, age, attrs)
+}
+
+// CreateUserWithAttributes is CreateUser for a user that starts with custom
+// attributes; they are part of the payload a repeated key must match.
+// Expired keys are purged whenever a new one is stored; nothing else
+// reclaims them.
+func (c *IdempotentCreator) CreateUserWithAttributes(key, name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+	if key == "" {
+		return c.inner.CreateUserWithAttributes(name, email, age, attrs)
+	}
+
+	// Calls sharing a key are serialized, so two concurrent retries cannot
+	// both miss the store and create twice.
+	unlock := c.locks.lock(key)
+	defer unlock()
+
+	fingerprint := createFingerprint(name, email, age, attrs)
+	now := c.cfg.Now()
+	rec, err := c.store.Get(key, now)
+	switch {
+	case err == nil:
+		if rec.Fingerprint != fingerprint {
+			return nil, domain.ErrIdempotencyKeyReused
+		}
+		return rec.User, nil
+	case !errors.Is(err, domain.ErrNotFound):
+		return nil, err
+	}
+
+	user, err := c.inner.CreateUserWithAttributes(name, email, age, attrs)
+	if err != nil {
+		return nil, err
+	}
+	c.store.Purge(now)
+	err = c.store.Save(&domain.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		User:        user,
+		ExpiresAt:   now.Add(c.cfg.TTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createFingerprint hashes the payload after the same normalization
+// domain.User.Validate applies, so a retry that only differs in email case
+// or surrounding spaces counts as the same request.
+func createFingerprint(name, email string, age int, attrs domain.Attributes) string {
+	fields := []string{
+		strings.TrimSpace(name),
+		strings.ToLower(strings.TrimSpace(email)),
+		strconv.Itoa(age),
+	}
+	for _, n := range attrs.Names() {
+		v, _ := json.Marshal(attrs[n])
+		fields = append(fields, n+"="+string(v))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"time"
+
+	"property-based/internal/domain"
+	"property-based/internal/metrics"
+)
+
+type InstrumentedUserService struct {
+	inner UserOperations
+	ops   *metrics.Operations
+}
+
+func NewInstrumentedUserService(inner UserOperations, ops *metrics.Operations) *InstrumentedUserService {
+	return &InstrumentedUserService{inner: inner, ops: ops}
+}
+
+func (s *InstrumentedUserService) CreateUser(name, email string, age int) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.CreateUser(name, email, age)
+	s.ops.Observe(OpCreateUser, start, err)
+	return user, err
+}
+
+func (s *InstrumentedUserService) CreateUserWithAttributes(name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.CreateUserWithAttributes(name, email, age, attrs)
+	s.ops.Observe(OpCreateUser, start, err)
+	return user, err
+}
+
+func (s *InstrumentedUserService) GetUser(id string) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.GetUser(id)
+	s.ops.Observe(OpGetUser, start, err)
+	return user, err
+}
+
+func (s *InstrumentedUserService) GetUserByEmail(email string) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.GetUserByEmail(email)
+	s.ops.Observe(OpGetUserByEmail, start, err)
+	return user, err
+}
+
+func (s *InstrumentedUserService) GetAllUsers() ([]*domain.User, error) {
+	start := time.Now()
+	users, err := s.inner.GetAllUsers()
+	s.ops.Observe(OpGetAllUsers, start, err)
+	return users, err
+}
+
+func (s *InstrumentedUserService) FindUsersByAttributes(filters ...domain.AttributeFilter) ([]*domain.User, error) {
+	start := time.Now()
+	users, err := s.inner.FindUsersByAttributes(filters...)
+	s.ops.Observe(OpFindUsersByAttributes, start, err)
+	return users, err
+}
+
+func (s *InstrumentedUserService) UpdateUser(id, name, email string, age int) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.UpdateUser(id, name, email, age)
+	s.ops.Observe(OpUpdateUser, start, err)
+	return user, err
+}
+
+func (s *InstrumentedUserService) PatchUser(id string, patch domain.UserPatch) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.PatchUser(id, patch)
+	s.ops.Observe(OpPatchUser, start, err)
+	return user, err
+}
+
+func (s *InstrumentedUserService) DeleteUser(id string) error {
+	start := time.Now()
+	err := s.inner.DeleteUser(id)
+	s.ops.Observe(OpDeleteUser, start, err)
+	return err
+}
+
+func (s *InstrumentedUserService) CountUsers() int {
+	start := time.Now()
+	count := s.inner.CountUsers()
+	s.ops.Observe(OpCountUsers, start, nil)
+	return count
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"property-based/internal/domain"
+)
+
+type Redaction int
+
+const (
+	RedactHide Redaction = iota
+	RedactMask
+	RedactNone
+)
+
+func ParseRedaction(s string) (Redaction, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "hide":
+		return RedactHide, nil
+	case "mask":
+		return RedactMask, nil
+	case "none":
+		return RedactNone, nil
+	default:
+		return 0, fmt.Errorf("unknown redaction %q (want hide, mask or none)", s)
+	}
+}
+
+func (r Redaction) String() string {
+	switch r {
+	case RedactHide:
+		return "hide"
+	case RedactMask:
+		return "mask"
+	case RedactNone:
+		return "none"
+	default:
+		return fmt.Sprintf("Redaction(%d)", int(r))
+	}
+}
+
+const redacted = "[REDACTED]"
+
+type RedactionPolicy struct {
+	Email Redaction
+	Name  Redaction
+}
+
+var DefaultRedactionPolicy = RedactionPolicy{Email: RedactMask, Name: RedactHide}
+
+func (p RedactionPolicy) email(email string) string {
+	switch p.Email {
+	case RedactNone:
+		return email
+	case RedactMask:
+		local, domainPart, found := strings.Cut(email, "@")
+		if !found || local == "" {
+			return redacted
+		}
+		return string([]rune(local)[:1]) + "***@" + domainPart
+	default:
+		return redacted
+	}
+}
+
+func (p RedactionPolicy) name(name string) string {
+	switch p.Name {
+	case RedactNone:
+		return name
+	case RedactMask:
+		if name == "" {
+			return redacted
+		}
+		return string([]rune(name)[:1]) + "***"
+	default:
+		return redacted
+	}
+}
+
+// LoggingUserService emits one structured record per operation. Successful
+// calls log at Info, domain errors (validation, not found, duplicates) at
+// Warn and anything else at Error.
+type LoggingUserService struct {
+	inner  UserOperations
+	logger *slog.Logger
+	policy RedactionPolicy
+}
+
+func NewLoggingUserService(inner UserOperations, logger *slog.Logger, policy RedactionPolicy) *LoggingUserService {
+	return &LoggingUserService{inner: inner, logger: logger, policy: policy}
+}
+
+func (s *LoggingUserService) log(operation string, start time.Time, err error, attrs ...slog.Attr) {
+	level := slog.LevelInfo
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+		level = slog.LevelWarn
+		code := domain.ErrorCode(err)
+		if code == domain.CodeInternal {
+			level = slog.LevelError
+		}
+		attrs = append(attrs, slog.String("error_code", code), slog.String("error", err.Error()))
+	}
+
+	attrs = append([]slog.Attr{
+		slog.String("operation", operation),
+		slog.String("outcome", outcome),
+		slog.Duration("duration", time.Since(start)),
+	}, attrs...)
+	s.logger.LogAttrs(context.Background(), level, "user operation", attrs...)
+}
+
+func (s *LoggingUserService) userAttrs(user *domain.User, id, name, email string) []slog.Attr {
+	if user != nil {
+		id, name, email = user.ID, user.Name, user.Email
+	}
+	var attrs []slog.Attr
+	if id != "" {
+		attrs = append(attrs, slog.String("user_id", id))
+	}
+	if name != "" {
+		attrs = append(attrs, slog.String("name", s.policy.name(name)))
+	}
+	if email != "" {
+		attrs = append(attrs, slog.String("email", s.policy.email(email)))
+	}
+	return attrs
+}
+
+func (s *LoggingUserService) CreateUser(name, email string, age int) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.CreateUser(name, email, age)
+	s.log(OpCreateUser, start, err, s.userAttrs(user, "", name, email)...)
+	return user, err
+}
+
+func (s *LoggingUserService) CreateUserWithAttributes(name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.CreateUserWithAttributes(name, email, age, attrs)
+	s.log(OpCreateUser, start, err, s.userAttrs(user, "", name, email)...)
+	return user, err
+}
+
+func (s *LoggingUserService) GetUser(id string) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.GetUser(id)
+	s.log(OpGetUser, start, err, s.userAttrs(nil, id, "", "")...)
+	return user, err
+}
+
+func (s *LoggingUserService) GetUserByEmail(email string) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.GetUserByEmail(email)
+	attrs := s.userAttrs(nil, "", "", email)
+	if user != nil {
+		attrs = append(attrs, slog.String("user_id", user.ID))
+	}
+	s.log(OpGetUserByEmail, start, err, attrs...)
+	return user, err
+}
+
+func (s *LoggingUserService) GetAllUsers() ([]*domain.User, error) {
+	start := time.Now()
+	users, err := s.inner.GetAllUsers()
+	s.log(OpGetAllUsers, start, err, slog.Int("count", len(users)))
+	return users, err
+}
+
+func (s *LoggingUserService) FindUsersByAttributes(filters ...domain.AttributeFilter) ([]*domain.User, error) {
+	start := time.Now()
+	users, err := s.inner.FindUsersByAttributes(filters...)
+	s.log(OpFindUsersByAttributes, start, err, slog.Int("filters", len(filters)), slog.Int("count", len(users)))
+	return users, err
+}
+
+func (s *LoggingUserService) UpdateUser(id, name, email string, age int) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.UpdateUser(id, name, email, age)
+	s.log(OpUpdateUser, start, err, s.userAttrs(user, id, name, email)...)
+	return user, err
+}
+
+func (s *LoggingUserService) PatchUser(id string, patch domain.UserPatch) (*domain.User, error) {
+	start := time.Now()
+	user, err := s.inner.PatchUser(id, patch)
+	var name, email string
+	if patch.Name != nil {
+		name = *patch.Name
+	}
+	if patch.Email != nil {
+		email = *patch.Email
+	}
+	s.log(OpPatchUser, start, err, s.userAttrs(user, id, name, email)...)
+	return user, err
+}
+
+func (s *LoggingUserService) DeleteUser(id string) error {
+	start := time.Now()
+	err := s.inner.DeleteUser(id)
+	s.log(OpDeleteUser, start, err, s.userAttrs(nil, id, "", "")...)
+	return err
+}
+
+func (s *LoggingUserService) CountUsers() int {
+	start := time.Now()
+	count := s.inner.CountUsers()
+	s.log(OpCountUsers, start, nil, slog.Int("count", count))
+	return count
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"time"
+
+	"property-based/internal/domain"
+	"property-based/internal/ratelimit"
+)
+
+type RateLimitConfig struct {
+	Reads  ratelimit.Limit
+	Writes ratelimit.Limit
+	// Attempts bounds the requests a transport accepts from one address
+	// before it knows the caller, such as token guesses. Everyone behind
+	// the address shares it, so it should be coarse.
+	Attempts ratelimit.Limit
+	Now      func() time.Time
+}
+
+var DefaultRateLimitConfig = RateLimitConfig{
+	Reads:    ratelimit.Limit{Rate: 50, Burst: 100},
+	Writes:   ratelimit.Limit{Rate: 5, Burst: 10},
+	Attempts: ratelimit.Limit{Rate: 100, Burst: 200},
+}
+
+// RateLimits holds the read and write budgets of every caller. Share one
+// instance across the per-request RateLimitedUserService values so budgets
+// persist between requests.
+type RateLimits struct {
+	reads    *ratelimit.Limiter
+	writes   *ratelimit.Limiter
+	attempts *ratelimit.Limiter
+}
+
+func NewRateLimits(cfg RateLimitConfig) *RateLimits {
+	return &RateLimits{
+		reads:    ratelimit.NewLimiter(cfg.Reads, cfg.Now),
+		writes:   ratelimit.NewLimiter(cfg.Writes, cfg.Now),
+		attempts: ratelimit.NewLimiter(cfg.Attempts, cfg.Now),
+	}
+}
+
+// AllowRead spends one read token of key, or returns a
+// *domain.RateLimitError if none is left.
+func (l *RateLimits) AllowRead(key string) error {
+	return allow(l.reads, key)
+}
+
+// AllowWrite spends one write token of key, or returns a
+// *domain.RateLimitError if none is left.
+func (l *RateLimits) AllowWrite(key string) error {
+	return allow(l.writes, key)
+}
+
+// AllowAttempt spends one attempt token of key, typically a client
+// address, or returns a *domain.RateLimitError if none is left.
+func (l *RateLimits) AllowAttempt(key string) error {
+	return allow(l.attempts, key)
+}
+
+func allow(limiter *ratelimit.Limiter, key string) error {
+	if ok, wait := limiter.Take(key); !ok {
+		return &domain.RateLimitError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RateLimitedUserService charges every call to one key, typically the
+// caller's user ID, tenant ID or both joined. Transports build one per
+// request. CountUsers cannot report an error and is not limited.
+type RateLimitedUserService struct {
+	inner  UserOperations
+	limits *RateLimits
+	key    string
+}
+
+func NewRateLimitedUserService(inner UserOperations, limits *RateLimits, key string) *RateLimitedUserService {
+	return &RateLimitedUserService{inner: inner, limits: limits, key: key}
+}
+
+func (s *RateLimitedUserService) CreateUser(name, email string, age int) (*domain.User, error) {
+	if err := s.limits.AllowWrite(s.key); err != nil {
+		return nil, err
+	}
+	return s.inner.CreateUser(name, email, age)
+}
+
+func (s *RateLimitedUserService) CreateUserWithAttributes(name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+	if err := s.limits.AllowWrite(s.key); err != nil {
+		return nil, err
+	}
+	return s.inner.CreateUserWithAttributes(name, email, age, attrs)
+}
+
+func (s *RateLimitedUserService) GetUser(id string) (*domain.User, error) {
+	if err := s.limits.AllowRead(s.key); err != nil {
+		return nil, err
+	}
+	return s.inner.GetUser(id)
+}
+
+func (s *RateLimitedUserService) GetUserByEmail(email string) (*domain.User, error) {
+	if err := s.limits.AllowRead(s.key); err != nil {
+		return nil, err
+	}
+	return s.inner.GetUserByEmail(email)
+}
+
+func (s *RateLimitedUserService) GetAllUsers() ([]*domain.User, error) {
+	if err := s.limits.AllowRead(s.key); err != nil {
+		return nil, err
+	}
+	return s.inner.GetAllUsers()
+}
+
+func (s *RateLimitedUserService) FindUsersByAttributes(filters ...domain.AttributeFilter) ([]*domain.User, error) {
+	if err := s.limits.AllowRead(s.key); err != nil {
+		return nil, err
+	}
+	return s.inner.FindUsersByAttributes(filters...)
+}
+
+func (s *RateLimitedUserService) UpdateUser(id, name, email string, age int) (*domain.User, error) {
+	if err := s.limits.AllowWrite(s.key); err != nil {
+		return nil, err
+	}
+	return s.inner.UpdateUser(id, name, email, age)
+}
+
+func (s *RateLimitedUserService) PatchUser(id string, patch domain.UserPatch) (*domain.User, error) {
+	if err := s.limits.AllowWrite(s.key); err != nil {
+		return nil, err
+	}
+	return s.inner.PatchUser(id, patch)
+}
+
+func (s *RateLimitedUserService) DeleteUser(id string) error {
+	if err := s.limits.AllowWrite(s.key); err != nil {
+		return err
+	}
+	return s.inner.DeleteUser(id)
+}
+
+func (s *RateLimitedUserService) CountUsers() int {
+	return s.inner.CountUsers()
+}
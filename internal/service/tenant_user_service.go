@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+)
+
+// TenantUserService scopes every operation to the tenant passed with the
+// call. Each tenant is backed by its own repository, created by the first
+// CreateUser; every other operation on an unknown tenant behaves as on an
+// empty one without creating it.
+type TenantUserService struct {
+	repos    *repository.TenantRepositories
+	policies func(tenantID string) *domain.ValidationPolicy
+}
+
+type TenantUserServiceOption func(*TenantUserService)
+
+// WithTenantValidationPolicies validates each tenant's users against the
+// policy returned for its ID, as given by repository.TenantKey. A nil
+// policy means domain.DefaultValidationPolicy.
+func WithTenantValidationPolicies(policies func(tenantID string) *domain.ValidationPolicy) TenantUserServiceOption {
+	return func(s *TenantUserService) {
+		s.policies = policies
+	}
+}
+
+func NewTenantUserService(repos *repository.TenantRepositories, opts ...TenantUserServiceOption) *TenantUserService {
+	s := &TenantUserService{repos: repos}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// service binds repo to the validation policy of the tenant stored under
+// key.
+func (s *TenantUserService) service(key string, repo repository.UserRepository) *UserService {
+	policy := domain.DefaultValidationPolicy
+	if s.policies != nil {
+		if p := s.policies(key); p != nil {
+			policy = p
+		}
+	}
+	return NewUserService(repo, WithValidationPolicy(policy))
+}
+
+// ForTenant returns a UserService bound to an existing tenant and its
+// validation policy, for operations beyond the per-call methods below. It
+// fails with domain.ErrNotFound for a tenant without users ever created.
+func (s *TenantUserService) ForTenant(tenantID string) (*UserService, error) {
+	key, err := repository.TenantKey(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := s.repos.ForTenant(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.service(key, repo), nil
+}
+
+func (s *TenantUserService) CreateUser(tenantID, name, email string, age int) (*domain.User, error) {
+	key, err := repository.TenantKey(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := s.repos.OpenTenant(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.service(key, repo).CreateUser(name, email, age)
+}
+
+func (s *TenantUserService) GetUser(tenantID, id string) (*domain.User, error) {
+	svc, err := s.ForTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.GetUser(id)
+}
+
+func (s *TenantUserService) GetUserByEmail(tenantID, email string) (*domain.User, error) {
+	svc, err := s.ForTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.GetUserByEmail(email)
+}
+
+func (s *TenantUserService) GetAllUsers(tenantID string) ([]*domain.User, error) {
+	svc, err := s.ForTenant(tenantID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return []*domain.User{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return svc.GetAllUsers()
+}
+
+func (s *TenantUserService) UpdateUser(tenantID, id, name, email string, age int) (*domain.User, error) {
+	svc, err := s.ForTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.UpdateUser(id, name, email, age)
+}
+
+func (s *TenantUserService) PatchUser(tenantID, id string, patch domain.UserPatch) (*domain.User, error) {
+	svc, err := s.ForTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.PatchUser(id, patch)
+}
+
+func (s *TenantUserService) DeleteUser(tenantID, id string) error {
+	svc, err := s.ForTenant(tenantID)
+	if err != nil {
+		return err
+	}
+	return svc.DeleteUser(id)
+}
+
+func (s *TenantUserService) CountUsers(tenantID string) (int, error) {
+	svc, err := s.ForTenant(tenantID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return svc.CountUsers(), nil
+}
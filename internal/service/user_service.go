@@ -1,6 +1,9 @@
 package service
 
 import (
+	"errors"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,17 +12,78 @@ import (
 	"property-based/internal/repository"
 )
 
+const (
+	OpCreateUser            = "CreateUser"
+	OpGetUser               = "GetUser"
+	OpGetUserByEmail        = "GetUserByEmail"
+	OpGetAllUsers           = "GetAllUsers"
+	OpFindUsersByAttributes = "FindUsersByAttributes"
+	OpUpdateUser            = "UpdateUser"
+	OpPatchUser             = "PatchUser"
+	OpDeleteUser            = "DeleteUser"
+	OpCountUsers            = "CountUsers"
+)
+
+type UserOperations interface {
+	CreateUser(name, email string, age int) (*domain.User, error)
+	CreateUserWithAttributes(name, email string, age int, attrs domain.Attributes) (*domain.User, error)
+	GetUser(id string) (*domain.User, error)
+	GetUserByEmail(email string) (*domain.User, error)
+	GetAllUsers() ([]*domain.User, error)
+	FindUsersByAttributes(filters ...domain.AttributeFilter) ([]*domain.User, error)
+	UpdateUser(id, name, email string, age int) (*domain.User, error)
+	PatchUser(id string, patch domain.UserPatch) (*domain.User, error)
+	DeleteUser(id string) error
+	CountUsers() int
+}
+
+var _ UserOperations = (*UserService)(nil)
+
 type UserService struct {
-	repo repository.UserRepository
+	repo        repository.UserRepository
+	policy      *domain.ValidationPolicy
+	credentials repository.CredentialRepository
 }
 
-func NewUserService(repo repository.UserRepository) *UserService {
-	return &UserService{repo: repo}
+type UserServiceOption func(*UserService)
+
+// WithValidationPolicy validates created and updated users against p
+// instead of domain.DefaultValidationPolicy.
+func WithValidationPolicy(p *domain.ValidationPolicy) UserServiceOption {
+	return func(s *UserService) {
+		s.policy = p
+	}
+}
+
+// WithCredentials deletes a user's credentials together with the user.
+func WithCredentials(creds repository.CredentialRepository) UserServiceOption {
+	return func(s *UserService) {
+		s.credentials = creds
+	}
+}
+
+func NewUserService(repo repository.UserRepository, opts ...UserServiceOption) *UserService {
+	s := &UserService{repo: repo, policy: domain.DefaultValidationPolicy}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ValidationPolicy returns the policy the service validates users against.
+func (s *UserService) ValidationPolicy() *domain.ValidationPolicy {
+	return s.policy
 }
 
 func (s *UserService) CreateUser(name, email string, age int) (*domain.User, error) {
+	return s.CreateUserWithAttributes(name, email, age, nil)
+}
+
+// CreateUserWithAttributes creates a user that starts with the given custom
+// attributes, which must be declared by the policy's schema.
+func (s *UserService) CreateUserWithAttributes(name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
 	id := uuid.New().String()
-	user, err := domain.NewUser(id, name, email, age)
+	user, err := s.policy.NewUserWithAttributes(id, name, email, age, attrs)
 	if err != nil {
 		return nil, err
 	}
@@ -50,17 +114,19 @@ func (s *UserService) UpdateUser(id, name, email string, age int) (*domain.User,
 	}
 
 	updatedUser := &domain.User{
-		ID:        id,
-		Name:      name,
-		Email:     email,
-		Age:       age,
-		CreatedAt: existingUser.CreatedAt,
-		UpdatedAt: time.Now().UTC(),
+		ID:         id,
+		Name:       name,
+		Email:      email,
+		Age:        age,
+		Attributes: existingUser.Attributes,
+		CreatedAt:  existingUser.CreatedAt,
+		UpdatedAt:  time.Now().UTC(),
 	}
 
-	if err := updatedUser.Validate(); err != nil {
+	if err := s.policy.Validate(updatedUser); err != nil {
 		return nil, err
 	}
+	updatedUser.EmailVerified = existingUser.EmailVerified && existingUser.Email == updatedUser.Email
 
 	if err := s.repo.Update(updatedUser); err != nil {
 		return nil, err
@@ -69,10 +135,194 @@ func (s *UserService) UpdateUser(id, name, email string, age int) (*domain.User,
 	return updatedUser, nil
 }
 
+// PatchUser changes only the fields set in patch. UpdatedAt moves only when
+// the merged, normalized user differs from the stored one.
+func (s *UserService) PatchUser(id string, patch domain.UserPatch) (*domain.User, error) {
+	apply := func(user *domain.User) (bool, error) {
+		before := *user
+		patch.ApplyTo(user)
+		if err := s.policy.Validate(user); err != nil {
+			return false, err
+		}
+		if user.Name == before.Name && user.Email == before.Email && user.Age == before.Age &&
+			user.Attributes.Equal(before.Attributes) {
+			return false, nil
+		}
+		if user.Email != before.Email {
+			user.EmailVerified = false
+		}
+		user.UpdatedAt = time.Now().UTC()
+		return true, nil
+	}
+
+	return modify(s.repo, id, apply)
+}
+
+// modify applies fn through repo's atomic Modify, or as a read followed by
+// an Update when repo cannot modify in place.
+func modify(repo repository.UserRepository, id string, fn func(user *domain.User) (bool, error)) (*domain.User, error) {
+	if modifier, ok := repo.(repository.UserModifier); ok {
+		user, err := modifier.Modify(id, fn)
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return user, err
+		}
+	}
+
+	user, err := repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := fn(user)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := repo.Update(user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
 func (s *UserService) DeleteUser(id string) error {
-	return s.repo.Delete(id)
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	return deleteCredentials(s.credentials, id)
+}
+
+// deleteCredentials removes the password of a deleted user, if it had one.
+func deleteCredentials(creds repository.CredentialRepository, userID string) error {
+	if creds == nil {
+		return nil
+	}
+	if err := creds.Delete(userID); err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	return nil
 }
 
 func (s *UserService) CountUsers() int {
 	return s.repo.Count()
 }
+
+func (s *UserService) ListUserVersions(id string) ([]domain.UserVersion, error) {
+	history, ok := s.repo.(repository.UserHistory)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return history.Versions(id)
+}
+
+// GetUserAt returns the user as of at: the latest version recorded at or
+// before it. ErrNotFound means the user did not exist or was deleted at that
+// time, or that the time predates the retained history.
+func (s *UserService) GetUserAt(id string, at time.Time) (*domain.User, error) {
+	versions, err := s.ListUserVersions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].RecordedAt.After(at) })
+	if i == 0 || versions[i-1].Deleted {
+		return nil, domain.ErrNotFound
+	}
+	return versions[i-1].User, nil
+}
+
+func (s *UserService) DiffUserVersions(id string, from, to int) ([]domain.FieldChange, error) {
+	versions, err := s.ListUserVersions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromVersion, toVersion *domain.UserVersion
+	for i := range versions {
+		if versions[i].Version == from {
+			fromVersion = &versions[i]
+		}
+		if versions[i].Version == to {
+			toVersion = &versions[i]
+		}
+	}
+	if fromVersion == nil || toVersion == nil {
+		return nil, domain.ErrNotFound
+	}
+	return domain.DiffVersions(*fromVersion, *toVersion), nil
+}
+
+func (s *UserService) FindUsersByAgeRange(min, max int) ([]*domain.User, error) {
+	if q, ok := s.repo.(repository.UserQuerier); ok {
+		if users, err := q.FindByAgeRange(min, max); !errors.Is(err, errors.ErrUnsupported) {
+			return users, err
+		}
+	}
+
+	users, err := s.scan(func(u *domain.User) bool { return u.Age >= min && u.Age <= max })
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Age != users[j].Age {
+			return users[i].Age < users[j].Age
+		}
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+func (s *UserService) FindUsersByNamePrefix(prefix string) ([]*domain.User, error) {
+	if q, ok := s.repo.(repository.UserQuerier); ok {
+		if users, err := q.FindByNamePrefix(prefix); !errors.Is(err, errors.ErrUnsupported) {
+			return users, err
+		}
+	}
+
+	prefix = strings.ToLower(prefix)
+	users, err := s.scan(func(u *domain.User) bool { return strings.HasPrefix(strings.ToLower(u.Name), prefix) })
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool {
+		a, b := strings.ToLower(users[i].Name), strings.ToLower(users[j].Name)
+		if a != b {
+			return a < b
+		}
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+// FindUsersByAttributes returns the users matching every filter, ordered by
+// ID. No filter matches every user.
+func (s *UserService) FindUsersByAttributes(filters ...domain.AttributeFilter) ([]*domain.User, error) {
+	users, err := s.scan(func(u *domain.User) bool {
+		for _, f := range filters {
+			if !f.Matches(u) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+// scan is the fallback for repositories without secondary indexes.
+func (s *UserService) scan(match func(*domain.User) bool) ([]*domain.User, error) {
+	all, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*domain.User, 0)
+	for _, u := range all {
+		if match(u) {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
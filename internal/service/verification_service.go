@@ -0,0 +1,123 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"property-based/internal/domain"
+	"property-based/internal/mail"
+	"property-based/internal/repository"
+)
+
+type VerificationConfig struct {
+	TokenTTL time.Duration
+	Now      func() time.Time
+}
+
+var DefaultVerificationConfig = VerificationConfig{
+	TokenTTL: 24 * time.Hour,
+}
+
+const VerificationSubject = "Verify your email address"
+
+type VerificationService struct {
+	users  repository.UserRepository
+	tokens repository.VerificationTokenRepository
+	mailer mail.Mailer
+	cfg    VerificationConfig
+}
+
+func NewVerificationService(users repository.UserRepository, tokens repository.VerificationTokenRepository, mailer mail.Mailer, cfg VerificationConfig) *VerificationService {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = DefaultVerificationConfig.TokenTTL
+	}
+	return &VerificationService{users: users, tokens: tokens, mailer: mailer, cfg: cfg}
+}
+
+// RequestVerification issues a single-use token bound to the user's current
+// email and mails it to that address. It also purges expired tokens, so
+// the repository holds at most the tokens issued within one TTL; an expired
+// token that has been purged is rejected with ErrInvalidToken instead of
+// ErrTokenExpired.
+func (s *VerificationService) RequestVerification(userID string) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return domain.ErrEmailAlreadyVerified
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := s.cfg.Now()
+	s.tokens.Purge(now)
+	err = s.tokens.Save(&domain.VerificationToken{
+		Hash:      hashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: now.Add(s.cfg.TokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: VerificationSubject,
+		Body:    fmt.Sprintf("Use this code to verify your email address within %s:\n\n%s", s.cfg.TokenTTL, token),
+	})
+}
+
+// VerifyEmail redeems token. Tokens are invalid once used, once expired, and
+// once the user's email no longer matches the address they were sent to.
+func (s *VerificationService) VerifyEmail(token string) (*domain.User, error) {
+	stored, err := s.tokens.Consume(hashToken(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.Used {
+		return nil, domain.ErrInvalidToken
+	}
+	if !s.cfg.Now().Before(stored.ExpiresAt) {
+		return nil, domain.ErrTokenExpired
+	}
+
+	verify := func(user *domain.User) (bool, error) {
+		if user.Email != stored.Email {
+			return false, domain.ErrInvalidToken
+		}
+		if user.EmailVerified {
+			return false, nil
+		}
+		user.EmailVerified = true
+		user.UpdatedAt = time.Now().UTC()
+		return true, nil
+	}
+
+	user, err := modify(s.users, stored.UserID, verify)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidToken
+	}
+	return user, err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,232 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"property-based/internal/domain"
+)
+
+// CodeInvalidRequest is reported for requests the server cannot decode. It
+// has no domain sentinel.
+const CodeInvalidRequest = "invalid_request"
+
+// User is the JSON representation of domain.User.
+type User struct {
+	ID            string               `json:"id"`
+	Name          string               `json:"name"`
+	Email         string               `json:"email"`
+	Age           int                  `json:"age"`
+	EmailVerified bool                 `json:"email_verified"`
+	Attributes    map[string]Attribute `json:"attributes"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+func UserFromDomain(u *domain.User) User {
+	user := User{
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		Age:           u.Age,
+		EmailVerified: u.EmailVerified,
+		Attributes:    make(map[string]Attribute, len(u.Attributes)),
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+	for name, v := range u.Attributes {
+		user.Attributes[name] = AttributeFromDomain(v)
+	}
+	return user
+}
+
+// Domain converts u back. Attributes the server could not have sent, such
+// as a value that does not match its type, are dropped.
+func (u User) Domain() *domain.User {
+	user := &domain.User{
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		Age:           u.Age,
+		EmailVerified: u.EmailVerified,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+	for name, a := range u.Attributes {
+		if v, err := a.Domain(); err == nil {
+			if user.Attributes == nil {
+				user.Attributes = domain.Attributes{}
+			}
+			user.Attributes[name] = v
+		}
+	}
+	return user
+}
+
+// Attribute is the JSON representation of a domain.AttributeValue. Type is
+// string, number, bool or date; Value is a string, number or boolean, and
+// dates are "YYYY-MM-DD" strings.
+type Attribute struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+func AttributeFromDomain(v domain.AttributeValue) Attribute {
+	return Attribute{Type: string(v.Type()), Value: v.Value()}
+}
+
+func (a Attribute) Domain() (domain.AttributeValue, error) {
+	return domain.ParseAttribute(domain.AttributeType(a.Type), a.Value)
+}
+
+// CreateUserRequest is the body of POST /users.
+type CreateUserRequest struct {
+	Name       string               `json:"name"`
+	Email      string               `json:"email"`
+	Age        int                  `json:"age"`
+	Attributes map[string]Attribute `json:"attributes,omitempty"`
+}
+
+func CreateUserRequestFromDomain(name, email string, age int, attrs domain.Attributes) CreateUserRequest {
+	req := CreateUserRequest{Name: name, Email: email, Age: age}
+	if len(attrs) > 0 {
+		req.Attributes = make(map[string]Attribute, len(attrs))
+		for n, v := range attrs {
+			req.Attributes[n] = AttributeFromDomain(v)
+		}
+	}
+	return req
+}
+
+// DomainAttributes fails when an attribute value does not match its type;
+// whether the attribute is declared is up to the service.
+func (r CreateUserRequest) DomainAttributes() (domain.Attributes, error) {
+	if len(r.Attributes) == 0 {
+		return nil, nil
+	}
+	attrs := make(domain.Attributes, len(r.Attributes))
+	for name, a := range r.Attributes {
+		v, err := a.Domain()
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		attrs[name] = v
+	}
+	return attrs, nil
+}
+
+// UpdateUserRequest is the body of PUT /users/{id}. It has no attributes:
+// PUT keeps the stored ones, which PATCH changes, and a body that sends
+// some is rejected as an unknown field.
+type UpdateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+// PatchUserRequest is the body of PATCH /users/{id}; absent fields are left
+// unchanged. Attributes lists only the custom attributes to change, and a
+// null value removes one.
+type PatchUserRequest struct {
+	Name       *string               `json:"name,omitempty"`
+	Email      *string               `json:"email,omitempty"`
+	Age        *int                  `json:"age,omitempty"`
+	Attributes map[string]*Attribute `json:"attributes,omitempty"`
+}
+
+func PatchUserRequestFromDomain(p domain.UserPatch) PatchUserRequest {
+	req := PatchUserRequest{Name: p.Name, Email: p.Email, Age: p.Age}
+	if len(p.Attributes) > 0 {
+		req.Attributes = make(map[string]*Attribute, len(p.Attributes))
+		for name, v := range p.Attributes {
+			if v == nil {
+				req.Attributes[name] = nil
+				continue
+			}
+			a := AttributeFromDomain(*v)
+			req.Attributes[name] = &a
+		}
+	}
+	return req
+}
+
+// Domain fails when an attribute value does not match its type; whether
+// the attribute is declared is up to the service.
+func (p PatchUserRequest) Domain() (domain.UserPatch, error) {
+	patch := domain.UserPatch{Name: p.Name, Email: p.Email, Age: p.Age}
+	if len(p.Attributes) > 0 {
+		patch.Attributes = make(map[string]*domain.AttributeValue, len(p.Attributes))
+		for name, a := range p.Attributes {
+			if a == nil {
+				patch.Attributes[name] = nil
+				continue
+			}
+			v, err := a.Domain()
+			if err != nil {
+				return domain.UserPatch{}, fmt.Errorf("attribute %q: %w", name, err)
+			}
+			patch.Attributes[name] = &v
+		}
+	}
+	return patch, nil
+}
+
+// ChangeEvent is the data of each Server-Sent Event on GET /users/changes.
+// The event id is Seq and the event name is Op.
+type ChangeEvent struct {
+	Seq  uint64    `json:"seq"`
+	Op   string    `json:"op"`
+	At   time.Time `json:"at"`
+	User User      `json:"user"`
+}
+
+func ChangeEventFromDomain(c domain.UserChange) ChangeEvent {
+	return ChangeEvent{Seq: c.Seq, Op: string(c.Op), At: c.At, User: UserFromDomain(c.User)}
+}
+
+type UserListResponse struct {
+	Users []User `json:"users"`
+}
+
+type CountResponse struct {
+	Count int `json:"count"`
+}
+
+// ErrorResponse is the body of every non-2xx response. Code is one of the
+// stable domain codes, CodeInvalidRequest or domain.CodeInternal.
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+var codeStatus = map[string]int{
+	domain.CodeInvalidUserName:  http.StatusBadRequest,
+	domain.CodeInvalidUserEmail: http.StatusBadRequest,
+	domain.CodeInvalidUserAge:   http.StatusBadRequest,
+	domain.CodeInvalidTenant:    http.StatusBadRequest,
+	domain.CodeWeakPassword:     http.StatusBadRequest,
+	domain.CodeInvalidToken:     http.StatusBadRequest,
+	domain.CodeTokenExpired:     http.StatusBadRequest,
+	domain.CodeInvalidMerge:     http.StatusBadRequest,
+	domain.CodeInvalidAttribute: http.StatusBadRequest,
+	CodeInvalidRequest:          http.StatusBadRequest,
+	domain.CodeUnauthenticated:  http.StatusUnauthorized,
+	domain.CodeInvalidCreds:     http.StatusUnauthorized,
+	domain.CodeForbidden:        http.StatusForbidden,
+	domain.CodeNotFound:         http.StatusNotFound,
+	domain.CodeAlreadyExists:    http.StatusConflict,
+	domain.CodeAlreadyVerified:  http.StatusConflict,
+	domain.CodeAccountLocked:    http.StatusLocked,
+	domain.CodeIdempotencyKey:   http.StatusUnprocessableEntity,
+	domain.CodeRateLimited:      http.StatusTooManyRequests,
+	domain.CodeChangesGone:      http.StatusGone,
+}
+
+// StatusForCode returns the HTTP status used for an error code.
+func StatusForCode(code string) int {
+	if status, ok := codeStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
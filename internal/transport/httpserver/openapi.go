@@ -0,0 +1,233 @@
+package httpserver
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIVersion is the version of the specification the document follows.
+const OpenAPIVersion = "3.0.3"
+
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+
+	operationID string
+	summary     string
+	// headers and query are optional string parameters the route reads.
+	headers []string
+	query   []string
+	// request is a zero value of the body type, or nil when there is none.
+	request any
+	status  int
+	// response is a zero value of the success body type, or nil for an
+	// empty body. For streams it is the type of each event's data.
+	response any
+	// contentType of the success body; empty means application/json.
+	contentType string
+	// errors are the statuses the route answers with ErrorResponse besides
+	// 500, which every route may return.
+	errors []int
+	// secured routes require a bearer token.
+	secured bool
+}
+
+// bearerScheme names the security scheme of secured routes.
+const bearerScheme = "bearerAuth"
+
+// openAPIDocument describes routes as an OpenAPI 3 document. Schemas are
+// derived from the message types by reflection, one component per named
+// struct, so a field added to User shows up in the document without
+// touching this file.
+func openAPIDocument(routes []route) map[string]any {
+	components := map[string]any{}
+	paths := map[string]any{}
+	secured := false
+
+	for _, rt := range routes {
+		op := map[string]any{
+			"operationId": rt.operationID,
+			"summary":     rt.summary,
+			"tags":        []string{"users"},
+		}
+		if rt.secured {
+			op["security"] = []map[string][]string{{bearerScheme: {}}}
+			secured = true
+		}
+		if params := parameters(rt); len(params) > 0 {
+			op["parameters"] = params
+		}
+		if rt.request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemaOf(reflect.TypeOf(rt.request), components)),
+			}
+		}
+
+		responses := map[string]any{}
+		success := map[string]any{"description": http.StatusText(rt.status)}
+		if rt.response != nil {
+			success["content"] = content(rt.contentType, schemaOf(reflect.TypeOf(rt.response), components))
+		}
+		responses[strconv.Itoa(rt.status)] = success
+
+		errSchema := schemaOf(reflect.TypeOf(ErrorResponse{}), components)
+		for _, status := range append(rt.errors, http.StatusInternalServerError) {
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": http.StatusText(status),
+				"content":     jsonContent(errSchema),
+			}
+		}
+		op["responses"] = responses
+
+		item, _ := paths[rt.path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[rt.path] = item
+		}
+		item[strings.ToLower(rt.method)] = op
+	}
+
+	doc := map[string]any{
+		"openapi": OpenAPIVersion,
+		"info": map[string]any{
+			"title":   "User API",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": components},
+	}
+	if secured {
+		doc["components"] = map[string]any{
+			"schemas": components,
+			"securitySchemes": map[string]any{
+				bearerScheme: map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		}
+	}
+	return doc
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return content("", schema)
+}
+
+func content(contentType string, schema map[string]any) map[string]any {
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	return map[string]any{contentType: map[string]any{"schema": schema}}
+}
+
+func parameters(rt route) []map[string]any {
+	var params []map[string]any
+	for _, segment := range strings.Split(rt.path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, map[string]any{
+				"name":     strings.Trim(segment, "{}"),
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+	}
+	for _, name := range rt.headers {
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "header",
+			"required": false,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	for _, name := range rt.query {
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "query",
+			"required": false,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	return params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the JSON schema for t, adding named structs to
+// components and referring to them by $ref. Pointers are nullable: a null
+// field of a patch leaves it unchanged and a null attribute removes it.
+func schemaOf(t reflect.Type, components map[string]any) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := schemaOf(t.Elem(), components)
+		if _, ok := schema["$ref"]; ok {
+			// OpenAPI 3.0 ignores the siblings of a $ref.
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), components)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), components)}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, done := components[t.Name()]; done {
+			return ref
+		}
+		// Register before recursing so self-referencing types terminate.
+		components[t.Name()] = map[string]any{}
+		components[t.Name()] = structSchema(t, components)
+		return ref
+	default:
+		return map[string]any{}
+	}
+}
+
+func structSchema(t reflect.Type, components map[string]any) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaOf(field.Type, components)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,576 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"property-based/internal/auth"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+)
+
+// maxBodyBytes bounds request bodies; user payloads are tiny.
+const maxBodyBytes = 1 << 20
+
+// IdempotencyKeyHeader carries the client-chosen key that makes POST /users
+// safe to retry when the handler is built WithIdempotency.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// LastEventIDHeader is sent by Server-Sent Events clients when they
+// reconnect; GET /users/changes resumes after it.
+const LastEventIDHeader = "Last-Event-ID"
+
+// AcceptLanguageHeader selects the language of error messages; see
+// domain.Locales for the supported ones.
+const AcceptLanguageHeader = "Accept-Language"
+
+// AuthorizationHeader carries "Bearer <token>" when the handler is built
+// WithAuthenticator.
+const AuthorizationHeader = "Authorization"
+
+// sseHeartbeat is how often an idle change stream sends a comment so that
+// proxies do not time it out.
+const sseHeartbeat = 15 * time.Second
+
+// Handler serves the user REST API described by routes, plus its OpenAPI
+// document at GET /openapi.json.
+type Handler struct {
+	svc           service.UserOperations
+	authenticator auth.Authenticator
+	idempotency   *service.IdempotentCreator
+	limits        *service.RateLimits
+	limitKey      func(*http.Request) string
+	changes       repository.UserWatcher
+	mux           *http.ServeMux
+	openAPI       []byte
+}
+
+type Option func(*Handler)
+
+// WithAuthenticator requires a bearer token that a accepts on every API
+// request, answering 401 otherwise, and runs the request as the resulting
+// principal through service.NewAuthorizedUserService. The OpenAPI document
+// stays public.
+func WithAuthenticator(a auth.Authenticator) Option {
+	return func(h *Handler) {
+		h.authenticator = a
+	}
+}
+
+// WithIdempotency routes POST /users through c, honouring the
+// Idempotency-Key header. c should wrap the same service as the handler.
+// Under WithAuthenticator each principal has its own key space.
+func WithIdempotency(c *service.IdempotentCreator) Option {
+	return func(h *Handler) {
+		h.idempotency = c
+	}
+}
+
+// WithRateLimits charges every API request to the key returned by key: GET
+// requests spend read budget, the rest write budget. Exhausted callers get
+// 429 with a Retry-After header. key runs after authentication; a nil key
+// limits by principal, or by client IP when there is no authenticator.
+// Under WithAuthenticator every request also spends the attempt budget of
+// its client IP before the token is checked, which bounds token guessing.
+func WithRateLimits(limits *service.RateLimits, key func(*http.Request) string) Option {
+	return func(h *Handler) {
+		if key == nil {
+			key = callerKey
+		}
+		h.limits = limits
+		h.limitKey = key
+	}
+}
+
+// WithChangeFeed serves the changes published by w as Server-Sent Events at
+// GET /users/changes. w should be the repository behind the handler's
+// service. WithAuthenticator limits the stream to principals allowed to list
+// users.
+func WithChangeFeed(w repository.UserWatcher) Option {
+	return func(h *Handler) {
+		h.changes = w
+	}
+}
+
+func NewHandler(svc service.UserOperations, opts ...Option) *Handler {
+	h := &Handler{svc: svc, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	routes := h.routes()
+	for _, rt := range routes {
+		h.mux.HandleFunc(rt.method+" "+rt.path, h.throttled(h.authenticated(h.limited(rt.method, rt.handler))))
+	}
+
+	doc, err := json.Marshal(openAPIDocument(routes))
+	if err != nil {
+		// Only reachable if a message type stops being JSON-encodable.
+		panic(err)
+	}
+	h.openAPI = doc
+	h.mux.HandleFunc("GET /openapi.json", h.serveOpenAPI)
+	return h
+}
+
+// routes lists every endpoint together with the types it reads and writes.
+// NewHandler registers exactly these and the OpenAPI document is generated
+// from them, so the two cannot drift apart.
+func (h *Handler) routes() []route {
+	create := route{
+		method: http.MethodPost, path: "/users", handler: h.createUser,
+		operationID: "createUser", summary: "Create a user",
+		request: CreateUserRequest{}, status: http.StatusCreated, response: User{},
+		errors: []int{http.StatusBadRequest, http.StatusConflict},
+	}
+	if h.idempotency != nil {
+		create.headers = []string{IdempotencyKeyHeader}
+		create.errors = append(create.errors, http.StatusUnprocessableEntity)
+	}
+
+	routes := []route{
+		create,
+		{
+			method: http.MethodGet, path: "/users", handler: h.getAllUsers,
+			operationID: "getAllUsers", summary: "List all users, or those matching every attribute filter",
+			query:  []string{"attribute"},
+			status: http.StatusOK, response: UserListResponse{},
+			errors: []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodGet, path: "/users/count", handler: h.countUsers,
+			operationID: "countUsers", summary: "Count users",
+			status: http.StatusOK, response: CountResponse{},
+		},
+		{
+			method: http.MethodGet, path: "/users/by-email/{email}", handler: h.getUserByEmail,
+			operationID: "getUserByEmail", summary: "Find a user by email",
+			status: http.StatusOK, response: User{},
+			errors: []int{http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, path: "/users/{id}", handler: h.getUser,
+			operationID: "getUser", summary: "Read a user",
+			status: http.StatusOK, response: User{},
+			errors: []int{http.StatusNotFound},
+		},
+		{
+			method: http.MethodPut, path: "/users/{id}", handler: h.updateUser,
+			operationID: "updateUser", summary: "Replace a user's name, email and age",
+			request: UpdateUserRequest{}, status: http.StatusOK, response: User{},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		},
+		{
+			method: http.MethodPatch, path: "/users/{id}", handler: h.patchUser,
+			operationID: "patchUser", summary: "Update only the given fields of a user",
+			request: PatchUserRequest{}, status: http.StatusOK, response: User{},
+			errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		},
+		{
+			method: http.MethodDelete, path: "/users/{id}", handler: h.deleteUser,
+			operationID: "deleteUser", summary: "Delete a user",
+			status: http.StatusNoContent,
+			errors: []int{http.StatusNotFound},
+		},
+	}
+	if h.changes != nil {
+		routes = append(routes, route{
+			method: http.MethodGet, path: "/users/changes", handler: h.watchChanges,
+			operationID: "watchChanges", summary: "Stream user changes as Server-Sent Events",
+			headers: []string{LastEventIDHeader}, query: []string{"after"},
+			status: http.StatusOK, response: ChangeEvent{}, contentType: "text/event-stream",
+			errors: []int{http.StatusBadRequest, http.StatusGone},
+		})
+	}
+	for i := range routes {
+		routes[i].headers = append(routes[i].headers, AcceptLanguageHeader)
+		if h.authenticator != nil {
+			routes[i].secured = true
+			routes[i].errors = append(routes[i].errors, http.StatusUnauthorized)
+			if routes[i].operationID != "countUsers" {
+				routes[i].errors = append(routes[i].errors, http.StatusForbidden)
+			}
+		}
+		if h.limits != nil {
+			routes[i].errors = append(routes[i].errors, http.StatusTooManyRequests)
+		}
+	}
+	return routes
+}
+
+// throttled charges a request to the attempt budget of its client IP before
+// running next. It wraps authentication, so failed token guesses spend
+// budget too.
+func (h *Handler) throttled(next http.HandlerFunc) http.HandlerFunc {
+	if h.limits == nil || h.authenticator == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.limits.AllowAttempt(clientIP(r)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// limited charges a request with the given method to the caller's budget
+// before running next. It runs after authentication, so the caller is the
+// principal where there is one.
+func (h *Handler) limited(method string, next http.HandlerFunc) http.HandlerFunc {
+	if h.limits == nil {
+		return next
+	}
+	allow := h.limits.AllowWrite
+	if method == http.MethodGet {
+		allow = h.limits.AllowRead
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := allow(h.limitKey(r)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+type principalKey struct{}
+
+// authenticated resolves the caller's bearer token to a principal, stored in
+// the request context for users, before running next.
+func (h *Handler) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	if h.authenticator == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get(AuthorizationHeader), "Bearer ")
+		if !ok {
+			token = ""
+		}
+		principal, err := h.authenticator.Authenticate(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, err)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	}
+}
+
+// principal returns the caller of an authenticated request.
+func principal(r *http.Request) (auth.Principal, bool) {
+	p, ok := r.Context().Value(principalKey{}).(auth.Principal)
+	return p, ok
+}
+
+// users is the service r runs against: the handler's own, narrowed to the
+// caller's permissions when the request is authenticated.
+func (h *Handler) users(r *http.Request) service.UserOperations {
+	if p, ok := principal(r); ok {
+		return service.NewAuthorizedUserService(h.svc, p)
+	}
+	return h.svc
+}
+
+// callerKey identifies the caller of r for rate limiting: the principal of
+// an authenticated request, the client IP otherwise.
+func callerKey(r *http.Request) string {
+	if p, ok := principal(r); ok {
+		return "principal:" + string(p.Role) + ":" + p.UserID
+	}
+	return "ip:" + clientIP(r)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (h *Handler) serveOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(h.openAPI)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	var req CreateUserRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	attrs, err := req.DomainAttributes()
+	if err != nil {
+		writeMalformed(w, r, domain.MalformedBody, err)
+		return
+	}
+	var user *domain.User
+	key := r.Header.Get(IdempotencyKeyHeader)
+	p, authenticated := principal(r)
+	switch {
+	case h.idempotency != nil && authenticated:
+		user, err = h.idempotency.CreateUserAs(p, key, req.Name, req.Email, req.Age, attrs)
+	case h.idempotency != nil:
+		user, err = h.idempotency.CreateUserWithAttributes(key, req.Name, req.Email, req.Age, attrs)
+	default:
+		user, err = h.users(r).CreateUserWithAttributes(req.Name, req.Email, req.Age, attrs)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, UserFromDomain(user))
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.users(r).GetUser(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, UserFromDomain(user))
+}
+
+func (h *Handler) getUserByEmail(w http.ResponseWriter, r *http.Request) {
+	user, err := h.users(r).GetUserByEmail(r.PathValue("email"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, UserFromDomain(user))
+}
+
+// getAllUsers lists every user, or with one or more attribute query
+// parameters (domain.ParseAttributeFilter syntax) only those matching all
+// of them.
+func (h *Handler) getAllUsers(w http.ResponseWriter, r *http.Request) {
+	var filters []domain.AttributeFilter
+	for _, s := range r.URL.Query()["attribute"] {
+		f, err := domain.ParseAttributeFilter(s)
+		if err != nil {
+			writeMalformed(w, r, domain.MalformedFilter, err)
+			return
+		}
+		filters = append(filters, f)
+	}
+
+	var users []*domain.User
+	var err error
+	if len(filters) > 0 {
+		users, err = h.users(r).FindUsersByAttributes(filters...)
+	} else {
+		users, err = h.users(r).GetAllUsers()
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := UserListResponse{Users: make([]User, 0, len(users))}
+	for _, u := range users {
+		resp.Users = append(resp.Users, UserFromDomain(u))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request) {
+	var req UpdateUserRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	user, err := h.users(r).UpdateUser(r.PathValue("id"), req.Name, req.Email, req.Age)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, UserFromDomain(user))
+}
+
+func (h *Handler) patchUser(w http.ResponseWriter, r *http.Request) {
+	var req PatchUserRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	patch, err := req.Domain()
+	if err != nil {
+		writeMalformed(w, r, domain.MalformedBody, err)
+		return
+	}
+	user, err := h.users(r).PatchUser(r.PathValue("id"), patch)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, UserFromDomain(user))
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.users(r).DeleteUser(r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) countUsers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, CountResponse{Count: h.users(r).CountUsers()})
+}
+
+// watcher is the change feed r reads, narrowed like users to what the
+// caller may see.
+func (h *Handler) watcher(r *http.Request) repository.UserWatcher {
+	if p, ok := principal(r); ok {
+		return service.NewAuthorizedUserWatcher(h.changes, p)
+	}
+	return h.changes
+}
+
+// watchChanges streams changes after the Last-Event-ID header or the after
+// query parameter, in that order of preference, or only new changes when
+// neither is given. Users are rendered with UserFromDomain like on every
+// other read. If the stream is cut off because the client fell behind, a
+// final "error" event carries the ErrorResponse.
+func (h *Handler) watchChanges(w http.ResponseWriter, r *http.Request) {
+	changes := h.watcher(r)
+	after := changes.Head()
+	cursor := r.Header.Get(LastEventIDHeader)
+	if cursor == "" {
+		cursor = r.URL.Query().Get("after")
+	}
+	if cursor != "" {
+		var err error
+		if after, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			writeMalformed(w, r, domain.MalformedCursor, err)
+			return
+		}
+	}
+
+	sub, err := changes.Watch(after)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if rc.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+		case change, ok := <-sub.C():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					writeEvent(w, "", "error", ErrorResponse{Code: domain.ErrorCode(err), Message: domain.Message(err, requestLocale(r))})
+					_ = rc.Flush()
+				}
+				return
+			}
+			writeEvent(w, strconv.FormatUint(change.Seq, 10), string(change.Op), ChangeEventFromDomain(change))
+		}
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w io.Writer, id, event string, data any) {
+	payload, _ := json.Marshal(data)
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+// decode reads a JSON body into dst, answering 400 itself when it cannot.
+func decode(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		writeMalformed(w, r, domain.MalformedBody, err)
+		return false
+	}
+	return true
+}
+
+// writeError answers with the status and stable code for err, and the text
+// domain.Message gives it in the locale the caller asked for.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var limited *domain.RateLimitError
+	if errors.As(err, &limited) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+	}
+
+	loc := contentLanguage(w, r)
+	code := domain.ErrorCode(err)
+	writeJSON(w, StatusForCode(code), ErrorResponse{Code: code, Message: domain.Message(err, loc)})
+}
+
+// writeMalformed answers 400 for input the server could not parse, with the
+// parser's error after a prefix in the caller's locale.
+func writeMalformed(w http.ResponseWriter, r *http.Request, input domain.MalformedInput, err error) {
+	loc := contentLanguage(w, r)
+	writeJSON(w, http.StatusBadRequest, ErrorResponse{Code: CodeInvalidRequest, Message: domain.MalformedMessage(input, err.Error(), loc)})
+}
+
+// contentLanguage picks the locale of an error response and announces it.
+func contentLanguage(w http.ResponseWriter, r *http.Request) domain.Locale {
+	loc := requestLocale(r)
+	w.Header().Set("Content-Language", string(loc))
+	w.Header().Add("Vary", AcceptLanguageHeader)
+	return loc
+}
+
+// requestLocale is the supported locale the caller ranks highest in its
+// Accept-Language header, matched on the primary language subtag so that
+// "es-MX;q=0.9" selects Spanish. Entries with a q outside [0, 1] are
+// ignored. Ties keep header order; no match, "*" or a missing header fall
+// back to domain.DefaultLocale.
+func requestLocale(r *http.Request) domain.Locale {
+	best, bestQ := domain.DefaultLocale, 0.0
+	for _, part := range strings.Split(strings.Join(r.Header.Values(AcceptLanguageHeader), ","), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		lang, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		if loc := domain.Locale(strings.ToLower(lang)); loc.Supported() && q > bestQ {
+			best, bestQ = loc, q
+		}
+	}
+	return best
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// The status line is already out, so a failed write has no one left to
+	// report to.
+	_ = json.NewEncoder(w).Encode(body)
+}
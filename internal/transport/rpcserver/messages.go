@@ -0,0 +1,122 @@
+package rpcserver
+
+import (
+	"strings"
+
+	"property-based/internal/domain"
+)
+
+// ServiceName is the name the user service is registered under; methods are
+// called as "UserService.<Method>".
+const ServiceName = "UserService"
+
+// Auth carries the caller's bearer token. Every Args type embeds it; a
+// server built WithAuthenticator rejects calls whose token does not
+// authenticate with ErrUnauthenticated.
+type Auth struct {
+	Token string
+}
+
+// CreateUserArgs.Attributes may be nil.
+type CreateUserArgs struct {
+	Auth
+	Name       string
+	Email      string
+	Age        int
+	Attributes domain.Attributes
+}
+
+type GetUserArgs struct {
+	Auth
+	ID string
+}
+
+type GetUserByEmailArgs struct {
+	Auth
+	Email string
+}
+
+type GetAllUsersArgs struct {
+	Auth
+}
+
+type FindUsersByAttributesArgs struct {
+	Auth
+	Filters []domain.AttributeFilter
+}
+
+type UpdateUserArgs struct {
+	Auth
+	ID    string
+	Name  string
+	Email string
+	Age   int
+}
+
+type PatchUserArgs struct {
+	Auth
+	ID    string
+	Patch domain.UserPatch
+}
+
+type DeleteUserArgs struct {
+	Auth
+	ID string
+}
+
+type CountUsersArgs struct {
+	Auth
+}
+
+type UserReply struct {
+	User *domain.User
+}
+
+type UsersReply struct {
+	Users []*domain.User
+}
+
+type DeleteUserReply struct{}
+
+type CountUsersReply struct {
+	Count int
+}
+
+// RemoteError is the error a call fails with. net/rpc only carries the error
+// string, so it travels as "<code>: <message>" and is parsed back by
+// ParseRemoteError.
+type RemoteError struct {
+	Code    string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Unwrap returns the domain sentinel for Code so errors.Is works on the
+// client side. Internal and unknown codes unwrap to nil.
+func (e *RemoteError) Unwrap() error {
+	return domain.ErrorForCode(e.Code)
+}
+
+// NewRemoteError converts a service error into its wire form. A
+// CodeInternal error travels as a fixed "internal error", since the wrapped
+// cause may name storage details the client has no use for.
+func NewRemoteError(err error) *RemoteError {
+	code := domain.ErrorCode(err)
+	if code == domain.CodeInternal {
+		return &RemoteError{Code: code, Message: "internal error"}
+	}
+	return &RemoteError{Code: code, Message: err.Error()}
+}
+
+// ParseRemoteError reverses RemoteError.Error. Strings without a code
+// prefix are reported as internal errors.
+func ParseRemoteError(s string) *RemoteError {
+	code, msg, ok := strings.Cut(s, ": ")
+	if !ok || strings.ContainsAny(code, " \t\n") {
+		return &RemoteError{Code: domain.CodeInternal, Message: s}
+	}
+	return &RemoteError{Code: code, Message: msg}
+}
@@ -0,0 +1,189 @@
+package rpcserver
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+
+	"property-based/internal/auth"
+	"property-based/internal/service"
+)
+
+// UserServer exposes a service.UserOperations over net/rpc. Its exported
+// methods follow the net/rpc calling convention and are not meant to be
+// called directly.
+type UserServer struct {
+	svc           service.UserOperations
+	authenticator auth.Authenticator
+}
+
+type Option func(*UserServer)
+
+// WithAuthenticator requires every call to carry a token that a accepts,
+// and runs it as the resulting principal through
+// service.NewAuthorizedUserService.
+func WithAuthenticator(a auth.Authenticator) Option {
+	return func(s *UserServer) {
+		s.authenticator = a
+	}
+}
+
+// NewServer returns an rpc.Server with the user service registered under
+// ServiceName.
+func NewServer(svc service.UserOperations, opts ...Option) *rpc.Server {
+	us := &UserServer{svc: svc}
+	for _, opt := range opts {
+		opt(us)
+	}
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(ServiceName, us); err != nil {
+		// Only reachable if UserServer stops satisfying net/rpc's rules.
+		panic(err)
+	}
+	return srv
+}
+
+// ServeConn serves JSON-RPC requests on conn until the peer hangs up.
+func ServeConn(srv *rpc.Server, conn io.ReadWriteCloser) {
+	srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+}
+
+// Serve accepts connections on l and serves each one with JSON-RPC. It
+// returns nil once l is closed.
+func Serve(srv *rpc.Server, l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go ServeConn(srv, conn)
+	}
+}
+
+// as returns the service a call authenticated by a runs against.
+func (s *UserServer) as(a Auth) (service.UserOperations, error) {
+	if s.authenticator == nil {
+		return s.svc, nil
+	}
+	principal, err := s.authenticator.Authenticate(a.Token)
+	if err != nil {
+		return nil, err
+	}
+	return service.NewAuthorizedUserService(s.svc, principal), nil
+}
+
+func (s *UserServer) CreateUser(args CreateUserArgs, reply *UserReply) error {
+	svc, err := s.as(args.Auth)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	user, err := svc.CreateUserWithAttributes(args.Name, args.Email, args.Age, args.Attributes)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	reply.User = user
+	return nil
+}
+
+func (s *UserServer) GetUser(args GetUserArgs, reply *UserReply) error {
+	svc, err := s.as(args.Auth)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	user, err := svc.GetUser(args.ID)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	reply.User = user
+	return nil
+}
+
+func (s *UserServer) GetUserByEmail(args GetUserByEmailArgs, reply *UserReply) error {
+	svc, err := s.as(args.Auth)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	user, err := svc.GetUserByEmail(args.Email)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	reply.User = user
+	return nil
+}
+
+func (s *UserServer) GetAllUsers(args GetAllUsersArgs, reply *UsersReply) error {
+	svc, err := s.as(args.Auth)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	users, err := svc.GetAllUsers()
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	reply.Users = users
+	return nil
+}
+
+func (s *UserServer) FindUsersByAttributes(args FindUsersByAttributesArgs, reply *UsersReply) error {
+	svc, err := s.as(args.Auth)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	users, err := svc.FindUsersByAttributes(args.Filters...)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	reply.Users = users
+	return nil
+}
+
+func (s *UserServer) UpdateUser(args UpdateUserArgs, reply *UserReply) error {
+	svc, err := s.as(args.Auth)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	user, err := svc.UpdateUser(args.ID, args.Name, args.Email, args.Age)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	reply.User = user
+	return nil
+}
+
+func (s *UserServer) PatchUser(args PatchUserArgs, reply *UserReply) error {
+	svc, err := s.as(args.Auth)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	user, err := svc.PatchUser(args.ID, args.Patch)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	reply.User = user
+	return nil
+}
+
+func (s *UserServer) DeleteUser(args DeleteUserArgs, _ *DeleteUserReply) error {
+	svc, err := s.as(args.Auth)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	if err = svc.DeleteUser(args.ID); err != nil {
+		return NewRemoteError(err)
+	}
+	return nil
+}
+
+func (s *UserServer) CountUsers(args CountUsersArgs, reply *CountUsersReply) error {
+	svc, err := s.as(args.Auth)
+	if err != nil {
+		return NewRemoteError(err)
+	}
+	reply.Count = svc.CountUsers()
+	return nil
+}
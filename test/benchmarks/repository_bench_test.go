@@ -0,0 +1,155 @@
+package benchmarks_test
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+)
+
+// repoFactories son las implementaciones comparadas: un solo lock frente a shards
+var repoFactories = []struct {
+	name string
+	new  func() repository.UserRepository
+}{
+	{"single-lock", func() repository.UserRepository { return repository.NewInMemoryUserRepository(repository.WithHistoryLimit(1)) }},
+	{"sharded", func() repository.UserRepository { return repository.NewShardedUserRepository(repository.DefaultShardCount) }},
+}
+
+func benchUser(i int64) *domain.User {
+	id := strconv.FormatInt(i, 10)
+	return &domain.User{ID: "user-" + id, Name: "Bench User", Email: "bench" + id + "@example.com", Age: 30}
+}
+
+// seedRepo carga n usuarios con IDs user-0 … user-(n-1)
+func seedRepo(b *testing.B, repo repository.UserRepository, n int) {
+	b.Helper()
+	for i := 0; i < n; i++ {
+		if err := repo.Create(benchUser(int64(i))); err != nil {
+			b.Fatalf("seed: %v", err)
+		}
+	}
+}
+
+// BenchmarkRepository_ParallelCreate mide creaciones concurrentes de usuarios distintos
+func BenchmarkRepository_ParallelCreate(b *testing.B) {
+	for _, f := range repoFactories {
+		b.Run(f.name, func(b *testing.B) {
+			repo := f.new()
+			var next atomic.Int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := repo.Create(benchUser(next.Add(1))); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkRepository_ParallelMixed mide una carga concurrente con un porcentaje de escrituras
+func BenchmarkRepository_ParallelMixed(b *testing.B) {
+	const size = 10_000
+	for _, writePct := range []int{10, 50, 90} {
+		for _, f := range repoFactories {
+			b.Run(fmt.Sprintf("writes=%d%%/%s", writePct, f.name), func(b *testing.B) {
+				repo := f.new()
+				seedRepo(b, repo, size)
+				var seq atomic.Int64
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						n := seq.Add(1)
+						user := benchUser(n % size)
+						if int(n%100) < writePct {
+							user.Age = int(n%150) + 1
+							if err := repo.Update(user); err != nil {
+								b.Fatal(err)
+							}
+							continue
+						}
+						if _, err := repo.GetByID(user.ID); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+// changeRetentions son retenciones del feed de cambios de órdenes de magnitud distintos
+var changeRetentions = []int{16, 1024, 65536}
+
+// fullChangeFeedRepo devuelve un repositorio con un usuario y el feed de cambios ya
+// lleno hasta el doble de su retención, para medir escrituras en régimen estable
+func fullChangeFeedRepo(tb testing.TB, retention int) (repository.UserRepository, *domain.User) {
+	tb.Helper()
+	repo := repository.NewInMemoryUserRepository(repository.WithHistoryLimit(1), repository.WithChangeRetention(retention))
+	user := benchUser(0)
+	if err := repo.Create(user); err != nil {
+		tb.Fatalf("seed: %v", err)
+	}
+	for i := 0; i < 2*retention; i++ {
+		user.Age = i%150 + 1
+		if err := repo.Update(user); err != nil {
+			tb.Fatalf("fill: %v", err)
+		}
+	}
+	return repo, user
+}
+
+// BenchmarkRepository_ChangeFeedWrite mide una escritura con el feed de cambios lleno;
+// el coste no debe crecer con la retención
+func BenchmarkRepository_ChangeFeedWrite(b *testing.B) {
+	for _, retention := range changeRetentions {
+		b.Run(fmt.Sprintf("retention=%d", retention), func(b *testing.B) {
+			repo, user := fullChangeFeedRepo(b, retention)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				user.Age = i%150 + 1
+				if err := repo.Update(user); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestRepository_ChangeFeedWriteCostIndependentOfRetention
+// Invariante: Una escritura con el feed lleno no copia la retención entera
+// Relación: bytes(escritura, retención grande) ≤ 2 × bytes(escritura, retención pequeña)
+// Bordes: Feed en el punto de recorte (2 × retención), retenciones de 16 a 65536
+func TestRepository_ChangeFeedWriteCostIndependentOfRetention(t *testing.T) {
+	bytesPerWrite := func(retention int) uint64 {
+		repo, user := fullChangeFeedRepo(t, retention)
+		writes := 4 * retention
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for i := 0; i < writes; i++ {
+			user.Age = i%150 + 1
+			if err := repo.Update(user); err != nil {
+				t.Fatal(err)
+			}
+		}
+		runtime.ReadMemStats(&after)
+		return (after.TotalAlloc - before.TotalAlloc) / uint64(writes)
+	}
+
+	baseline := bytesPerWrite(changeRetentions[0])
+	for _, retention := range changeRetentions[1:] {
+		if got := bytesPerWrite(retention); got > 2*baseline {
+			t.Fatalf("retention %d: %d bytes per write, want at most %d (twice retention %d)",
+				retention, got, 2*baseline, changeRetentions[0])
+		}
+	}
+}
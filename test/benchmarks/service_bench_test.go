@@ -0,0 +1,122 @@
+package benchmarks_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+)
+
+// benchSizes son los tamaños de dataset precargados antes de medir
+var benchSizes = []int{100, 1_000, 10_000}
+
+// benchParallelism son los multiplicadores de GOMAXPROCS usados con RunParallel
+var benchParallelism = []int{1, 4, 16}
+
+// seedService crea n usuarios por el servicio y devuelve los creados
+func seedService(b *testing.B, svc *service.UserService, n int) []*domain.User {
+	b.Helper()
+	users := make([]*domain.User, n)
+	for i := range users {
+		u, err := svc.CreateUser("Bench User", fmt.Sprintf("seed%d@example.com", i), 30)
+		if err != nil {
+			b.Fatalf("seed: %v", err)
+		}
+		users[i] = u
+	}
+	return users
+}
+
+// runServiceBench ejecuta op en paralelo para cada tamaño y nivel de paralelismo.
+// op recibe el número de operación, único entre goroutines.
+func runServiceBench(b *testing.B, sizes []int, op func(b *testing.B, svc *service.UserService, users []*domain.User) func(n int64) error) {
+	for _, size := range sizes {
+		for _, p := range benchParallelism {
+			b.Run(fmt.Sprintf("size=%d/par=%d", size, p), func(b *testing.B) {
+				svc := service.NewUserService(repository.NewInMemoryUserRepository(repository.WithHistoryLimit(1)))
+				users := seedService(b, svc, size)
+				call := op(b, svc, users)
+				var seq atomic.Int64
+				b.SetParallelism(p)
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if err := call(seq.Add(1)); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+func BenchmarkUserService_CreateUser(b *testing.B) {
+	runServiceBench(b, benchSizes, func(_ *testing.B, svc *service.UserService, _ []*domain.User) func(int64) error {
+		return func(n int64) error {
+			_, err := svc.CreateUser("Bench User", fmt.Sprintf("new%d@example.com", n), 30)
+			return err
+		}
+	})
+}
+
+func BenchmarkUserService_GetUser(b *testing.B) {
+	runServiceBench(b, benchSizes, func(_ *testing.B, svc *service.UserService, users []*domain.User) func(int64) error {
+		return func(n int64) error {
+			_, err := svc.GetUser(users[n%int64(len(users))].ID)
+			return err
+		}
+	})
+}
+
+func BenchmarkUserService_GetUserByEmail(b *testing.B) {
+	runServiceBench(b, benchSizes, func(_ *testing.B, svc *service.UserService, users []*domain.User) func(int64) error {
+		return func(n int64) error {
+			_, err := svc.GetUserByEmail(users[n%int64(len(users))].Email)
+			return err
+		}
+	})
+}
+
+func BenchmarkUserService_UpdateUser(b *testing.B) {
+	runServiceBench(b, benchSizes, func(_ *testing.B, svc *service.UserService, users []*domain.User) func(int64) error {
+		return func(n int64) error {
+			u := users[n%int64(len(users))]
+			_, err := svc.UpdateUser(u.ID, u.Name, u.Email, int(n%150)+1)
+			return err
+		}
+	})
+}
+
+// BenchmarkUserService_DeleteUser precarga b.N usuarios extra fuera del tiempo medido,
+// de modo que cada iteración borra uno distinto sobre un dataset del tamaño indicado
+func BenchmarkUserService_DeleteUser(b *testing.B) {
+	runServiceBench(b, benchSizes, func(b *testing.B, svc *service.UserService, _ []*domain.User) func(int64) error {
+		victims := make([]string, b.N)
+		for i := range victims {
+			u, err := svc.CreateUser("Bench User", fmt.Sprintf("victim%d@example.com", i), 30)
+			if err != nil {
+				b.Fatalf("seed victims: %v", err)
+			}
+			victims[i] = u.ID
+		}
+		return func(n int64) error {
+			return svc.DeleteUser(victims[n-1])
+		}
+	})
+}
+
+// BenchmarkUserService_GetAllUsers copia el dataset completo en cada iteración,
+// así que el tamaño mayor se omite para mantener la suite manejable
+func BenchmarkUserService_GetAllUsers(b *testing.B) {
+	runServiceBench(b, benchSizes[:2], func(_ *testing.B, svc *service.UserService, _ []*domain.User) func(int64) error {
+		return func(int64) error {
+			_, err := svc.GetAllUsers()
+			return err
+		}
+	})
+}
@@ -0,0 +1,461 @@
+package user_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/client/httpclient"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/internal/transport/httpserver"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// attributeService crea un servicio cuya política declara schema
+func attributeService(t *rapid.T, repo repository.UserRepository, schema domain.AttributeSchema) *service.UserService {
+	policy := *domain.DefaultValidationPolicy
+	policy.Attributes = schema
+	validation, err := domain.NewValidationPolicy(policy)
+	helpers.AssertNoError(t, err, "Policy with attribute schema")
+	return service.NewUserService(repo, service.WithValidationPolicy(validation))
+}
+
+// createWithAttributes da de alta un usuario válido y le fija attrs con un parche
+func createWithAttributes(t *rapid.T, svc *service.UserService, attrs domain.Attributes) *domain.User {
+	data := generators.ValidUserStruct().Draw(t, "user_data")
+	user, err := svc.CreateUser(data.Name, data.Email, data.Age)
+	helpers.AssertNoError(t, err, "Create user")
+
+	patch := domain.UserPatch{Attributes: map[string]*domain.AttributeValue{}}
+	for name, v := range attrs {
+		patch.Attributes[name] = &v
+	}
+	user, err = svc.PatchUser(user.ID, patch)
+	helpers.AssertNoError(t, err, "Set attributes")
+	return user
+}
+
+// TestProperty_UserAttributes_CloneIsDeep
+// Invariante: Ni un clon ni un usuario leído comparten el mapa de atributos con el original
+// Relación: mutar(Clone(u).Attributes) ⟹ u sin cambios ∧ mutar(repo.GetByID(id).Attributes) ⟹ repo.GetByID(id) sin cambios
+// Bordes: Usuario sin atributos (mapa nil), añadir, borrar y sobrescribir un atributo, cada repositorio
+func TestProperty_UserAttributes_CloneIsDeep(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		schema := generators.AttributeSchema().Draw(t, "schema")
+		repo := rapid.SampledFrom([]func() repository.UserRepository{
+			func() repository.UserRepository { return repository.NewInMemoryUserRepository() },
+			func() repository.UserRepository { return repository.NewShardedUserRepository(4) },
+			func() repository.UserRepository {
+				return repository.NewCachingUserRepository(repository.NewInMemoryUserRepository(), repository.CacheConfig{
+					Capacity: 8, TTL: time.Minute, Now: time.Now,
+				})
+			},
+		}).Draw(t, "repository")()
+		user := createWithAttributes(t, attributeService(t, repo, schema), generators.AttributesFor(schema).Draw(t, "attributes"))
+		// Copia independiente de Clone, que es lo que se prueba
+		original := maps.Clone(user.Attributes)
+
+		mutate := func(attrs domain.Attributes) {
+			if attrs == nil {
+				return
+			}
+			name := rapid.SampledFrom(slices.Concat(attrs.Names(), []string{"extra"})).Draw(t, "mutated")
+			if rapid.Bool().Draw(t, "delete") {
+				delete(attrs, name)
+			} else {
+				attrs[name] = domain.StringAttribute("mutated")
+			}
+		}
+
+		clone := user.Clone()
+		mutate(clone.Attributes)
+		if !user.Attributes.Equal(original) {
+			t.Fatalf("Mutating a clone changed the original: %v, want %v", user.Attributes, original)
+		}
+
+		read, err := repo.GetByID(user.ID)
+		helpers.AssertNoError(t, err, "GetByID")
+		mutate(read.Attributes)
+		stored, err := repo.GetByID(user.ID)
+		helpers.AssertNoError(t, err, "GetByID after mutation")
+		if !stored.Attributes.Equal(original) {
+			t.Fatalf("Mutating a read user changed the stored one: %v, want %v", stored.Attributes, original)
+		}
+	})
+}
+
+// TestProperty_UserAttributes_SchemaRejectsUndeclaredOrMistyped
+// Invariante: Solo se guardan atributos declarados en el esquema y con su tipo
+// Relación: parche válido ⟹ atributos == modelo; atributo inválido ⟹ ErrInvalidAttribute con su nombre ∧ usuario sin cambios
+// Bordes: Nombre no declarado, tipo distinto, borrar un atributo ausente, mensaje en cada locale
+func TestProperty_UserAttributes_SchemaRejectsUndeclaredOrMistyped(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		schema := generators.AttributeSchema().Draw(t, "schema")
+		svc := attributeService(t, repository.NewInMemoryUserRepository(), schema)
+		user := createWithAttributes(t, svc, generators.AttributesFor(schema).Draw(t, "attributes"))
+
+		patch := generators.ValidAttributePatchFor(schema).Draw(t, "patch")
+		expected := user.Clone()
+		for name, v := range patch.Attributes {
+			if v == nil {
+				delete(expected.Attributes, name)
+			} else {
+				if expected.Attributes == nil {
+					expected.Attributes = domain.Attributes{}
+				}
+				expected.Attributes[name] = *v
+			}
+		}
+		patched, err := svc.PatchUser(user.ID, patch)
+		helpers.AssertNoError(t, err, "Valid attribute patch")
+		helpers.AssertUserEquals(t, expected, patched, "Patched attributes")
+
+		invalid := generators.InvalidAttributesFor(schema).Draw(t, "invalid")
+		bad := domain.UserPatch{Attributes: map[string]*domain.AttributeValue{}}
+		for name, v := range invalid.Attributes {
+			bad.Attributes[name] = &v
+		}
+		_, err = svc.PatchUser(user.ID, bad)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidAttribute, "Invalid attribute patch")
+		var attrErr *domain.AttributeError
+		if !errors.As(err, &attrErr) || attrErr.Name != invalid.Name {
+			t.Fatalf("Expected an AttributeError for %q, got %v", invalid.Name, err)
+		}
+		if undeclared := attrErr.Type == ""; undeclared != invalid.Undeclared {
+			t.Fatalf("AttributeError %+v: expected undeclared=%v", attrErr, invalid.Undeclared)
+		}
+		for _, loc := range domain.Locales() {
+			if msg := domain.Message(err, loc); !strings.Contains(msg, strconv.Quote(invalid.Name)) {
+				t.Fatalf("%s message %q does not name the attribute %q", loc, msg, invalid.Name)
+			}
+		}
+
+		got, err := svc.GetUser(user.ID)
+		helpers.AssertNoError(t, err, "GetUser after rejected patch")
+		helpers.AssertUserEquals(t, patched, got, "Rejected patch left the user unchanged")
+	})
+}
+
+// compareAttribute es el modelo de orden: interpreta text con el tipo de v y
+// devuelve el signo de v - text, o false si text no es de ese tipo
+func compareAttribute(v domain.AttributeValue, text string) (int, bool) {
+	switch v.Type() {
+	case domain.AttributeNumber:
+		want, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, false
+		}
+		n := v.Value().(float64)
+		switch {
+		case n < want:
+			return -1, true
+		case n > want:
+			return 1, true
+		}
+		return 0, true
+	case domain.AttributeBool:
+		want, err := strconv.ParseBool(text)
+		if err != nil {
+			return 0, false
+		}
+		rank := map[bool]int{false: 0, true: 1}
+		return rank[v.Value().(bool)] - rank[want], true
+	case domain.AttributeDate:
+		want, err := time.Parse(domain.AttributeDateLayout, text)
+		if err != nil {
+			return 0, false
+		}
+		day, _ := time.Parse(domain.AttributeDateLayout, v.Value().(string))
+		return day.Compare(want), true
+	}
+	return strings.Compare(v.Value().(string), text), true
+}
+
+// matchesFilter es el modelo de un filtro sobre un usuario
+func matchesFilter(u *domain.User, f domain.AttributeFilter) bool {
+	v, ok := u.Attributes[f.Name]
+	if !ok {
+		return false
+	}
+	if f.Op == domain.FilterExists {
+		return true
+	}
+	c, ok := compareAttribute(v, f.Value)
+	if !ok {
+		return false
+	}
+	switch f.Op {
+	case domain.FilterEqual:
+		return c == 0
+	case domain.FilterNotEqual:
+		return c != 0
+	case domain.FilterLess:
+		return c < 0
+	case domain.FilterLessEqual:
+		return c <= 0
+	case domain.FilterGreater:
+		return c > 0
+	}
+	return c >= 0
+}
+
+// TestProperty_UserAttributes_FilterMatchesModel
+// Invariante: Filtrar por atributos devuelve exactamente los usuarios que cumplen todos los filtros
+// Relación: FindUsersByAttributes(fs) == {u | ∀ f ∈ fs: modelo(u, f)} ordenado por ID == GET /users?attribute=fs == RPC FindUsersByAttributes(fs) ∧ Parse(f.String()) == f
+// Bordes: Sin filtros (todos), atributo ausente o no declarado, valor de otro tipo, límites iguales con <= y >=
+func TestProperty_UserAttributes_FilterMatchesModel(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		schema := generators.AttributeSchema().Draw(t, "schema")
+		svc := attributeService(t, repository.NewInMemoryUserRepository(), schema)
+		server := httptest.NewServer(httpserver.NewHandler(svc))
+		defer server.Close()
+
+		var users []*domain.User
+		for i := rapid.IntRange(0, 8).Draw(t, "user_count"); i > 0; i-- {
+			users = append(users, createWithAttributes(t, svc, generators.AttributesFor(schema).Draw(t, "attributes")))
+		}
+		filters := rapid.SliceOfN(generators.AttributeFilterFor(schema), 0, 3).Draw(t, "filters")
+		for i := range filters {
+			if len(users) == 0 || filters[i].Op == domain.FilterExists || !rapid.Bool().Draw(t, "exact_value") {
+				continue
+			}
+			// Un valor existente, para cubrir los límites exactos
+			u := rapid.SampledFrom(users).Draw(t, "exact_user")
+			if names := u.Attributes.Names(); len(names) > 0 {
+				name := rapid.SampledFrom(names).Draw(t, "exact_name")
+				filters[i].Name, filters[i].Value = name, u.Attributes[name].String()
+			}
+		}
+
+		for _, f := range filters {
+			parsed, err := domain.ParseAttributeFilter(f.String())
+			helpers.AssertNoError(t, err, "Parse filter")
+			if parsed != f {
+				t.Fatalf("Filter %q parsed as %+v, want %+v", f, parsed, f)
+			}
+		}
+
+		var expected []string
+		for _, u := range users {
+			if !slices.ContainsFunc(filters, func(f domain.AttributeFilter) bool { return !matchesFilter(u, f) }) {
+				expected = append(expected, u.ID)
+			}
+		}
+		slices.Sort(expected)
+
+		ids := func(users []*domain.User) []string {
+			out := make([]string, 0, len(users))
+			for _, u := range users {
+				out = append(out, u.ID)
+			}
+			return out
+		}
+		found, err := svc.FindUsersByAttributes(filters...)
+		helpers.AssertNoError(t, err, "FindUsersByAttributes")
+		if got := ids(found); !slices.Equal(got, expected) {
+			t.Fatalf("Filters %v: expected %v, got %v", filters, expected, got)
+		}
+
+		remote, err := httpclient.New(server.URL, httpclient.Config{}).FindUsersByAttributes(filters...)
+		helpers.AssertNoError(t, err, "GET /users?attribute=")
+		got := ids(remote)
+		slices.Sort(got)
+		if !slices.Equal(got, expected) {
+			t.Fatalf("Filters %v over HTTP: expected %v, got %v", filters, expected, got)
+		}
+
+		rpc := dialRPC(svc)
+		defer rpc.Close()
+		remote, err = rpc.FindUsersByAttributes(filters...)
+		helpers.AssertNoError(t, err, "RPC FindUsersByAttributes")
+		if got := ids(remote); !slices.Equal(got, expected) {
+			t.Fatalf("Filters %v over RPC: expected %v, got %v", filters, expected, got)
+		}
+	})
+}
+
+// TestProperty_UserAttributes_RoundTripThroughEveryBackend
+// Invariante: Los atributos llegan intactos, con su tipo, a través de cada repositorio y transporte
+// Relación: ∀ backend b: b.leer(b.escribir(u)).Attributes == u.Attributes
+// Bordes: Sin atributos, cadena vacía, número 0 y extremos de float64, fechas de 1900 a 2100, esquema distinto al restaurar
+func TestProperty_UserAttributes_RoundTripThroughEveryBackend(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		schema := generators.AttributeSchema().Draw(t, "schema")
+		source := repository.NewInMemoryUserRepository()
+		svc := attributeService(t, source, schema)
+		user := createWithAttributes(t, svc, generators.AttributesFor(schema).Draw(t, "attributes"))
+
+		check := func(backend string, got *domain.User, err error) {
+			t.Helper()
+			helpers.AssertNoError(t, err, backend)
+			if !got.Attributes.Equal(user.Attributes) {
+				t.Fatalf("%s: expected attributes %v, got %v", backend, user.Attributes, got.Attributes)
+			}
+		}
+
+		for name, repo := range map[string]repository.UserRepository{
+			"sharded": repository.NewShardedUserRepository(3),
+			"caching": repository.NewCachingUserRepository(repository.NewInMemoryUserRepository(), repository.CacheConfig{
+				Capacity: 4, TTL: time.Minute, Now: time.Now,
+			}),
+		} {
+			helpers.AssertNoError(t, repo.Create(user), name+" create")
+			got, err := repo.GetByID(user.ID)
+			check(name, got, err)
+		}
+
+		var buf bytes.Buffer
+		_, err := source.Snapshot().WriteTo(&buf)
+		helpers.AssertNoError(t, err, "Write snapshot")
+		snap, err := repository.ReadSnapshot(&buf)
+		helpers.AssertNoError(t, err, "Read snapshot")
+		// Restaurar no depende del esquema vigente
+		restored := repository.NewInMemoryUserRepository()
+		helpers.AssertNoError(t, restored.Restore(snap), "Restore")
+		got, err := restored.GetByID(user.ID)
+		check("snapshot", got, err)
+
+		server := httptest.NewServer(httpserver.NewHandler(svc))
+		defer server.Close()
+		got, err = httpclient.New(server.URL, httpclient.Config{}).GetUser(user.ID)
+		check("http", got, err)
+
+		rpc := dialRPC(svc)
+		defer rpc.Close()
+		got, err = rpc.GetUser(user.ID)
+		check("rpc", got, err)
+
+		raw, err := json.Marshal(httpserver.ChangeEventFromDomain(domain.UserChange{Op: domain.ChangeUpdated, User: user}))
+		helpers.AssertNoError(t, err, "Encode change event")
+		var event httpserver.ChangeEvent
+		helpers.AssertNoError(t, json.Unmarshal(raw, &event), "Decode change event")
+		check("change event", event.User.Domain(), nil)
+	})
+}
+
+// TestProperty_UserAttributes_PutRejectsAttributes
+// Invariante: PUT no acepta atributos en lugar de descartarlos en silencio, y conserva los guardados
+// Relación: PUT con "attributes" ⟹ 400 invalid_request ∧ usuario sin cambios; PUT sin ellos ⟹ Attributes == los guardados
+// Bordes: Objeto de atributos vacío, los mismos atributos que ya tiene, usuario sin atributos
+func TestProperty_UserAttributes_PutRejectsAttributes(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		schema := generators.AttributeSchema().Draw(t, "schema")
+		svc := attributeService(t, repository.NewInMemoryUserRepository(), schema)
+		server := httptest.NewServer(httpserver.NewHandler(svc))
+		defer server.Close()
+
+		user := createWithAttributes(t, svc, generators.AttributesFor(schema).Draw(t, "attributes"))
+		sent := map[string]httpserver.Attribute{}
+		for name, v := range generators.AttributesFor(schema).Draw(t, "sent") {
+			sent[name] = httpserver.AttributeFromDomain(v)
+		}
+		age := generators.ValidAge().Draw(t, "age")
+		body := map[string]any{"name": user.Name, "email": user.Email, "age": age, "attributes": sent}
+		encoded, err := json.Marshal(body)
+		helpers.AssertNoError(t, err, "Marshal PUT body")
+
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/users/"+user.ID, bytes.NewReader(encoded))
+		resp, err := http.DefaultClient.Do(req)
+		helpers.AssertNoError(t, err, "PUT with attributes")
+		var apiErr httpserver.ErrorResponse
+		helpers.AssertNoError(t, json.NewDecoder(resp.Body).Decode(&apiErr), "Decode error")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || apiErr.Code != httpserver.CodeInvalidRequest {
+			t.Fatalf("PUT %s: expected 400 %s, got %d %s", encoded, httpserver.CodeInvalidRequest, resp.StatusCode, apiErr.Code)
+		}
+		stored, err := svc.GetUser(user.ID)
+		helpers.AssertNoError(t, err, "GetUser after rejected PUT")
+		helpers.AssertUserEquals(t, user, stored, "Rejected PUT")
+
+		updated, err := httpclient.New(server.URL, httpclient.Config{}).UpdateUser(user.ID, user.Name, user.Email, age)
+		helpers.AssertNoError(t, err, "PUT without attributes")
+		if !updated.Attributes.Equal(user.Attributes) {
+			t.Fatalf("PUT changed attributes from %v to %v", user.Attributes, updated.Attributes)
+		}
+	})
+}
+
+// TestProperty_UserAttributes_CreateSetsAttributesOnEveryTransport
+// Invariante: Un alta con atributos los guarda tal cual, en proceso, por HTTP y por JSON-RPC, y valida el esquema como un parche
+// Relación: Create(u, attrs).Attributes == GetUser(id).Attributes == attrs; attrs inválidos ⟹ ErrInvalidAttribute ∧ CountUsers sin cambios;
+// misma clave de idempotencia con otros atributos ⟹ ErrIdempotencyKeyReused
+// Bordes: Sin atributos, atributo no declarado, valor de otro tipo, alta con clave de idempotencia por HTTP
+func TestProperty_UserAttributes_CreateSetsAttributesOnEveryTransport(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		schema := generators.AttributeSchema().Draw(t, "schema")
+		svc := attributeService(t, repository.NewInMemoryUserRepository(), schema)
+		keyed := service.NewIdempotentCreator(svc, repository.NewInMemoryIdempotencyStore(), service.DefaultIdempotencyConfig)
+		server := httptest.NewServer(httpserver.NewHandler(svc, httpserver.WithIdempotency(keyed)))
+		defer server.Close()
+		client := httpclient.New(server.URL, httpclient.Config{})
+		rpc := dialRPC(svc)
+		defer rpc.Close()
+
+		type creator func(name, email string, age int, attrs domain.Attributes) (*domain.User, error)
+		transports := map[string]creator{
+			"service": svc.CreateUserWithAttributes,
+			"http":    client.CreateUserWithAttributes,
+			"http_keyed": func(name, email string, age int, attrs domain.Attributes) (*domain.User, error) {
+				return client.CreateUserWithKeyAndAttributes("key-"+email, name, email, age, attrs)
+			},
+			"rpc": rpc.CreateUserWithAttributes,
+		}
+		data := rapid.SliceOfNDistinct(generators.ValidUserStruct(), 2*len(transports), 2*len(transports), func(u generators.ValidUserData) string {
+			return strings.ToLower(strings.TrimSpace(u.Email))
+		}).Draw(t, "users")
+
+		for _, name := range slices.Sorted(maps.Keys(transports)) {
+			create := transports[name]
+			u := data[0]
+			data = data[1:]
+
+			attrs := generators.AttributesFor(schema).Draw(t, name+"_attributes")
+			created, err := create(u.Name, u.Email, u.Age, attrs)
+			helpers.AssertNoError(t, err, name+" create with attributes")
+			if !created.Attributes.Equal(attrs) {
+				t.Fatalf("%s: expected attributes %v, got %v", name, attrs, created.Attributes)
+			}
+			stored, err := svc.GetUser(created.ID)
+			helpers.AssertNoError(t, err, name+" get created user")
+			helpers.AssertUserEquals(t, created, stored, name+" stored user")
+
+			before := svc.CountUsers()
+			invalid := generators.InvalidAttributesFor(schema).Draw(t, name+"_invalid")
+			u = data[0]
+			data = data[1:]
+			_, err = create(u.Name, u.Email, u.Age, invalid.Attributes)
+			helpers.AssertErrorIs(t, err, domain.ErrInvalidAttribute, name+" create with invalid attributes")
+			if after := svc.CountUsers(); after != before {
+				t.Fatalf("%s: rejected create changed the count from %d to %d", name, before, after)
+			}
+		}
+
+		u := generators.ValidUserStruct().Filter(func(u generators.ValidUserData) bool {
+			_, err := svc.GetUserByEmail(u.Email)
+			return errors.Is(err, domain.ErrNotFound)
+		}).Draw(t, "keyed_user")
+		attrs := generators.AttributesFor(schema).Draw(t, "keyed_attributes")
+		first, err := client.CreateUserWithKeyAndAttributes("key", u.Name, u.Email, u.Age, attrs)
+		helpers.AssertNoError(t, err, "Keyed create with attributes")
+		replayed, err := client.CreateUserWithKeyAndAttributes("key", u.Name, u.Email, u.Age, attrs.Clone())
+		helpers.AssertNoError(t, err, "Keyed retry with the same attributes")
+		helpers.AssertUserEquals(t, first, replayed, "Keyed retry")
+		if other := generators.AttributesFor(schema).Draw(t, "other_attributes"); !other.Equal(attrs) {
+			_, err = client.CreateUserWithKeyAndAttributes("key", u.Name, u.Email, u.Age, other)
+			helpers.AssertErrorIs(t, err, domain.ErrIdempotencyKeyReused, "Keyed retry with other attributes")
+		}
+	})
+}
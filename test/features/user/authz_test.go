@@ -0,0 +1,163 @@
+package user_test
+
+import (
+	"errors"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/auth"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// allowed es la tabla de permisos esperada, escrita de forma independiente a auth.Authorize
+func allowed(role auth.Role, op string, own bool) bool {
+	switch role {
+	case auth.RoleAdmin:
+		return true
+	case auth.RoleAuditor:
+		return op == service.OpGetUser || op == service.OpGetUserByEmail || op == service.OpGetAllUsers ||
+			op == service.OpFindUsersByAttributes
+	case auth.RoleUser:
+		return own && (op == service.OpGetUser || op == service.OpGetUserByEmail || op == service.OpUpdateUser || op == service.OpPatchUser)
+	}
+	return false
+}
+
+// TestProperty_UserAuthz_EnforcesRolePolicy
+// Invariante: Una operación prohibida devuelve ErrForbidden y no cambia el estado
+// Relación: permitido(rol, op, propio) ⟹ resultado == servicio sin autorización; ¬permitido ⟹ ErrForbidden
+// Bordes: Usuario sobre su propio registro vs ajeno, auditor intentando escribir, rol desconocido
+func TestProperty_UserAuthz_EnforcesRolePolicy(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		base := service.NewUserService(repo)
+
+		selfData := generators.ValidUserStruct().Draw(t, "self")
+		self, err := base.CreateUser(selfData.Name, selfData.Email, selfData.Age)
+		helpers.AssertNoError(t, err, "Create self")
+		otherData := generators.ValidUserStruct().Draw(t, "other")
+		other, err := base.CreateUser(otherData.Name, otherData.Email, otherData.Age)
+		helpers.AssertNoError(t, err, "Create other")
+
+		role := rapid.SampledFrom([]auth.Role{auth.RoleAdmin, auth.RoleUser, auth.RoleAuditor, "guest"}).Draw(t, "role")
+		svc := service.NewAuthorizedUserService(base, auth.Principal{UserID: self.ID, Role: role})
+
+		target := other
+		own := rapid.Bool().Draw(t, "own_record")
+		if own {
+			target = self
+		}
+
+		before := snapshotUsers(t, repo)
+		data := generators.ValidUserStruct().Draw(t, "data")
+
+		op := rapid.SampledFrom([]string{
+			service.OpCreateUser, service.OpGetUser, service.OpGetUserByEmail, service.OpGetAllUsers,
+			service.OpFindUsersByAttributes, service.OpUpdateUser, service.OpPatchUser, service.OpDeleteUser,
+		}).Draw(t, "op")
+
+		var opErr error
+		switch op {
+		case service.OpCreateUser:
+			_, opErr = svc.CreateUser(data.Name, data.Email, data.Age)
+			own = false
+		case service.OpGetUser:
+			var got *domain.User
+			got, opErr = svc.GetUser(target.ID)
+			if opErr == nil {
+				helpers.AssertUserEquals(t, target, got, "Authorized read")
+			}
+		case service.OpGetUserByEmail:
+			_, opErr = svc.GetUserByEmail(target.Email)
+		case service.OpGetAllUsers:
+			_, opErr = svc.GetAllUsers()
+			own = false
+		case service.OpFindUsersByAttributes:
+			_, opErr = svc.FindUsersByAttributes()
+			own = false
+		case service.OpUpdateUser:
+			_, opErr = svc.UpdateUser(target.ID, data.Name, data.Email, data.Age)
+		case service.OpPatchUser:
+			_, opErr = svc.PatchUser(target.ID, domain.UserPatch{Age: &data.Age})
+		case service.OpDeleteUser:
+			opErr = svc.DeleteUser(target.ID)
+		}
+
+		if allowed(role, op, own) {
+			helpers.AssertNoError(t, opErr, "Allowed "+op+" for "+string(role))
+			return
+		}
+		if !errors.Is(opErr, domain.ErrForbidden) {
+			t.Fatalf("%s by %s (own=%v): expected ErrForbidden, got %v", op, role, own, opErr)
+		}
+		if !sameSnapshot(before, snapshotUsers(t, repo)) {
+			t.Fatalf("Forbidden %s by %s modified the repository", op, role)
+		}
+	})
+}
+
+// TestProperty_UserAuthz_EmailLookupDoesNotLeakExistence
+// Invariante: Un usuario sin permiso de listado no distingue emails ajenos de inexistentes
+// Relación: rol == user ⟹ GetUserByEmail(ajeno) == GetUserByEmail(inexistente) == ErrForbidden
+// Bordes: Email propio (permitido), ajeno existente, nunca registrado
+func TestProperty_UserAuthz_EmailLookupDoesNotLeakExistence(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		base := service.NewUserService(repository.NewInMemoryUserRepository())
+
+		selfData := generators.ValidUserStruct().Draw(t, "self")
+		self, err := base.CreateUser(selfData.Name, selfData.Email, selfData.Age)
+		helpers.AssertNoError(t, err, "Create self")
+		otherData := generators.ValidUserStruct().Draw(t, "other")
+		other, err := base.CreateUser(otherData.Name, otherData.Email, otherData.Age)
+		helpers.AssertNoError(t, err, "Create other")
+
+		svc := service.NewAuthorizedUserService(base, auth.Principal{UserID: self.ID, Role: auth.RoleUser})
+
+		got, err := svc.GetUserByEmail(self.Email)
+		helpers.AssertNoError(t, err, "Own email")
+		helpers.AssertUserEquals(t, self, got, "Own record by email")
+
+		_, err = svc.GetUserByEmail(other.Email)
+		helpers.AssertErrorIs(t, err, domain.ErrForbidden, "Other user's email")
+
+		_, err = svc.GetUserByEmail(generators.ValidEmail().Draw(t, "unknown_email"))
+		helpers.AssertErrorIs(t, err, domain.ErrForbidden, "Unknown email")
+	})
+}
+
+// TestProperty_UserAuthz_TokenAuthentication
+// Invariante: Solo tokens registrados y no revocados se autentican
+// Relación: Authenticate(token registrado) == principal; revocado/desconocido/vacío ⟹ ErrUnauthenticated
+// Bordes: Token vacío, token revocado, prefijo de un token válido
+func TestProperty_UserAuthz_TokenAuthentication(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		authenticator := auth.NewTokenAuthenticator()
+
+		token := rapid.StringMatching(`[a-zA-Z0-9]{16,32}`).Draw(t, "token")
+		principal := auth.Principal{
+			UserID: rapid.StringMatching(`[a-f0-9]{8}`).Draw(t, "user_id"),
+			Role:   rapid.SampledFrom([]auth.Role{auth.RoleAdmin, auth.RoleUser, auth.RoleAuditor}).Draw(t, "role"),
+		}
+		authenticator.Register(token, principal)
+
+		got, err := authenticator.Authenticate(token)
+		helpers.AssertNoError(t, err, "Registered token")
+		if got != principal {
+			t.Fatalf("Expected principal %+v, got %+v", principal, got)
+		}
+
+		for _, bad := range []string{"", token[:len(token)-1], token + "x"} {
+			_, err := authenticator.Authenticate(bad)
+			helpers.AssertErrorIs(t, err, domain.ErrUnauthenticated, "Unknown token")
+		}
+
+		authenticator.Revoke(token)
+		_, err = authenticator.Authenticate(token)
+		helpers.AssertErrorIs(t, err, domain.ErrUnauthenticated, "Revoked token")
+	})
+}
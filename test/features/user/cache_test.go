@@ -0,0 +1,172 @@
+package user_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+func assertSameLookup(t *rapid.T, expected, actual *domain.User, expectedErr, actualErr error, context string) {
+	t.Helper()
+
+	if !errors.Is(actualErr, expectedErr) && !errors.Is(expectedErr, actualErr) {
+		t.Fatalf("%s: expected error %v, got %v", context, expectedErr, actualErr)
+	}
+	if expected == nil || actual == nil {
+		if expected != actual {
+			t.Fatalf("%s: expected %+v, got %+v", context, expected, actual)
+		}
+		return
+	}
+	helpers.AssertUserEquals(t, expected, actual, context)
+}
+
+// TestProperty_UserCache_ObservationallyEquivalent
+// Invariante: El repositorio con caché responde igual que el repositorio sin caché
+// Relación: ∀ secuencia de operaciones: cached.op(x) == plain.op(x)
+// Bordes: Capacidad 1, TTL vencido, cambio de email (clave antigua), IDs/emails en conflicto
+func TestProperty_UserCache_ObservationallyEquivalent(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		plain := repository.NewInMemoryUserRepository()
+		cached := repository.NewCachingUserRepository(repository.NewInMemoryUserRepository(), repository.CacheConfig{
+			Capacity: rapid.IntRange(1, 4).Draw(t, "capacity"),
+			TTL:      time.Duration(rapid.IntRange(0, 10).Draw(t, "ttl_s")) * time.Second,
+			Now:      func() time.Time { return now },
+		})
+
+		ids := []string{"id-0", "id-1", "id-2", "id-3", "id-4"}
+		emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+
+		opCount := rapid.IntRange(10, 60).Draw(t, "op_count")
+		for i := 0; i < opCount; i++ {
+			id := rapid.SampledFrom(ids).Draw(t, "id")
+			email := rapid.SampledFrom(emails).Draw(t, "email")
+
+			switch rapid.IntRange(0, 6).Draw(t, "op") {
+			case 0:
+				user, err := domain.NewUser(id, generators.ValidName().Draw(t, "name"), email, generators.ValidAge().Draw(t, "age"))
+				helpers.AssertNoError(t, err, "Build user")
+				expectedErr, actualErr := plain.Create(user), cached.Create(user)
+				assertSameLookup(t, nil, nil, expectedErr, actualErr, "Create")
+
+			case 1:
+				expected, expectedErr := plain.GetByID(id)
+				actual, actualErr := cached.GetByID(id)
+				assertSameLookup(t, expected, actual, expectedErr, actualErr, "GetByID")
+
+			case 2:
+				expected, expectedErr := plain.GetByEmail(email)
+				actual, actualErr := cached.GetByEmail(email)
+				assertSameLookup(t, expected, actual, expectedErr, actualErr, "GetByEmail")
+
+			case 3:
+				user, err := domain.NewUser(id, generators.ValidName().Draw(t, "new_name"), email, generators.ValidAge().Draw(t, "new_age"))
+				helpers.AssertNoError(t, err, "Build update")
+				expectedErr, actualErr := plain.Update(user), cached.Update(user)
+				assertSameLookup(t, nil, nil, expectedErr, actualErr, "Update")
+
+			case 4:
+				expectedErr, actualErr := plain.Delete(id), cached.Delete(id)
+				assertSameLookup(t, nil, nil, expectedErr, actualErr, "Delete")
+
+			case 5:
+				if plain.Count() != cached.Count() {
+					t.Fatalf("Count mismatch: expected %d, got %d", plain.Count(), cached.Count())
+				}
+
+			case 6:
+				now = now.Add(time.Duration(rapid.IntRange(1, 15).Draw(t, "advance_s")) * time.Second)
+			}
+		}
+
+		for _, id := range ids {
+			expected, expectedErr := plain.GetByID(id)
+			actual, actualErr := cached.GetByID(id)
+			assertSameLookup(t, expected, actual, expectedErr, actualErr, fmt.Sprintf("Final GetByID(%s)", id))
+		}
+		for _, email := range emails {
+			expected, expectedErr := plain.GetByEmail(email)
+			actual, actualErr := cached.GetByEmail(email)
+			assertSameLookup(t, expected, actual, expectedErr, actualErr, fmt.Sprintf("Final GetByEmail(%s)", email))
+		}
+	})
+}
+
+// TestProperty_UserCache_UpdateInvalidatesOldEmail
+// Invariante: Tras cambiar el email, la clave antigua no sirve datos desde la caché
+// Relación: Update(email→nuevo) ⟹ GetUserByEmail(antiguo) == ErrNotFound ∧ GetUserByEmail(nuevo) == usuario
+// Bordes: Usuario cacheado por ID y por email antes del cambio
+func TestProperty_UserCache_UpdateInvalidatesOldEmail(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		cache := repository.NewCachingUserRepository(repository.NewInMemoryUserRepository(), repository.CacheConfig{
+			Capacity: 10,
+			TTL:      time.Hour,
+		})
+		svc := service.NewUserService(cache)
+
+		userData := generators.ValidUserStruct().Draw(t, "user_data")
+		created, err := svc.CreateUser(userData.Name, userData.Email, userData.Age)
+		helpers.AssertNoError(t, err, "Create user")
+
+		_, err = svc.GetUser(created.ID)
+		helpers.AssertNoError(t, err, "Warm cache by ID")
+		_, err = svc.GetUserByEmail(created.Email)
+		helpers.AssertNoError(t, err, "Warm cache by email")
+
+		updateData := generators.ValidUserStruct().Draw(t, "update_data")
+		updated, err := svc.UpdateUser(created.ID, updateData.Name, updateData.Email, updateData.Age)
+		helpers.AssertNoError(t, err, "Update user")
+
+		_, err = svc.GetUserByEmail(created.Email)
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Old email after update")
+
+		byEmail, err := svc.GetUserByEmail(updated.Email)
+		helpers.AssertNoError(t, err, "New email after update")
+		helpers.AssertUserEquals(t, updated, byEmail, "User by new email")
+
+		byID, err := svc.GetUser(created.ID)
+		helpers.AssertNoError(t, err, "GetUser after update")
+		helpers.AssertUserEquals(t, updated, byID, "User by ID")
+	})
+}
+
+// TestProperty_UserCache_StatsCountEveryLookup
+// Invariante: Cada lectura por ID o email cuenta exactamente un acierto o un fallo
+// Relación: hits + misses == lecturas; lecturas repetidas sin escrituras ⟹ aciertos
+// Bordes: Primera lectura (fallo), lecturas repetidas (aciertos)
+func TestProperty_UserCache_StatsCountEveryLookup(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		cache := repository.NewCachingUserRepository(repository.NewInMemoryUserRepository(), repository.CacheConfig{
+			Capacity: 10,
+		})
+		svc := service.NewUserService(cache)
+
+		userData := generators.ValidUserStruct().Draw(t, "user_data")
+		created, err := svc.CreateUser(userData.Name, userData.Email, userData.Age)
+		helpers.AssertNoError(t, err, "Create user")
+
+		reads := rapid.IntRange(1, 20).Draw(t, "reads")
+		for i := 0; i < reads; i++ {
+			_, err := svc.GetUser(created.ID)
+			helpers.AssertNoError(t, err, "GetUser")
+		}
+
+		stats := cache.Stats()
+		if stats.Hits+stats.Misses != uint64(reads) {
+			t.Fatalf("Expected %d lookups, got %d hits + %d misses", reads, stats.Hits, stats.Misses)
+		}
+		if stats.Misses != 1 {
+			t.Fatalf("Only the first read should miss, got %d misses", stats.Misses)
+		}
+	})
+}
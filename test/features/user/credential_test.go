@@ -0,0 +1,342 @@
+package user_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// hashIterations es bajo a propósito para que la suite sea rápida
+const hashIterations = 1000
+
+// TestProperty_UserCredential_VerifiesOnlyTheSetPassword
+// Invariante: Solo la última contraseña establecida es válida
+// Relación: SetPassword(p) ⟹ Verify(p) == nil ∧ Verify(q ≠ p) == ErrInvalidCredentials ∧ Login(email, p) == usuario
+// Bordes: Cambio de contraseña (la anterior deja de valer), usuario inexistente, email desconocido
+func TestProperty_UserCredential_VerifiesOnlyTheSetPassword(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		store := repository.NewInMemoryCredentialRepository()
+		users := service.NewUserService(repo, service.WithCredentials(store))
+		creds := service.NewCredentialService(repo, store, service.CredentialConfig{Iterations: hashIterations})
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := users.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+
+		first := generators.ValidPassword().Draw(t, "first")
+		helpers.AssertNoError(t, creds.SetPassword(user.ID, first), "Set first password")
+		helpers.AssertNoError(t, creds.VerifyPassword(user.ID, first), "Verify first password")
+
+		second := generators.ValidPassword().Filter(func(p string) bool { return p != first }).Draw(t, "second")
+		helpers.AssertNoError(t, creds.SetPassword(user.ID, second), "Change password")
+
+		helpers.AssertErrorIs(t, creds.VerifyPassword(user.ID, first), domain.ErrInvalidCredentials, "Old password")
+		helpers.AssertNoError(t, creds.VerifyPassword(user.ID, second), "New password")
+
+		loggedIn, err := creds.Login(user.Email, second)
+		helpers.AssertNoError(t, err, "Login")
+		helpers.AssertUserEquals(t, user, loggedIn, "Login result")
+
+		_, err = creds.Login(generators.ValidEmail().Draw(t, "unknown"), second)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidCredentials, "Unknown email")
+
+		helpers.AssertErrorIs(t, creds.VerifyPassword("missing", second), domain.ErrInvalidCredentials, "Unknown user")
+	})
+}
+
+// TestProperty_UserCredential_WeakPasswordRejected
+// Invariante: Contraseñas fuera de política nunca se almacenan
+// Relación: SetPassword(débil) == ErrWeakPassword ∧ Verify(débil) == ErrInvalidCredentials
+// Bordes: 7 caracteres, sin dígitos, sin letras, más de 128 caracteres
+func TestProperty_UserCredential_WeakPasswordRejected(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		store := repository.NewInMemoryCredentialRepository()
+		users := service.NewUserService(repo, service.WithCredentials(store))
+		creds := service.NewCredentialService(repo, store, service.CredentialConfig{Iterations: hashIterations})
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := users.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+
+		weak := generators.InvalidPassword().Draw(t, "weak")
+		helpers.AssertErrorIs(t, creds.SetPassword(user.ID, weak), domain.ErrWeakPassword, "Weak password")
+		helpers.AssertErrorIs(t, creds.VerifyPassword(user.ID, weak), domain.ErrInvalidCredentials, "Weak password not stored")
+	})
+}
+
+// TestProperty_UserCredential_LockoutAfterRepeatedFailures
+// Invariante: Tras N fallos consecutivos la cuenta se bloquea durante el periodo configurado
+// Relación: N fallos ⟹ Verify(correcta) == ErrAccountLocked hasta now ≥ bloqueo; un acierto reinicia el contador
+// Bordes: N-1 fallos + acierto (sin bloqueo), exactamente N fallos, justo antes/después de expirar
+func TestProperty_UserCredential_LockoutAfterRepeatedFailures(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		maxAttempts := rapid.IntRange(1, 5).Draw(t, "max_attempts")
+		lockout := time.Duration(rapid.IntRange(1, 60).Draw(t, "lockout_min")) * time.Minute
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		repo := repository.NewInMemoryUserRepository()
+		store := repository.NewInMemoryCredentialRepository()
+		users := service.NewUserService(repo, service.WithCredentials(store))
+		creds := service.NewCredentialService(repo, store, service.CredentialConfig{
+			Iterations:        hashIterations,
+			MaxFailedAttempts: maxAttempts,
+			LockoutDuration:   lockout,
+			Now:               func() time.Time { return now },
+		})
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := users.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		password := generators.ValidPassword().Draw(t, "password")
+		helpers.AssertNoError(t, creds.SetPassword(user.ID, password), "Set password")
+		wrong := password + "x"
+
+		for i := 0; i < maxAttempts-1; i++ {
+			helpers.AssertErrorIs(t, creds.VerifyPassword(user.ID, wrong), domain.ErrInvalidCredentials, "Wrong password")
+		}
+		helpers.AssertNoError(t, creds.VerifyPassword(user.ID, password), "Success before limit resets counter")
+
+		for i := 0; i < maxAttempts; i++ {
+			helpers.AssertErrorIs(t, creds.VerifyPassword(user.ID, wrong), domain.ErrInvalidCredentials, "Wrong password")
+		}
+		helpers.AssertErrorIs(t, creds.VerifyPassword(user.ID, password), domain.ErrAccountLocked, "Locked with correct password")
+
+		now = now.Add(lockout - time.Second)
+		helpers.AssertErrorIs(t, creds.VerifyPassword(user.ID, password), domain.ErrAccountLocked, "Still locked")
+
+		now = now.Add(time.Second)
+		helpers.AssertNoError(t, creds.VerifyPassword(user.ID, password), "Unlocked after lockout")
+	})
+}
+
+// lockingCredentials bloquea la cuenta justo después de cada lectura, como
+// otra instancia del servicio que registra un fallo en ese momento
+type lockingCredentials struct {
+	*repository.InMemoryCredentialRepository
+	until time.Time
+}
+
+func (r lockingCredentials) Get(userID string) (*domain.Credential, error) {
+	cred, err := r.InMemoryCredentialRepository.Get(userID)
+	if err == nil {
+		_, err = r.Update(userID, func(c *domain.Credential) { c.LockedUntil = r.until })
+	}
+	return cred, err
+}
+
+// TestProperty_UserCredential_ConcurrentGuessesRespectLockout
+// Invariante: Intentos simultáneos no superan el límite de fallos ni entran en una cuenta bloqueada
+// Relación: k intentos erróneos ∥ ⟹ #ErrInvalidCredentials == min(k, N) ∧ el resto ErrAccountLocked;
+// bloqueo entre la lectura y el registro del intento ⟹ Verify(correcta) == ErrAccountLocked
+// Bordes: k < N, k == N, muchos más intentos que el límite, N == 1
+func TestProperty_UserCredential_ConcurrentGuessesRespectLockout(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		maxAttempts := rapid.IntRange(1, 5).Draw(t, "max_attempts")
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		repo := repository.NewInMemoryUserRepository()
+		store := repository.NewInMemoryCredentialRepository()
+		users := service.NewUserService(repo, service.WithCredentials(store))
+		config := service.CredentialConfig{
+			Iterations:        hashIterations,
+			MaxFailedAttempts: maxAttempts,
+			LockoutDuration:   time.Hour,
+			Now:               func() time.Time { return now },
+		}
+		creds := service.NewCredentialService(repo, store, config)
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := users.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		password := generators.ValidPassword().Draw(t, "password")
+		helpers.AssertNoError(t, creds.SetPassword(user.ID, password), "Set password")
+
+		guesses := rapid.IntRange(1, 4*maxAttempts+10).Draw(t, "guesses")
+		errs := make(chan error, guesses)
+		var wg sync.WaitGroup
+		for i := 0; i < guesses; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- creds.VerifyPassword(user.ID, password+"x")
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		invalid, locked := 0, 0
+		for err := range errs {
+			switch {
+			case errors.Is(err, domain.ErrInvalidCredentials):
+				invalid++
+			case errors.Is(err, domain.ErrAccountLocked):
+				locked++
+			default:
+				t.Fatalf("Unexpected result for a wrong guess: %v", err)
+			}
+		}
+		if want := min(guesses, maxAttempts); invalid != want || locked != guesses-want {
+			t.Fatalf("%d concurrent wrong guesses with limit %d: %d evaluated, %d locked", guesses, maxAttempts, invalid, locked)
+		}
+		if guesses >= maxAttempts {
+			helpers.AssertErrorIs(t, creds.VerifyPassword(user.ID, password), domain.ErrAccountLocked, "Correct password after lockout")
+		}
+
+		// Otra instancia bloquea la cuenta mientras se comprueba la contraseña
+		helpers.AssertNoError(t, creds.SetPassword(user.ID, password), "Reset password")
+		racing := service.NewCredentialService(repo, lockingCredentials{store, now.Add(time.Hour)}, config)
+		helpers.AssertErrorIs(t, racing.VerifyPassword(user.ID, password), domain.ErrAccountLocked, "Correct password locked in the meantime")
+	})
+}
+
+// TestProperty_UserCredential_HashesNeverLeakThroughUsers
+// Invariante: Ni la contraseña ni su hash aparecen en los usuarios devueltos por el servicio
+// Relación: ∀u ∈ GetUser ∪ GetAllUsers: hash ∉ fmt(u) ∧ hash ∉ json(u) ∧ password ∉ ambos
+// Bordes: Varios usuarios con contraseña, representación %+v y JSON
+func TestProperty_UserCredential_HashesNeverLeakThroughUsers(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		store := repository.NewInMemoryCredentialRepository()
+		users := service.NewUserService(repo, service.WithCredentials(store))
+		creds := service.NewCredentialService(repo, store, service.CredentialConfig{Iterations: hashIterations})
+
+		var secrets []string
+		var ids []string
+		userCount := rapid.IntRange(1, 4).Draw(t, "user_count")
+		for i := 0; i < userCount; i++ {
+			data := generators.ValidUserStruct().Draw(t, "user_data")
+			user, err := users.CreateUser(data.Name, data.Email, data.Age)
+			helpers.AssertNoError(t, err, "Create user")
+			password := generators.ValidPassword().Draw(t, "password")
+			helpers.AssertNoError(t, creds.SetPassword(user.ID, password), "Set password")
+
+			cred, err := store.Get(user.ID)
+			helpers.AssertNoError(t, err, "Stored credential")
+			secrets = append(secrets, password, cred.PasswordHash)
+			ids = append(ids, user.ID)
+		}
+
+		var exposed []*domain.User
+		for _, id := range ids {
+			u, err := users.GetUser(id)
+			helpers.AssertNoError(t, err, "GetUser")
+			exposed = append(exposed, u)
+		}
+		all, err := users.GetAllUsers()
+		helpers.AssertNoError(t, err, "GetAllUsers")
+		exposed = append(exposed, all...)
+
+		for _, u := range exposed {
+			encoded, err := json.Marshal(u)
+			helpers.AssertNoError(t, err, "Marshal user")
+			rendered := fmt.Sprintf("%+v", *u) + string(encoded)
+			for _, secret := range secrets {
+				if strings.Contains(rendered, secret) {
+					t.Fatalf("User %s exposes credential material", u.ID)
+				}
+			}
+		}
+	})
+}
+
+// fastestRejection es la menor de varias duraciones de fn, para filtrar el ruido del planificador
+func fastestRejection(t *rapid.T, fn func() error) time.Duration {
+	fastest := time.Duration(math.MaxInt64)
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		helpers.AssertErrorIs(t, fn(), domain.ErrInvalidCredentials, "Rejected login")
+		fastest = min(fastest, time.Since(start))
+	}
+	return fastest
+}
+
+// TestProperty_UserCredential_RejectionTimeDoesNotRevealEmails
+// Invariante: Rechazar un email desconocido cuesta lo mismo que rechazar una contraseña errónea
+// Relación: tiempo(Login(desconocido)) ≥ tiempo(Login(registrado, errónea)) / 4, y lo mismo para usuarios sin contraseña
+// Bordes: Email nunca registrado, usuario sin contraseña, ID inexistente en VerifyPassword
+func TestProperty_UserCredential_RejectionTimeDoesNotRevealEmails(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		store := repository.NewInMemoryCredentialRepository()
+		users := service.NewUserService(repo, service.WithCredentials(store))
+		creds := service.NewCredentialService(repo, store, service.CredentialConfig{Iterations: hashIterations})
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := users.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		password := generators.ValidPassword().Draw(t, "password")
+		helpers.AssertNoError(t, creds.SetPassword(user.ID, password), "Set password")
+
+		other := generators.ValidUserStruct().Draw(t, "without_password")
+		unprotected, err := users.CreateUser(other.Name, other.Email, other.Age)
+		helpers.AssertNoError(t, err, "Create user without password")
+
+		wrong := password + "x"
+		known := fastestRejection(t, func() error { _, err := creds.Login(user.Email, wrong); return err })
+		for name, reject := range map[string]func() error{
+			"unknown email":    func() error { _, err := creds.Login("nobody-"+user.Email, wrong); return err },
+			"without password": func() error { _, err := creds.Login(unprotected.Email, wrong); return err },
+			"unknown id":       func() error { return creds.VerifyPassword("missing", wrong) },
+		} {
+			if got := fastestRejection(t, reject); got < known/4 {
+				t.Fatalf("Rejecting %s took %v, a registered user with a wrong password %v", name, got, known)
+			}
+		}
+	})
+}
+
+// TestProperty_UserCredential_DeletedWithUser
+// Invariante: Borrar un usuario, directamente o al fusionarlo, borra su contraseña
+// Relación: DeleteUser(u) ∨ MergeUsers(k, u) ⟹ store.Get(u) == ErrNotFound ∧ la contraseña de k sigue valiendo
+// Bordes: Usuario sin contraseña, fusión en ambos sentidos
+func TestProperty_UserCredential_DeletedWithUser(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		store := repository.NewInMemoryCredentialRepository()
+		users := service.NewUserService(repo, service.WithCredentials(store))
+		creds := service.NewCredentialService(repo, store, service.CredentialConfig{Iterations: hashIterations})
+		dedup := service.NewDedupService(repo, repository.NewInMemoryMergeAuditRepository(), service.DedupConfig{Credentials: store})
+
+		var ids []string
+		passwords := map[string]string{}
+		for i := 0; i < 2; i++ {
+			data := generators.ValidUserStruct().Draw(t, "user_data")
+			user, err := users.CreateUser(data.Name, data.Email, data.Age)
+			helpers.AssertNoError(t, err, "Create user")
+			ids = append(ids, user.ID)
+			if rapid.Bool().Draw(t, "with_password") {
+				passwords[user.ID] = generators.ValidPassword().Draw(t, "password")
+				helpers.AssertNoError(t, creds.SetPassword(user.ID, passwords[user.ID]), "Set password")
+			}
+		}
+		if rapid.Bool().Draw(t, "reverse") {
+			ids[0], ids[1] = ids[1], ids[0]
+		}
+		kept, removed := ids[0], ids[1]
+
+		if rapid.Bool().Draw(t, "merge") {
+			_, err := dedup.MergeUsers(kept, removed)
+			helpers.AssertNoError(t, err, "MergeUsers")
+		} else {
+			helpers.AssertNoError(t, users.DeleteUser(removed), "DeleteUser")
+		}
+
+		_, err := store.Get(removed)
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Credential of deleted user")
+		if password, ok := passwords[kept]; ok {
+			helpers.AssertNoError(t, creds.VerifyPassword(kept, password), "Remaining user keeps its password")
+		}
+	})
+}
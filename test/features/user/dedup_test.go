@@ -0,0 +1,328 @@
+package user_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// TestProperty_UserDedup_EmailNormalizationFindsSameMailbox
+// Invariante: Los alias de un buzón se normalizan igual y la normalización es idempotente
+// Relación: alias(e) ⟹ Normalize(alias) == Normalize(e) ∧ Normalize(Normalize(x)) == Normalize(x)
+// Bordes: Sufijo +etiqueta, mayúsculas, puntos en gmail, googlemail.com, puntos fuera de gmail (sí cuentan)
+func TestProperty_UserDedup_EmailNormalizationFindsSameMailbox(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		email := rapid.OneOf(generators.ValidEmail(), generators.GmailAddress()).Draw(t, "email")
+		alias := generators.MailboxAlias(email).Draw(t, "alias")
+
+		normalized := domain.NormalizeEmail(email)
+		if got := domain.NormalizeEmail(alias); got != normalized {
+			t.Fatalf("Alias %s normalizes to %s, expected %s", alias, got, normalized)
+		}
+		if again := domain.NormalizeEmail(normalized); again != normalized {
+			t.Fatalf("Normalization is not idempotent: %s → %s", normalized, again)
+		}
+
+		collapses := domain.NormalizeEmail("a.b"+normalized) == domain.NormalizeEmail("ab"+normalized)
+		if collapses != hasHost(normalized, "gmail.com") {
+			t.Fatalf("Dots must collapse only for providers that ignore them (%s: %v)", normalized, collapses)
+		}
+	})
+}
+
+func hasHost(email, host string) bool {
+	return len(email) > len(host) && email[len(email)-len(host)-1:] == "@"+host
+}
+
+// TestProperty_UserDedup_NameSimilarityIsABoundedMetric
+// Invariante: La similitud de nombres es simétrica, está en [0, 1] y una errata cuesta un carácter
+// Relación: sim(a, b) == sim(b, a) ∧ sim(a, a) == 1 ∧ errata(a) ⟹ sim == 1 − 1/max(|a|, |errata|)
+// Bordes: Nombres de 2 caracteres, mayúsculas y espacios extra (no cuentan), inserción frente a sustitución
+func TestProperty_UserDedup_NameSimilarityIsABoundedMetric(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		a := generators.ValidName().Draw(t, "a")
+		b := generators.ValidName().Draw(t, "b")
+
+		s := domain.NameSimilarity(a, b)
+		if s < 0 || s > 1 || s != domain.NameSimilarity(b, a) {
+			t.Fatalf("sim(%q, %q) = %v, reverse %v", a, b, s, domain.NameSimilarity(b, a))
+		}
+		if domain.NameSimilarity(a, "  "+strings.ToUpper(a)+" ") != 1 {
+			t.Fatalf("Case and surrounding spaces must not count for %q", a)
+		}
+
+		typo := generators.NameTypo(a).Draw(t, "typo")
+		expected := 1 - 1/float64(max(len(a), len(typo)))
+		if got := domain.NameSimilarity(a, typo); math.Abs(got-expected) > 1e-9 {
+			t.Fatalf("sim(%q, %q) = %v, expected %v", a, typo, got, expected)
+		}
+	})
+}
+
+// TestProperty_UserDedup_ClustersAreSoundAndComplete
+// Invariante: El informe agrupa exactamente a los usuarios enlazados por alguna coincidencia
+// Relación: ∀ match ∈ informe: criterio(a, b) ∧ ∀ par con criterio(a, b): mismo cluster; clusters disjuntos y de tamaño ≥ 2
+// Bordes: Duplicados plantados por alias de email y por errata en el nombre, cadenas a~b~c, usuarios sin duplicados
+func TestProperty_UserDedup_ClustersAreSoundAndComplete(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		users := service.NewUserService(repo)
+		threshold := rapid.Float64Range(0.6, 0.95).Draw(t, "threshold")
+		dedup := service.NewDedupService(repo, repository.NewInMemoryMergeAuditRepository(), service.DedupConfig{NameThreshold: threshold})
+
+		var planted [][2]string
+		count := rapid.IntRange(1, 8).Draw(t, "people")
+		for i := 0; i < count; i++ {
+			data := generators.ValidUserStruct().Draw(t, "person")
+			if rapid.Bool().Draw(t, "gmail") {
+				data.Email = generators.GmailAddress().Draw(t, "gmail_address")
+			}
+			original, err := users.CreateUser(data.Name, data.Email, data.Age)
+			if err != nil {
+				continue
+			}
+			switch rapid.IntRange(0, 2).Draw(t, "duplicate") {
+			case 1:
+				alias := generators.MailboxAlias(original.Email).Draw(t, "alias")
+				if dup, err := users.CreateUser(generators.ValidName().Draw(t, "other_name"), alias, data.Age); err == nil {
+					planted = append(planted, [2]string{original.ID, dup.ID})
+				}
+			case 2:
+				typo := generators.NameTypo(original.Name).Draw(t, "typo")
+				dup, err := users.CreateUser(typo, generators.ValidEmail().Draw(t, "other_email"), data.Age)
+				if err == nil && domain.NameSimilarity(original.Name, typo) >= threshold {
+					planted = append(planted, [2]string{original.ID, dup.ID})
+				}
+			}
+		}
+
+		clusters, err := dedup.FindDuplicates()
+		helpers.AssertNoError(t, err, "FindDuplicates")
+
+		all, err := repo.GetAll()
+		helpers.AssertNoError(t, err, "GetAll")
+		byID := map[string]*domain.User{}
+		for _, u := range all {
+			byID[u.ID] = u
+		}
+		related := func(a, b *domain.User) bool {
+			return domain.NormalizeEmail(a.Email) == domain.NormalizeEmail(b.Email) ||
+				domain.NameSimilarity(a.Name, b.Name) >= threshold
+		}
+
+		clusterOf := map[string]int{}
+		for i, c := range clusters {
+			if len(c.UserIDs) < 2 || len(c.Matches) < len(c.UserIDs)-1 {
+				t.Fatalf("Cluster %d is too small or not connected: %+v", i, c)
+			}
+			for _, id := range c.UserIDs {
+				if _, dup := clusterOf[id]; dup {
+					t.Fatalf("User %s appears in two clusters", id)
+				}
+				clusterOf[id] = i
+			}
+			for _, m := range c.Matches {
+				if !related(byID[m.A], byID[m.B]) {
+					t.Fatalf("Match %+v does not meet either criterion", m)
+				}
+				if clusterOf[m.A] != i || clusterOf[m.B] != i {
+					t.Fatalf("Match %+v is filed under the wrong cluster", m)
+				}
+			}
+		}
+
+		for i, a := range all {
+			for _, b := range all[i+1:] {
+				if !related(a, b) {
+					continue
+				}
+				ca, okA := clusterOf[a.ID]
+				cb, okB := clusterOf[b.ID]
+				if !okA || !okB || ca != cb {
+					t.Fatalf("Related users %q <%s> and %q <%s> are not clustered together", a.Name, a.Email, b.Name, b.Email)
+				}
+			}
+		}
+		for _, pair := range planted {
+			ca, okA := clusterOf[pair[0]]
+			cb, okB := clusterOf[pair[1]]
+			if !okA || !okB || ca != cb {
+				t.Fatalf("Planted duplicates %s and %s were not reported together", pair[0], pair[1])
+			}
+		}
+	})
+}
+
+// TestProperty_UserDedup_MergeFreesEmailAndKeepsAudit
+// Invariante: Fusionar deja un solo usuario, libera el email perdedor y registra ambos estados previos
+// Relación: Merge(k, m) ⟹ Get(m) == ErrNotFound ∧ Create(email_m) ok ∧ k conserva sus datos ∧ CreatedAt == min ∧ auditoría(k) == auditoría(m) ∋ registro
+// Bordes: Fusionar consigo mismo (ErrInvalidMerge), usuario inexistente (sin cambios), m más antiguo que k
+func TestProperty_UserDedup_MergeFreesEmailAndKeepsAudit(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		users := service.NewUserService(repo)
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		dedup := service.NewDedupService(repo, repository.NewInMemoryMergeAuditRepository(), service.DedupConfig{Now: func() time.Time { return now }})
+
+		first := generators.ValidUserStruct().Draw(t, "first")
+		second := generators.ValidUserStruct().Draw(t, "second")
+		older, err := users.CreateUser(first.Name, first.Email, first.Age)
+		helpers.AssertNoError(t, err, "Create older")
+		time.Sleep(time.Microsecond)
+		newer, err := users.CreateUser(second.Name, second.Email, second.Age)
+		helpers.AssertNoError(t, err, "Create newer")
+
+		_, err = dedup.MergeUsers(older.ID, older.ID)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidMerge, "Merge into itself")
+		_, err = dedup.MergeUsers(older.ID, "missing")
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Merge a missing user")
+		_, err = dedup.MergeUsers("missing", newer.ID)
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Merge into a missing user")
+		if users.CountUsers() != 2 {
+			t.Fatalf("Failed merges must not change anything, have %d users", users.CountUsers())
+		}
+
+		kept, merged := older, newer
+		if rapid.Bool().Draw(t, "keep_newer") {
+			kept, merged = newer, older
+		}
+		result, err := dedup.MergeUsers(kept.ID, merged.ID)
+		helpers.AssertNoError(t, err, "MergeUsers")
+
+		helpers.AssertUserEquals(t, kept, result, "Kept user keeps its own data")
+		if !result.CreatedAt.Equal(older.CreatedAt) {
+			t.Fatalf("Expected CreatedAt %v (earliest), got %v", older.CreatedAt, result.CreatedAt)
+		}
+		_, err = users.GetUser(merged.ID)
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Merged user is gone")
+		if users.CountUsers() != 1 {
+			t.Fatalf("Expected 1 user after merge, got %d", users.CountUsers())
+		}
+		_, err = users.CreateUser(generators.ValidName().Draw(t, "reuser"), merged.Email, 30)
+		helpers.AssertNoError(t, err, "Merged email is free again")
+
+		for _, id := range []string{kept.ID, merged.ID} {
+			history, err := dedup.MergeHistory(id)
+			helpers.AssertNoError(t, err, "MergeHistory")
+			if len(history) != 1 {
+				t.Fatalf("Expected one audit record for %s, got %d", id, len(history))
+			}
+			rec := history[0]
+			if rec.KeptID != kept.ID || rec.MergedID != merged.ID || !rec.MergedAt.Equal(now) {
+				t.Fatalf("Unexpected audit record %+v", rec)
+			}
+			helpers.AssertUserEquals(t, kept, rec.Kept, fmt.Sprintf("Audit of %s: kept before", id))
+			helpers.AssertUserEquals(t, merged, rec.Merged, fmt.Sprintf("Audit of %s: merged before", id))
+			helpers.AssertUserEquals(t, result, rec.Result, fmt.Sprintf("Audit of %s: result", id))
+		}
+	})
+}
+
+// flakyAudit es un registro de auditoría que falla al reservar o al confirmar según failOn
+type flakyAudit struct {
+	*repository.InMemoryMergeAuditRepository
+	failOn string
+}
+
+func (a flakyAudit) Reserve(record *domain.MergeRecord) (repository.MergeReservation, error) {
+	if a.failOn == "reserve" {
+		return 0, repository.ErrInjectedFault
+	}
+	return a.InMemoryMergeAuditRepository.Reserve(record)
+}
+
+func (a flakyAudit) Commit(reservation repository.MergeReservation, record *domain.MergeRecord) error {
+	if a.failOn == "commit" {
+		return repository.ErrInjectedFault
+	}
+	return a.InMemoryMergeAuditRepository.Commit(reservation, record)
+}
+
+// TestProperty_UserDedup_MergeIsAllOrNothing
+// Invariante: Una fusión que falla no deja rastro: ambos usuarios intactos y sin registro de auditoría
+// Relación: err == nil ⟹ fusión completa ∧ 1 registro; err != nil ⟹ errors.Is(err, ErrInjectedFault) ∧ estado == previo ∧ 0 registros
+// Bordes: Fallo en cada lectura, en la escritura o en el borrado (antes o después de aplicarse), al reservar o confirmar la auditoría
+func TestProperty_UserDedup_MergeIsAllOrNothing(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		inner := repository.NewInMemoryUserRepository()
+		users := service.NewUserService(inner)
+
+		first := generators.ValidUserStruct().Draw(t, "first")
+		second := generators.ValidUserStruct().Draw(t, "second")
+		older, err := users.CreateUser(first.Name, first.Email, first.Age)
+		helpers.AssertNoError(t, err, "Create older")
+		time.Sleep(time.Microsecond)
+		newer, err := users.CreateUser(second.Name, second.Email, second.Age)
+		helpers.AssertNoError(t, err, "Create newer")
+		kept, merged := older, newer
+		if rapid.Bool().Draw(t, "keep_newer") {
+			kept, merged = newer, older
+		}
+
+		// Un único fallo en el paso elegido; los pasos de deshacer no fallan
+		script := map[repository.Operation][]repository.Fault{}
+		audit := flakyAudit{InMemoryMergeAuditRepository: repository.NewInMemoryMergeAuditRepository()}
+		switch step := rapid.SampledFrom([]string{"read", "update", "delete", "reserve", "commit"}).Draw(t, "failing_step"); step {
+		case "read":
+			at := rapid.IntRange(0, 2).Draw(t, "failing_read")
+			script[repository.OpGetByID] = append(make([]repository.Fault, at), generators.Fault().Draw(t, "fault"))
+		case "update":
+			script[repository.OpUpdate] = []repository.Fault{generators.Fault().Draw(t, "fault")}
+		case "delete":
+			script[repository.OpDelete] = []repository.Fault{generators.Fault().Draw(t, "fault")}
+		default:
+			audit.failOn = step
+		}
+		repo := repository.NewFaultyUserRepository(inner, repository.NewScriptedFaults(script))
+		dedup := service.NewDedupService(repo, audit, service.DedupConfig{})
+
+		before, err := inner.GetAll()
+		helpers.AssertNoError(t, err, "Snapshot before")
+
+		result, err := dedup.MergeUsers(kept.ID, merged.ID)
+
+		history, historyErr := dedup.MergeHistory(kept.ID)
+		helpers.AssertNoError(t, historyErr, "MergeHistory")
+		if err == nil {
+			helpers.AssertUserEquals(t, kept, result, "Kept user after merge")
+			_, getErr := inner.GetByID(merged.ID)
+			helpers.AssertErrorIs(t, getErr, domain.ErrNotFound, "Merged user is gone")
+			if len(history) != 1 {
+				t.Fatalf("Expected one audit record after a merge, got %d", len(history))
+			}
+			return
+		}
+
+		if !errors.Is(err, repository.ErrInjectedFault) {
+			t.Fatalf("Expected the injected fault, got %v", err)
+		}
+		if len(history) != 0 {
+			t.Fatalf("Failed merge left %d audit records", len(history))
+		}
+		after, getErr := inner.GetAll()
+		helpers.AssertNoError(t, getErr, "Snapshot after")
+		if len(after) != len(before) {
+			t.Fatalf("Failed merge changed the number of users from %d to %d", len(before), len(after))
+		}
+		for _, want := range before {
+			got, getErr := inner.GetByID(want.ID)
+			helpers.AssertNoError(t, getErr, "User survives a failed merge")
+			helpers.AssertUserEquals(t, want, got, "User after a failed merge")
+			if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+				t.Fatalf("Failed merge changed the timestamps of %s", want.ID)
+			}
+		}
+		assertRepositoryConsistent(t, inner)
+	})
+}
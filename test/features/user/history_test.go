@@ -0,0 +1,241 @@
+package user_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/mail"
+	"property-based/internal/metrics"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// writeHistory crea un usuario y le aplica actualizaciones, parches y opcionalmente un borrado;
+// devuelve el ID y el estado esperado tras cada escritura exitosa
+func writeHistory(t *rapid.T, svc *service.UserService) (string, []*domain.User, bool) {
+	data := generators.ValidUserStruct().Draw(t, "initial")
+	user, err := svc.CreateUser(data.Name, data.Email, data.Age)
+	helpers.AssertNoError(t, err, "Create user")
+	states := []*domain.User{user}
+
+	writes := rapid.IntRange(0, 8).Draw(t, "writes")
+	for i := 0; i < writes; i++ {
+		switch rapid.IntRange(0, 2).Draw(t, "write") {
+		case 0:
+			update := generators.ValidUserStruct().Draw(t, "update")
+			user, err = svc.UpdateUser(user.ID, update.Name, update.Email, update.Age)
+			helpers.AssertNoError(t, err, "Update user")
+			states = append(states, user)
+		case 1:
+			before := user
+			user, err = svc.PatchUser(user.ID, generators.ValidUserPatch().Draw(t, "patch"))
+			helpers.AssertNoError(t, err, "Patch user")
+			if !user.UpdatedAt.Equal(before.UpdatedAt) {
+				states = append(states, user)
+			}
+		case 2:
+			invalid := generators.InvalidUserStruct().Draw(t, "invalid")
+			_, err = svc.UpdateUser(user.ID, invalid.Name, invalid.Email, invalid.Age)
+			helpers.AssertError(t, err, "Invalid update")
+		}
+	}
+
+	deleted := rapid.Bool().Draw(t, "delete")
+	if deleted {
+		helpers.AssertNoError(t, svc.DeleteUser(user.ID), "Delete user")
+	}
+	return user.ID, states, deleted
+}
+
+// TestProperty_UserHistory_RecordsEverySuccessfulWrite
+// Invariante: Cada escritura exitosa agrega una versión; las fallidas o sin cambios no
+// Relación: versions[i].User == estado tras la escritura i ∧ Version == i+1 ∧ RecordedAt no decrece
+// Bordes: Sin escrituras posteriores, parches sin cambios, actualizaciones inválidas, borrado final (tombstone),
+// repositorio detrás de los decoradores de métricas (como en cmd/main.go) y caché
+func TestProperty_UserHistory_RecordsEverySuccessfulWrite(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		var repo repository.UserRepository = repository.NewInMemoryUserRepository(repository.WithHistoryLimit(0))
+		if rapid.Bool().Draw(t, "cached") {
+			repo = repository.NewCachingUserRepository(repo, repository.CacheConfig{Capacity: 4, TTL: time.Minute})
+		}
+		if rapid.Bool().Draw(t, "instrumented") {
+			repo = repository.NewInstrumentedUserRepository(repo, metrics.NewOperations(metrics.NewRegistry(), "user_repository"))
+		}
+		svc := service.NewUserService(repo)
+
+		id, states, deleted := writeHistory(t, svc)
+
+		versions, err := svc.ListUserVersions(id)
+		helpers.AssertNoError(t, err, "ListUserVersions")
+
+		expectedCount := len(states)
+		if deleted {
+			expectedCount++
+		}
+		if len(versions) != expectedCount {
+			t.Fatalf("Expected %d versions, got %d", expectedCount, len(versions))
+		}
+
+		for i, v := range versions {
+			if v.Version != i+1 {
+				t.Fatalf("Version %d numbered %d", i+1, v.Version)
+			}
+			if i > 0 && v.RecordedAt.Before(versions[i-1].RecordedAt) {
+				t.Fatal("RecordedAt must not decrease")
+			}
+			if i < len(states) {
+				helpers.AssertUserEquals(t, states[i], v.User, "Version "+strconv.Itoa(v.Version))
+			}
+			if v.Deleted != (deleted && i == len(versions)-1) {
+				t.Fatalf("Version %d has Deleted=%v", v.Version, v.Deleted)
+			}
+		}
+	})
+}
+
+// TestProperty_UserHistory_PointInTimeReads
+// Invariante: GetUserAt(t) devuelve la última versión registrada en o antes de t
+// Relación: GetUserAt(id, versions[i].RecordedAt) == última v con RecordedAt ≤ t; tombstone ⟹ ErrNotFound
+// Bordes: Antes de la creación, exactamente en cada versión, después del borrado, ID inexistente
+func TestProperty_UserHistory_PointInTimeReads(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		svc := service.NewUserService(repository.NewInMemoryUserRepository())
+
+		id, _, deleted := writeHistory(t, svc)
+
+		versions, err := svc.ListUserVersions(id)
+		helpers.AssertNoError(t, err, "ListUserVersions")
+
+		_, err = svc.GetUserAt(id, versions[0].RecordedAt.Add(-time.Nanosecond))
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Before creation")
+
+		for _, v := range versions {
+			expected := v
+			for _, later := range versions {
+				if !later.RecordedAt.After(v.RecordedAt) {
+					expected = later
+				}
+			}
+
+			got, err := svc.GetUserAt(id, v.RecordedAt)
+			if expected.Deleted {
+				helpers.AssertErrorIs(t, err, domain.ErrNotFound, "At tombstone")
+				continue
+			}
+			helpers.AssertNoError(t, err, "GetUserAt")
+			helpers.AssertUserEquals(t, expected.User, got, "Point-in-time read")
+		}
+
+		current, err := svc.GetUserAt(id, time.Now().Add(time.Hour))
+		if deleted {
+			helpers.AssertErrorIs(t, err, domain.ErrNotFound, "After delete")
+		} else {
+			helpers.AssertNoError(t, err, "GetUserAt in the future")
+			live, err := svc.GetUser(id)
+			helpers.AssertNoError(t, err, "GetUser")
+			helpers.AssertUserEquals(t, live, current, "Future read equals current state")
+		}
+
+		_, err = svc.GetUserAt("missing", time.Now())
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Unknown user")
+	})
+}
+
+// TestProperty_UserHistory_RetentionKeepsNewestVersions
+// Invariante: Con límite L se conservan como máximo L versiones, siempre las más recientes
+// Relación: len(versions) == min(total, L) ∧ versions == últimas L numeradas (total-L+1..total)
+// Bordes: Límite 1, límite mayor que el total de escrituras
+func TestProperty_UserHistory_RetentionKeepsNewestVersions(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		limit := rapid.IntRange(1, 5).Draw(t, "limit")
+		svc := service.NewUserService(repository.NewInMemoryUserRepository(repository.WithHistoryLimit(limit)))
+
+		id, states, deleted := writeHistory(t, svc)
+		total := len(states)
+		if deleted {
+			total++
+		}
+
+		versions, err := svc.ListUserVersions(id)
+		helpers.AssertNoError(t, err, "ListUserVersions")
+
+		expectedLen := total
+		if expectedLen > limit {
+			expectedLen = limit
+		}
+		if len(versions) != expectedLen {
+			t.Fatalf("Expected %d retained versions, got %d", expectedLen, len(versions))
+		}
+		for i, v := range versions {
+			if want := total - expectedLen + i + 1; v.Version != want {
+				t.Fatalf("Retained version %d should be %d, got %d", i, want, v.Version)
+			}
+		}
+	})
+}
+
+// TestProperty_UserHistory_DiffListsExactlyChangedFields
+// Invariante: El diff entre dos versiones contiene solo los campos que difieren
+// Relación: Diff(a, a) == ∅; campo ∈ Diff(a, b) ⟺ a.campo ≠ b.campo
+// Bordes: Misma versión, versión contra tombstone, versión inexistente, verificación del email
+func TestProperty_UserHistory_DiffListsExactlyChangedFields(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		svc := service.NewUserService(repo)
+
+		id, states, deleted := writeHistory(t, svc)
+		if !deleted && rapid.Bool().Draw(t, "verify_email") {
+			mailer := mail.NewInMemoryMailer()
+			verification := service.NewVerificationService(repo, repository.NewInMemoryVerificationTokenRepository(), mailer, service.DefaultVerificationConfig)
+			helpers.AssertNoError(t, verification.RequestVerification(id), "Request verification")
+			_, err := verification.VerifyEmail(lastToken(t, mailer, states[len(states)-1].Email))
+			helpers.AssertNoError(t, err, "Verify email")
+		}
+		versions, err := svc.ListUserVersions(id)
+		helpers.AssertNoError(t, err, "ListUserVersions")
+
+		a := rapid.SampledFrom(versions).Draw(t, "from")
+		b := rapid.SampledFrom(versions).Draw(t, "to")
+
+		changes, err := svc.DiffUserVersions(id, a.Version, b.Version)
+		helpers.AssertNoError(t, err, "DiffUserVersions")
+
+		expected := map[string][2]string{}
+		if a.User.Name != b.User.Name {
+			expected["name"] = [2]string{a.User.Name, b.User.Name}
+		}
+		if a.User.Email != b.User.Email {
+			expected["email"] = [2]string{a.User.Email, b.User.Email}
+		}
+		if a.User.EmailVerified != b.User.EmailVerified {
+			expected["email_verified"] = [2]string{strconv.FormatBool(a.User.EmailVerified), strconv.FormatBool(b.User.EmailVerified)}
+		}
+		if a.User.Age != b.User.Age {
+			expected["age"] = [2]string{strconv.Itoa(a.User.Age), strconv.Itoa(b.User.Age)}
+		}
+		if a.Deleted != b.Deleted {
+			expected["deleted"] = [2]string{strconv.FormatBool(a.Deleted), strconv.FormatBool(b.Deleted)}
+		}
+
+		if len(changes) != len(expected) {
+			t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+		}
+		for _, c := range changes {
+			if want, ok := expected[c.Field]; !ok || want != [2]string{c.From, c.To} {
+				t.Fatalf("Unexpected change %+v", c)
+			}
+		}
+
+		_, err = svc.DiffUserVersions(id, a.Version, len(versions)+100)
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("Diff with unknown version should be ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,230 @@
+package user_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/auth"
+	"property-based/internal/client/httpclient"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/internal/transport/httpserver"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// noRetry es la configuración del cliente sin reintentos ni esperas
+var noRetry = httpclient.Config{Sleep: func(time.Duration) {}}
+
+// countRequests envuelve h y cuenta las peticiones por método
+func countRequests(h http.Handler, counts map[string]*atomic.Int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, ok := counts[r.Method]; ok {
+			c.Add(1)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// TestProperty_UserHTTP_EquivalentToInProcessService
+// Invariante: El cliente HTTP se comporta igual que el servicio en proceso
+// Relación: ∀ secuencia: http.op(x) ≅ local.op(x) (mismos datos, errors.Is con el mismo centinela)
+// Bordes: IDs inexistentes, emails duplicados, datos inválidos, parches vacíos
+func TestProperty_UserHTTP_EquivalentToInProcessService(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		server := httptest.NewServer(httpserver.NewHandler(service.NewUserService(repository.NewInMemoryUserRepository())))
+		defer server.Close()
+
+		assertRemoteEquivalent(t, httpclient.New(server.URL, noRetry))
+	})
+}
+
+// TestProperty_UserHTTP_ErrorsKeepSentinelIdentity
+// Invariante: Todo error de dominio llega al cliente con su código, su estado HTTP y su centinela
+// Relación: servidor devuelve e ⟹ errors.Is(err_cliente, centinela(e)) ∧ StatusCode == StatusForCode(código)
+// Bordes: Centinela envuelto con contexto, error interno (500 sin filtrar el mensaje)
+func TestProperty_UserHTTP_ErrorsKeepSentinelIdentity(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		internal := rapid.Bool().Draw(t, "internal")
+		var sentinel, served error
+		if internal {
+			served = fmt.Errorf("disk on fire: %s", rapid.StringMatching(`[a-z]{4,12}`).Draw(t, "secret"))
+		} else {
+			sentinel = rapid.SampledFrom(domainSentinels).Draw(t, "sentinel")
+			served = sentinel
+			if rapid.Bool().Draw(t, "wrapped") {
+				served = fmt.Errorf("context: %w", sentinel)
+			}
+		}
+
+		server := httptest.NewServer(httpserver.NewHandler(failingOperations{err: served}))
+		defer server.Close()
+		client := httpclient.New(server.URL, noRetry)
+
+		var err error
+		switch rapid.IntRange(0, 3).Draw(t, "op") {
+		case 0:
+			_, err = client.GetUser("any")
+		case 1:
+			_, err = client.CreateUser("Any Name", "any@example.com", 30)
+		case 2:
+			_, err = client.PatchUser("any", domain.UserPatch{})
+		case 3:
+			err = client.DeleteUser("any")
+		}
+
+		var apiErr *httpclient.APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("Expected *httpclient.APIError, got %T: %v", err, err)
+		}
+		code := domain.ErrorCode(served)
+		if apiErr.Code != code || apiErr.StatusCode != httpserver.StatusForCode(code) {
+			t.Fatalf("Expected %s (HTTP %d), got %s (HTTP %d)", code, httpserver.StatusForCode(code), apiErr.Code, apiErr.StatusCode)
+		}
+		if internal {
+			if strings.Contains(err.Error(), "disk on fire") {
+				t.Fatalf("Internal error message leaked: %v", err)
+			}
+			return
+		}
+		if !errors.Is(err, sentinel) {
+			t.Fatalf("errors.Is(%v, %v) should hold across the wire", err, sentinel)
+		}
+	})
+}
+
+// TestProperty_UserHTTP_RetriesOnlyIdempotentCalls
+// Invariante: GET y PUT se reintentan ante fallos 5xx; POST, PATCH y DELETE nunca
+// Relación: idempotente ⟹ intentos == min(k, r)+1 ∧ (éxito ⟺ k ≤ r); si no ⟹ intentos == 1 sin cambios
+// Bordes: Sin reintentos (r = 0), fallos justo en el límite (k = r), más fallos que reintentos
+func TestProperty_UserHTTP_RetriesOnlyIdempotentCalls(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		var failing atomic.Int64
+		faults := repository.FaultFunc(func(repository.Operation) repository.Fault {
+			if failing.Add(-1) >= 0 {
+				return repository.Fault{Err: repository.ErrInjectedFault}
+			}
+			return repository.Fault{}
+		})
+		repo := repository.NewInMemoryUserRepository()
+		svc := service.NewUserService(repository.NewFaultyUserRepository(repo, faults))
+
+		counts := map[string]*atomic.Int64{}
+		for _, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			counts[m] = &atomic.Int64{}
+		}
+		server := httptest.NewServer(countRequests(httpserver.NewHandler(svc), counts))
+		defer server.Close()
+
+		retries := rapid.IntRange(0, 3).Draw(t, "retries")
+		var sleeps []time.Duration
+		client := httpclient.New(server.URL, httpclient.Config{
+			MaxRetries:   retries,
+			RetryBackoff: time.Millisecond,
+			Sleep:        func(d time.Duration) { sleeps = append(sleeps, d) },
+		})
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := client.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user without faults")
+		counts[http.MethodPost].Store(0)
+
+		k := rapid.IntRange(1, 4).Draw(t, "failures")
+		failing.Store(int64(k))
+		before := snapshotUsers(t, repo)
+
+		method := rapid.SampledFrom([]string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodPatch, http.MethodDelete}).Draw(t, "method")
+		update := generators.ValidUserStruct().Draw(t, "update")
+		switch method {
+		case http.MethodGet:
+			_, err = client.GetUser(user.ID)
+		case http.MethodPut:
+			_, err = client.UpdateUser(user.ID, update.Name, update.Email, update.Age)
+		case http.MethodPost:
+			_, err = client.CreateUser(update.Name, update.Email, update.Age)
+		case http.MethodPatch:
+			_, err = client.PatchUser(user.ID, domain.UserPatch{Age: &update.Age})
+		case http.MethodDelete:
+			err = client.DeleteUser(user.ID)
+		}
+		attempts := int(counts[method].Load())
+
+		if method == http.MethodGet || method == http.MethodPut {
+			if want := min(k, retries) + 1; attempts != want {
+				t.Fatalf("%s with %d failures and %d retries: expected %d attempts, got %d", method, k, retries, want, attempts)
+			}
+			if len(sleeps) != attempts-1 {
+				t.Fatalf("Expected %d backoff waits, got %d", attempts-1, len(sleeps))
+			}
+			for i := 1; i < len(sleeps); i++ {
+				if sleeps[i] != 2*sleeps[i-1] {
+					t.Fatalf("Backoff should double: %v", sleeps)
+				}
+			}
+			if k <= retries {
+				helpers.AssertNoError(t, err, method+" after retries")
+				return
+			}
+		} else if attempts != 1 {
+			t.Fatalf("%s must not be retried, got %d attempts", method, attempts)
+		}
+
+		var apiErr *httpclient.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("%s: expected HTTP 500, got %v", method, err)
+		}
+		if !sameSnapshot(before, snapshotUsers(t, repo)) {
+			t.Fatalf("Failed %s modified the repository", method)
+		}
+	})
+}
+
+// TestProperty_UserHTTP_AuthenticatesAndAuthorizesEveryRequest
+// Invariante: Sin un bearer token válido ninguna petición llega al servicio; con él, se aplica la política del rol
+// Relación: token inválido ⟹ 401 + WWW-Authenticate; válido ⟹ permitido(rol, op, propio) ? éxito : 403
+// Bordes: Sin cabecera, token desconocido, alta con clave de idempotencia, usuario sobre registro ajeno
+func TestProperty_UserHTTP_AuthenticatesAndAuthorizesEveryRequest(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		assertRemoteAuthorization(t, func(svc service.UserOperations, a auth.Authenticator, token string) (remoteUserOperations, func()) {
+			creator := service.NewIdempotentCreator(svc, repository.NewInMemoryIdempotencyStore(), service.DefaultIdempotencyConfig)
+			server := httptest.NewServer(httpserver.NewHandler(svc,
+				httpserver.WithAuthenticator(a),
+				httpserver.WithIdempotency(creator),
+			))
+
+			resp, err := http.Get(server.URL + "/users")
+			helpers.AssertNoError(t, err, "GET /users without a token")
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") != "Bearer" {
+				t.Fatalf("Expected 401 with a Bearer challenge, got %d %q", resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+			}
+
+			cfg := noRetry
+			cfg.Token = token
+			return keyedClient{httpclient.New(server.URL, cfg), rapid.Bool().Draw(t, "idempotency_key")}, server.Close
+		})
+	})
+}
+
+// keyedClient crea usuarios con clave de idempotencia cuando withKey es true,
+// para cubrir también ese camino de alta
+type keyedClient struct {
+	*httpclient.Client
+	withKey bool
+}
+
+func (c keyedClient) CreateUser(name, email string, age int) (*domain.User, error) {
+	if c.withKey {
+		return c.CreateUserWithKey("key-"+email, name, email, age)
+	}
+	return c.Client.CreateUser(name, email, age)
+}
@@ -0,0 +1,294 @@
+package user_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/auth"
+	"property-based/internal/client/httpclient"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/internal/transport/httpserver"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// TestProperty_UserIdempotency_RepeatReturnsOriginalUser
+// Invariante: Repetir un create con la misma clave y el mismo payload no crea nada nuevo
+// Relación: Create(k, p) == u ⟹ Create(k, p') == u (p' ≡ p tras normalizar) ∧ Count == 1
+// Bordes: Email en mayúsculas o con espacios, usuario modificado entre intentos, varias repeticiones
+func TestProperty_UserIdempotency_RepeatReturnsOriginalUser(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		ttl := time.Duration(rapid.IntRange(1, 48).Draw(t, "ttl_hours")) * time.Hour
+		users := service.NewUserService(repository.NewInMemoryUserRepository())
+		creator := service.NewIdempotentCreator(users, repository.NewInMemoryIdempotencyStore(), service.IdempotencyConfig{
+			TTL: ttl,
+			Now: func() time.Time { return now },
+		})
+
+		key := generators.IdempotencyKey().Draw(t, "key")
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		original, err := creator.CreateUser(key, data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "First create")
+
+		if rapid.Bool().Draw(t, "modify_between") {
+			age := generators.ValidAge().Draw(t, "age")
+			_, err := users.PatchUser(original.ID, domain.UserPatch{Age: &age})
+			helpers.AssertNoError(t, err, "Patch between attempts")
+		}
+
+		repeats := rapid.IntRange(1, 4).Draw(t, "repeats")
+		for i := 0; i < repeats; i++ {
+			now = now.Add(time.Duration(rapid.Int64Range(0, int64(ttl/time.Duration(repeats+1))).Draw(t, "elapsed")))
+			retry := generators.EquivalentUserData(data).Draw(t, "retry")
+			replayed, err := creator.CreateUser(key, retry.Name, retry.Email, retry.Age)
+			helpers.AssertNoError(t, err, "Repeated create")
+			helpers.AssertUserEquals(t, original, replayed, "Replay returns the original result")
+		}
+
+		if count := users.CountUsers(); count != 1 {
+			t.Fatalf("Expected exactly 1 user, got %d", count)
+		}
+	})
+}
+
+// TestProperty_UserIdempotency_DifferentPayloadRejected
+// Invariante: Una clave ya usada no puede reutilizarse para otro payload mientras no expire
+// Relación: Create(k, p) ok ∧ p' ≢ p ⟹ Create(k, p') == ErrIdempotencyKeyReused sin crear usuarios
+// Bordes: Solo cambia la edad, solo cambia el nombre, payload totalmente distinto, otra clave con el mismo payload
+func TestProperty_UserIdempotency_DifferentPayloadRejected(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		users := service.NewUserService(repository.NewInMemoryUserRepository())
+		creator := service.NewIdempotentCreator(users, repository.NewInMemoryIdempotencyStore(), service.DefaultIdempotencyConfig)
+
+		key := generators.IdempotencyKey().Draw(t, "key")
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		_, err := creator.CreateUser(key, data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "First create")
+
+		other := data
+		switch rapid.IntRange(0, 2).Draw(t, "change") {
+		case 0:
+			other.Age = generators.ValidAge().Filter(func(a int) bool { return a != data.Age }).Draw(t, "other_age")
+		case 1:
+			other.Name = generators.ValidName().Filter(func(n string) bool { return n != data.Name }).Draw(t, "other_name")
+		case 2:
+			other = generators.ValidUserStruct().Draw(t, "other")
+		}
+
+		_, err = creator.CreateUser(key, other.Name, other.Email, other.Age)
+		helpers.AssertErrorIs(t, err, domain.ErrIdempotencyKeyReused, "Same key, different payload")
+
+		otherKey := generators.IdempotencyKey().Filter(func(k string) bool { return k != key }).Draw(t, "other_key")
+		_, err = creator.CreateUser(otherKey, data.Name, data.Email, data.Age)
+		helpers.AssertErrorIs(t, err, domain.ErrAlreadyExists, "Different key, same payload")
+
+		if count := users.CountUsers(); count != 1 {
+			t.Fatalf("Expected exactly 1 user, got %d", count)
+		}
+	})
+}
+
+// TestProperty_UserIdempotency_KeysExpire
+// Invariante: Pasado el TTL la clave se olvida y vuelve a estar libre
+// Relación: now ≥ emitida + TTL ⟹ Create(k, p') crea un usuario nuevo; now < emitida + TTL ⟹ ErrIdempotencyKeyReused;
+// guardar otra clave purga las vencidas ⟹ no queda ninguna que purgar
+// Bordes: Justo antes del vencimiento, exactamente en el vencimiento, la purga elimina solo lo vencido
+func TestProperty_UserIdempotency_KeysExpire(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		ttl := time.Duration(rapid.IntRange(1, 48).Draw(t, "ttl_hours")) * time.Hour
+		users := service.NewUserService(repository.NewInMemoryUserRepository())
+		store := repository.NewInMemoryIdempotencyStore()
+		creator := service.NewIdempotentCreator(users, store, service.IdempotencyConfig{
+			TTL: ttl,
+			Now: func() time.Time { return now },
+		})
+
+		start := now
+		key := generators.IdempotencyKey().Draw(t, "key")
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		first, err := creator.CreateUser(key, data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "First create")
+
+		elapsed := time.Duration(rapid.Int64Range(0, int64(2*ttl)).Draw(t, "elapsed"))
+		now = now.Add(elapsed)
+
+		distinct := func(d generators.ValidUserData) bool {
+			return !strings.EqualFold(strings.TrimSpace(d.Email), strings.TrimSpace(data.Email))
+		}
+		otherKey := generators.IdempotencyKey().Filter(func(k string) bool { return k != key }).Draw(t, "other_key")
+		bystander := generators.ValidUserStruct().Filter(distinct).Draw(t, "bystander")
+		_, err = creator.CreateUser(otherKey, bystander.Name, bystander.Email, bystander.Age)
+		helpers.AssertNoError(t, err, "Create under another key")
+		_, err = store.Get(key, start)
+		if purged := errors.Is(err, domain.ErrNotFound); purged != (elapsed >= ttl) {
+			t.Fatalf("Storing a key %v later with TTL %v purged the first one: %v", elapsed, ttl, purged)
+		}
+		if removed := store.Purge(now); removed != 0 {
+			t.Fatalf("CreateUser left %d expired keys behind", removed)
+		}
+
+		other := generators.ValidUserStruct().Filter(func(d generators.ValidUserData) bool {
+			return distinct(d) && !strings.EqualFold(strings.TrimSpace(d.Email), strings.TrimSpace(bystander.Email))
+		}).Draw(t, "other")
+		second, err := creator.CreateUser(key, other.Name, other.Email, other.Age)
+		if elapsed < ttl {
+			helpers.AssertErrorIs(t, err, domain.ErrIdempotencyKeyReused, "Key still live")
+			return
+		}
+		helpers.AssertNoError(t, err, "Key reusable after expiry")
+		if second.ID == first.ID {
+			t.Fatal("Expired key must not replay the old user")
+		}
+	})
+}
+
+// TestProperty_UserIdempotency_ConcurrentRetriesCreateOnce
+// Invariante: Reintentos concurrentes con la misma clave crean un solo usuario
+// Relación: ∀ goroutine g: Create(k, p) == u (mismo ID) ∧ Count == 1
+// Bordes: 2 a 16 reintentos simultáneos
+func TestProperty_UserIdempotency_ConcurrentRetriesCreateOnce(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		users := service.NewUserService(repository.NewInMemoryUserRepository())
+		creator := service.NewIdempotentCreator(users, repository.NewInMemoryIdempotencyStore(), service.DefaultIdempotencyConfig)
+
+		key := generators.IdempotencyKey().Draw(t, "key")
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		n := rapid.IntRange(2, 16).Draw(t, "goroutines")
+
+		ids := make([]string, n)
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				user, err := creator.CreateUser(key, data.Name, data.Email, data.Age)
+				errs[i] = err
+				if err == nil {
+					ids[i] = user.ID
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		for i := range ids {
+			helpers.AssertNoError(t, errs[i], "Concurrent create")
+			if ids[i] != ids[0] {
+				t.Fatalf("Concurrent retries returned different users: %s vs %s", ids[0], ids[i])
+			}
+		}
+		if count := users.CountUsers(); count != 1 {
+			t.Fatalf("Expected exactly 1 user, got %d", count)
+		}
+	})
+}
+
+// TestProperty_UserIdempotency_LostResponsesRetriedOverHTTP
+// Invariante: Un POST con clave cuya respuesta se pierde puede reintentarse sin duplicar
+// Relación: k respuestas perdidas ≤ r reintentos ⟹ CreateUserWithKey == usuario creado una vez
+// Bordes: Ninguna respuesta perdida, tantas pérdidas como reintentos
+func TestProperty_UserIdempotency_LostResponsesRetriedOverHTTP(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		users := service.NewUserService(repository.NewInMemoryUserRepository())
+		creator := service.NewIdempotentCreator(users, repository.NewInMemoryIdempotencyStore(), service.DefaultIdempotencyConfig)
+		handler := httpserver.NewHandler(users, httpserver.WithIdempotency(creator))
+
+		// El servidor procesa la petición pero el cliente recibe un 503
+		var lost atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if lost.Add(-1) >= 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			for k, v := range rec.Header() {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(rec.Code)
+			_, _ = w.Write(rec.Body.Bytes())
+		}))
+		defer server.Close()
+
+		retries := rapid.IntRange(0, 3).Draw(t, "retries")
+		lost.Store(int64(rapid.IntRange(0, retries).Draw(t, "lost")))
+		client := httpclient.New(server.URL, httpclient.Config{MaxRetries: retries, Sleep: func(time.Duration) {}})
+
+		key := generators.IdempotencyKey().Draw(t, "key")
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		created, err := client.CreateUserWithKey(key, data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create with lost responses")
+
+		stored, err := users.GetUserByEmail(data.Email)
+		helpers.AssertNoError(t, err, "Stored user")
+		helpers.AssertUserEquals(t, stored, created, "Client sees the stored user")
+		if count := users.CountUsers(); count != 1 {
+			t.Fatalf("Expected exactly 1 user, got %d", count)
+		}
+	})
+}
+
+// TestProperty_UserIdempotency_KeysScopedByPrincipal
+// Invariante: Cada principal tiene su propio espacio de claves
+// Relación: A.Create(k, p) ∧ B.Create(k, q) ⟹ usuarios distintos; A.Create(k, p) otra vez == el de A;
+// B.Create(k, p) == ErrIdempotencyKeyReused; rol sin permiso de crear ⟹ ErrForbidden
+// Bordes: Misma clave y distinto payload, la clave de otro principal con el payload de este, rol user
+func TestProperty_UserIdempotency_KeysScopedByPrincipal(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		users := service.NewUserService(repository.NewInMemoryUserRepository())
+		creator := service.NewIdempotentCreator(users, repository.NewInMemoryIdempotencyStore(), service.DefaultIdempotencyConfig)
+		authenticator := auth.NewTokenAuthenticator()
+		authenticator.Register("token-a", auth.Principal{UserID: "admin-a", Role: auth.RoleAdmin})
+		authenticator.Register("token-b", auth.Principal{UserID: "admin-b", Role: auth.RoleAdmin})
+		authenticator.Register("token-user", auth.Principal{UserID: "someone", Role: auth.RoleUser})
+		server := httptest.NewServer(httpserver.NewHandler(users,
+			httpserver.WithAuthenticator(authenticator),
+			httpserver.WithIdempotency(creator),
+		))
+		defer server.Close()
+		clientFor := func(token string) *httpclient.Client {
+			return httpclient.New(server.URL, httpclient.Config{Sleep: noRetry.Sleep, Token: token})
+		}
+		a, b := clientFor("token-a"), clientFor("token-b")
+
+		key := generators.IdempotencyKey().Draw(t, "key")
+		first := generators.ValidUserStruct().Draw(t, "first")
+		second := generators.ValidUserStruct().Filter(func(d generators.ValidUserData) bool {
+			return !strings.EqualFold(strings.TrimSpace(d.Email), strings.TrimSpace(first.Email))
+		}).Draw(t, "second")
+
+		fromA, err := a.CreateUserWithKey(key, first.Name, first.Email, first.Age)
+		helpers.AssertNoError(t, err, "Create as A")
+		fromB, err := b.CreateUserWithKey(key, second.Name, second.Email, second.Age)
+		helpers.AssertNoError(t, err, "Same key as B")
+		if fromA.ID == fromB.ID {
+			t.Fatal("Principals sharing a key must not share the user")
+		}
+
+		replayed, err := a.CreateUserWithKey(key, first.Name, first.Email, first.Age)
+		helpers.AssertNoError(t, err, "Repeat as A")
+		helpers.AssertUserEquals(t, fromA, replayed, "A replays its own user")
+		_, err = b.CreateUserWithKey(key, first.Name, first.Email, first.Age)
+		helpers.AssertErrorIs(t, err, domain.ErrIdempotencyKeyReused, "B reuses its key for A's payload")
+
+		third := generators.ValidUserStruct().Draw(t, "third")
+		_, err = clientFor("token-user").CreateUserWithKey(key, third.Name, third.Email, third.Age)
+		helpers.AssertErrorIs(t, err, domain.ErrForbidden, "Role without create permission")
+
+		if count := users.CountUsers(); count != 2 {
+			t.Fatalf("Expected exactly 2 users, got %d", count)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package user_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/loadgen"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+)
+
+// TestProperty_UserLoadgen_ReportAccountsForEveryOperation
+// Invariante: El informe cuenta cada operación ejecutada y el estado final coincide con ellas
+// Relación: Total == requests == Σ count ∧ p50 ≤ p90 ≤ p99 ≤ max ∧ Count() == semilla + creados − borrados
+// Bordes: Sin escrituras, solo escrituras, un único worker, más workers que peticiones
+func TestProperty_UserLoadgen_ReportAccountsForEveryOperation(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		cfg := loadgen.Config{
+			Requests:     rapid.IntRange(1, 300).Draw(t, "requests"),
+			Concurrency:  rapid.IntRange(1, 8).Draw(t, "concurrency"),
+			SeedUsers:    rapid.IntRange(0, 50).Draw(t, "seed_users"),
+			WritePercent: rapid.IntRange(0, 100).Draw(t, "write_percent"),
+			RandSeed:     rapid.Int64().Draw(t, "rand_seed"),
+		}
+		svc := service.NewUserService(repository.NewInMemoryUserRepository())
+
+		report, err := loadgen.Run(svc, cfg)
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if report.Total != cfg.Requests {
+			t.Fatalf("Expected %d operations, report has %d", cfg.Requests, report.Total)
+		}
+
+		sum := 0
+		for op, s := range report.Ops {
+			sum += s.Count
+			if s.Errors != 0 {
+				t.Fatalf("%s: %d errors against a healthy service", op, s.Errors)
+			}
+			if !(s.P50 <= s.P90 && s.P90 <= s.P99 && s.P99 <= s.Max) {
+				t.Fatalf("%s: percentiles out of order: %+v", op, s)
+			}
+		}
+		if sum != report.Total {
+			t.Fatalf("Per-operation counts add up to %d, total is %d", sum, report.Total)
+		}
+
+		expected := cfg.SeedUsers + report.Ops[loadgen.OpCreateUser].Count - report.Ops[loadgen.OpDeleteUser].Count
+		if svc.CountUsers() != expected {
+			t.Fatalf("Expected %d users after the run, got %d", expected, svc.CountUsers())
+		}
+	})
+}
+
+// TestProperty_UserLoadgen_PercentileIsNearestRank
+// Invariante: El percentil p es el menor valor con al menos p% de las muestras a su altura o por debajo
+// Relación: v = Percentile(xs, p) ⟹ v ∈ xs ∧ |{x ≤ v}| ≥ p·n/100 ∧ |{x < v}| < p·n/100
+// Bordes: Una sola muestra, valores repetidos, p = 0 y p = 100
+func TestProperty_UserLoadgen_PercentileIsNearestRank(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		raw := rapid.SliceOfN(rapid.Int64Range(0, 1000), 1, 200).Draw(t, "samples")
+		p := rapid.Float64Range(0, 100).Draw(t, "p")
+		sorted := make([]time.Duration, len(raw))
+		for i, v := range raw {
+			sorted[i] = time.Duration(v)
+		}
+		slices.Sort(sorted)
+
+		v := loadgen.Percentile(sorted, p)
+		if !slices.Contains(sorted, v) {
+			t.Fatalf("Percentile %v is not one of the samples", v)
+		}
+		atOrBelow, below := 0, 0
+		for _, x := range sorted {
+			if x <= v {
+				atOrBelow++
+			}
+			if x < v {
+				below++
+			}
+		}
+		need := p / 100 * float64(len(sorted))
+		if float64(atOrBelow) < need || (below > 0 && float64(below) >= need) {
+			t.Fatalf("p%.2f = %v: %d samples at or below, %d below, n=%d", p, v, atOrBelow, below, len(sorted))
+		}
+	})
+}
+
+// TestProperty_UserLoadgen_UnboundedRunRejected
+// Invariante: Una ejecución sin duración ni número de peticiones se rechaza en lugar de no terminar
+// Relación: Duration ≤ 0 ∧ Requests ≤ 0 ⟹ Run == ErrUnbounded sin tocar el destino
+// Bordes: Ambos a cero, valores negativos, con usuarios semilla
+func TestProperty_UserLoadgen_UnboundedRunRejected(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		cfg := loadgen.Config{
+			Duration:    -time.Duration(rapid.IntRange(0, 10).Draw(t, "duration_s")) * time.Second,
+			Requests:    -rapid.IntRange(0, 10).Draw(t, "requests"),
+			Concurrency: rapid.IntRange(1, 8).Draw(t, "concurrency"),
+			SeedUsers:   rapid.IntRange(0, 50).Draw(t, "seed_users"),
+		}
+		svc := service.NewUserService(repository.NewInMemoryUserRepository())
+
+		if _, err := loadgen.Run(svc, cfg); !errors.Is(err, loadgen.ErrUnbounded) {
+			t.Fatalf("Run with %v and %d requests: expected ErrUnbounded, got %v", cfg.Duration, cfg.Requests, err)
+		}
+		if count := svc.CountUsers(); count != 0 {
+			t.Fatalf("Rejected run seeded %d users", count)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package user_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+)
+
+type expectedRecord struct {
+	operation string
+	userID    string
+	err       error
+}
+
+func decodeRecords(t *rapid.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Log line is not valid JSON: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestProperty_UserLogging_OneRecordPerOperation
+// Invariante: Cada operación emite exactamente un registro con nombre, id, resultado y duración
+// Relación: registros[i].operation == ops[i] ∧ outcome/level según el error
+// Bordes: Errores de validación (Warn), inexistentes (Warn), operaciones sin id (GetAll, Count)
+func TestProperty_UserLogging_OneRecordPerOperation(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		svc := service.NewLoggingUserService(
+			service.NewUserService(repository.NewInMemoryUserRepository()),
+			logger,
+			service.DefaultRedactionPolicy,
+		)
+
+		var expected []expectedRecord
+		var ids []string
+
+		opCount := rapid.IntRange(1, 20).Draw(t, "op_count")
+		for i := 0; i < opCount; i++ {
+			switch rapid.IntRange(0, 4).Draw(t, "op") {
+			case 0:
+				data := generators.ValidUserStruct().Draw(t, "valid")
+				user, err := svc.CreateUser(data.Name, data.Email, data.Age)
+				expected = append(expected, expectedRecord{service.OpCreateUser, user.ID, err})
+				ids = append(ids, user.ID)
+			case 1:
+				data := generators.InvalidUserStruct().Draw(t, "invalid")
+				_, err := svc.CreateUser(data.Name, data.Email, data.Age)
+				expected = append(expected, expectedRecord{service.OpCreateUser, "", err})
+			case 2:
+				id := "missing"
+				if len(ids) > 0 && rapid.Bool().Draw(t, "existing") {
+					id = rapid.SampledFrom(ids).Draw(t, "id")
+				}
+				_, err := svc.GetUser(id)
+				expected = append(expected, expectedRecord{service.OpGetUser, id, err})
+			case 3:
+				_, err := svc.GetAllUsers()
+				expected = append(expected, expectedRecord{service.OpGetAllUsers, "", err})
+			case 4:
+				svc.CountUsers()
+				expected = append(expected, expectedRecord{service.OpCountUsers, "", nil})
+			}
+		}
+
+		records := decodeRecords(t, &buf)
+		if len(records) != len(expected) {
+			t.Fatalf("Expected %d records, got %d", len(expected), len(records))
+		}
+
+		for i, want := range expected {
+			got := records[i]
+			if got["operation"] != want.operation {
+				t.Fatalf("Record %d: expected operation %s, got %v", i, want.operation, got["operation"])
+			}
+			if _, ok := got["duration"].(float64); !ok {
+				t.Fatalf("Record %d: missing numeric duration", i)
+			}
+			if want.userID != "" && got["user_id"] != want.userID {
+				t.Fatalf("Record %d: expected user_id %s, got %v", i, want.userID, got["user_id"])
+			}
+
+			wantOutcome, wantLevel := "ok", "INFO"
+			if want.err != nil {
+				wantOutcome, wantLevel = "error", "WARN"
+				if got["error_code"] != domain.ErrorCode(want.err) {
+					t.Fatalf("Record %d: expected error_code %s, got %v", i, domain.ErrorCode(want.err), got["error_code"])
+				}
+			}
+			if got["outcome"] != wantOutcome || got["level"] != wantLevel {
+				t.Fatalf("Record %d: expected %s/%s, got %v/%v", i, wantOutcome, wantLevel, got["outcome"], got["level"])
+			}
+		}
+	})
+}
+
+// TestProperty_UserLogging_RedactsPII
+// Invariante: Con política hide/mask, email y nombre nunca aparecen en claro en los logs
+// Relación: política == none ⟹ valores en claro; política != none ⟹ email ∉ salida ∧ name != original
+// Bordes: Creación, actualización y búsqueda por email; máscaras conservan solo la inicial
+func TestProperty_UserLogging_RedactsPII(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		policy := service.RedactionPolicy{
+			Email: rapid.SampledFrom([]service.Redaction{service.RedactHide, service.RedactMask, service.RedactNone}).Draw(t, "email_policy"),
+			Name:  rapid.SampledFrom([]service.Redaction{service.RedactHide, service.RedactMask, service.RedactNone}).Draw(t, "name_policy"),
+		}
+
+		var buf bytes.Buffer
+		svc := service.NewLoggingUserService(
+			service.NewUserService(repository.NewInMemoryUserRepository()),
+			slog.New(slog.NewJSONHandler(&buf, nil)),
+			policy,
+		)
+
+		data := generators.ValidUserStruct().Draw(t, "user")
+		created, _ := svc.CreateUser(data.Name, data.Email, data.Age)
+		_, _ = svc.GetUserByEmail(data.Email)
+		update := generators.ValidUserStruct().Draw(t, "update")
+		_, _ = svc.UpdateUser(created.ID, update.Name, update.Email, update.Age)
+
+		output := buf.String()
+		for _, email := range []string{data.Email, update.Email} {
+			if leaked := strings.Contains(output, email); leaked != (policy.Email == service.RedactNone) {
+				t.Fatalf("Email %s presence in logs = %v with policy %s", email, leaked, policy.Email)
+			}
+		}
+
+		for _, record := range decodeRecords(t, bytes.NewBufferString(output)) {
+			name, ok := record["name"].(string)
+			if !ok {
+				continue
+			}
+			original := data.Name
+			if record["operation"] == service.OpUpdateUser {
+				original = update.Name
+			}
+			switch policy.Name {
+			case service.RedactNone:
+				if name != original {
+					t.Fatalf("Expected clear name %q, got %q", original, name)
+				}
+			case service.RedactMask:
+				if name != original[:1]+"***" {
+					t.Fatalf("Expected masked name for %q, got %q", original, name)
+				}
+			default:
+				if name != "[REDACTED]" {
+					t.Fatalf("Expected hidden name, got %q", name)
+				}
+			}
+		}
+	})
+}
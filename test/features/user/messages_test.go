@@ -0,0 +1,252 @@
+package user_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/client/httpclient"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/internal/transport/httpserver"
+	"property-based/test/generators"
+)
+
+var numbers = regexp.MustCompile(`-?\d+`)
+
+// TestProperty_UserMessages_CatalogCoversEveryError
+// Invariante: Todo error de dominio tiene mensaje en cada locale sin perder su identidad
+// Relación: errors.Is(Localize(e, l), centinela(e)) ∧ Localize(e, l).Error() == Message(e, l) ≠ "" ∧ es ≠ en
+// Bordes: Centinela envuelto, RateLimitError con espera, error interno, locale no soportado ("fr", "")
+func TestProperty_UserMessages_CatalogCoversEveryError(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		code := rapid.SampledFrom(domain.ErrorCodes()).Draw(t, "code")
+		loc := rapid.SampledFrom(domain.Locales()).Draw(t, "locale")
+		sentinel := domain.ErrorForCode(code)
+
+		var err error = sentinel
+		switch {
+		case code == domain.CodeRateLimited:
+			err = &domain.RateLimitError{RetryAfter: time.Duration(rapid.IntRange(1, 120).Draw(t, "retry_after")) * time.Second}
+		case rapid.Bool().Draw(t, "wrapped"):
+			err = fmt.Errorf("context: %w", sentinel)
+		}
+
+		msg := domain.Message(err, loc)
+		if msg == "" {
+			t.Fatalf("No %s message for %s", loc, code)
+		}
+		localized := domain.Localize(err, loc)
+		if !errors.Is(localized, sentinel) || domain.ErrorCode(localized) != code {
+			t.Fatalf("Localized %s lost its identity: %v", code, localized)
+		}
+		if localized.Error() != msg {
+			t.Fatalf("Localize(%s).Error() = %q, Message = %q", code, localized.Error(), msg)
+		}
+
+		english := domain.Message(err, domain.LocaleEnglish)
+		if loc != domain.LocaleEnglish && msg == english {
+			t.Fatalf("%s message for %s is still English: %q", loc, code, msg)
+		}
+		if err == sentinel && english != sentinel.Error() {
+			t.Fatalf("English message %q differs from the sentinel %q", english, sentinel)
+		}
+		for _, unsupported := range []domain.Locale{"fr", ""} {
+			if got := domain.Message(err, unsupported); got != domain.Message(err, domain.DefaultLocale) {
+				t.Fatalf("Locale %q should fall back to %s, got %q", unsupported, domain.DefaultLocale, got)
+			}
+		}
+
+		internal := errors.New("disk on fire")
+		if got := domain.Message(internal, loc); strings.Contains(got, "disk") {
+			t.Fatalf("Internal error leaked in %s: %q", loc, got)
+		}
+	})
+}
+
+// defaultNameRule es la regla del patrón de nombre por defecto, dicha en cada idioma
+var defaultNameRule = map[domain.Locale]string{
+	domain.LocaleEnglish: "only letters and spaces, starting and ending with a letter",
+	domain.LocaleSpanish: "solo letras y espacios, empezando y terminando por una letra",
+}
+
+// TestProperty_UserMessages_ValidationRulesStateSameBoundsInEveryLocale
+// Invariante: Traducir un error de validación no cambia la regla que anuncia
+// Relación: números(Message(e, es)) == números(Message(e, en)) ∧ ningún mensaje cita el patrón
+// Bordes: Políticas con otros límites y patrones propios, patrones con dígitos ({2,}), contraseñas débiles
+func TestProperty_UserMessages_ValidationRulesStateSameBoundsInEveryLocale(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		policy := rapid.OneOf(rapid.Just(domain.DefaultValidationPolicy), generators.ValidationPolicy()).Draw(t, "policy")
+		invalid := generators.InvalidUserStructFor(policy).Draw(t, "invalid")
+
+		err := policy.Validate(&domain.User{Name: invalid.Name, Email: invalid.Email, Age: invalid.Age})
+		if rapid.Bool().Draw(t, "password") {
+			err = domain.ValidatePassword(generators.InvalidPassword().Draw(t, "weak_password"))
+		}
+		var verr *domain.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("Expected a *ValidationError, got %v", err)
+		}
+
+		english := domain.Message(err, domain.LocaleEnglish)
+		if english != err.Error() {
+			t.Fatalf("English message %q differs from Error() %q", english, err.Error())
+		}
+		for _, loc := range domain.Locales() {
+			msg := domain.Message(err, loc)
+			if loc != domain.LocaleEnglish && msg == english {
+				t.Fatalf("%s message is still English: %q", loc, msg)
+			}
+			if got, want := numbers.FindAllString(msg, -1), numbers.FindAllString(english, -1); !slices.Equal(got, want) {
+				t.Fatalf("%s message %q states %v, English %q states %v", loc, msg, got, english, want)
+			}
+			if verr.Pattern != "" && strings.Contains(msg, verr.Pattern) {
+				t.Fatalf("%s message %q shows the raw pattern %s", loc, msg, verr.Pattern)
+			}
+			if policy == domain.DefaultValidationPolicy && verr.Field == domain.FieldName && !strings.Contains(msg, defaultNameRule[loc]) {
+				t.Fatalf("%s message %q does not state the default name rule in words", loc, msg)
+			}
+		}
+	})
+}
+
+// acceptLanguageEntry es una entrada de Accept-Language con su q opcional
+type acceptLanguageEntry struct {
+	tag  string
+	q    float64
+	hasQ bool
+}
+
+// preferredLocale es el modelo de negociación: el locale soportado con mayor q > 0
+// según su subetiqueta primaria, el primero en caso de empate, o el por defecto.
+// Las entradas con q fuera de [0, 1] no cuentan.
+func preferredLocale(entries []acceptLanguageEntry) domain.Locale {
+	best, bestQ := domain.DefaultLocale, 0.0
+	for _, e := range entries {
+		q := 1.0
+		if e.hasQ {
+			q = e.q
+		}
+		if q < 0 || q > 1 {
+			continue
+		}
+		primary, _, _ := strings.Cut(e.tag, "-")
+		if loc := domain.Locale(strings.ToLower(primary)); slices.Contains(domain.Locales(), loc) && q > bestQ {
+			best, bestQ = loc, q
+		}
+	}
+	return best
+}
+
+// TestProperty_UserMessages_HTTPFollowsAcceptLanguage
+// Invariante: La API REST responde los errores en el idioma preferido del llamante
+// Relación: cuerpo.message == Message(e, preferido(Accept-Language)) ∧ Content-Language == preferido ∧ código sin cambios;
+// entrada mal formada ⟹ 400 invalid_request con el prefijo de MalformedMessage en el idioma preferido
+// Bordes: Sin cabecera, q=0, q fuera de [0, 1], "*", subetiquetas regionales (es-MX), mayúsculas, solo idiomas no soportados,
+// cuerpo JSON roto en POST y PATCH, filtro de atributo sin nombre, cursor de cambios no numérico
+func TestProperty_UserMessages_HTTPFollowsAcceptLanguage(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		server := httptest.NewServer(httpserver.NewHandler(service.NewUserService(repo), httpserver.WithChangeFeed(repo)))
+		defer server.Close()
+
+		entries := rapid.SliceOfN(rapid.Custom(func(t *rapid.T) acceptLanguageEntry {
+			return acceptLanguageEntry{
+				tag:  rapid.SampledFrom([]string{"es", "es-MX", "ES", "en", "en-GB", "fr", "de-AT", "*"}).Draw(t, "tag"),
+				q:    rapid.SampledFrom([]float64{-0.5, 0, 0.1, 0.5, 0.8, 1, 1.5}).Draw(t, "q"),
+				hasQ: rapid.Bool().Draw(t, "has_q"),
+			}
+		}), 0, 4).Draw(t, "accept_language")
+		parts := make([]string, len(entries))
+		for i, e := range entries {
+			parts[i] = e.tag
+			if e.hasQ {
+				parts[i] += fmt.Sprintf(";q=%g", e.q)
+			}
+		}
+		expected := preferredLocale(entries)
+
+		var req *http.Request
+		var want error
+		var malformed domain.MalformedInput
+		switch rapid.SampledFrom([]string{"missing", "invalid", "body", "patch", "filter", "cursor"}).Draw(t, "request") {
+		case "missing":
+			req, _ = http.NewRequest(http.MethodGet, server.URL+"/users/missing", nil)
+			want = domain.ErrNotFound
+		case "invalid":
+			invalid := generators.InvalidUserStruct().Draw(t, "invalid")
+			want = domain.DefaultValidationPolicy.Validate(&domain.User{Name: invalid.Name, Email: invalid.Email, Age: invalid.Age})
+			body, _ := json.Marshal(httpserver.CreateUserRequest{Name: invalid.Name, Email: invalid.Email, Age: invalid.Age})
+			req, _ = http.NewRequest(http.MethodPost, server.URL+"/users", bytes.NewReader(body))
+		case "body":
+			req, _ = http.NewRequest(http.MethodPost, server.URL+"/users", strings.NewReader(`{"name":`))
+			malformed = domain.MalformedBody
+		case "patch":
+			req, _ = http.NewRequest(http.MethodPatch, server.URL+"/users/missing", strings.NewReader(`[`))
+			malformed = domain.MalformedBody
+		case "filter":
+			req, _ = http.NewRequest(http.MethodGet, server.URL+"/users?attribute=%3Dgold", nil)
+			malformed = domain.MalformedFilter
+		case "cursor":
+			req, _ = http.NewRequest(http.MethodGet, server.URL+"/users/changes?after=latest", nil)
+			malformed = domain.MalformedCursor
+		}
+		if len(entries) > 0 {
+			req.Header.Set(httpserver.AcceptLanguageHeader, strings.Join(parts, ", "))
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var body httpserver.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Decoding error body: %v", err)
+		}
+
+		if got := resp.Header.Get("Content-Language"); got != string(expected) {
+			t.Fatalf("Accept-Language %q: expected Content-Language %s, got %s", req.Header.Get(httpserver.AcceptLanguageHeader), expected, got)
+		}
+		if !slices.Contains(resp.Header.Values("Vary"), httpserver.AcceptLanguageHeader) {
+			t.Fatalf("Expected Vary to list %s, got %v", httpserver.AcceptLanguageHeader, resp.Header.Values("Vary"))
+		}
+		if malformed != "" {
+			if resp.StatusCode != http.StatusBadRequest || body.Code != httpserver.CodeInvalidRequest {
+				t.Fatalf("Malformed %s: expected 400 %s, got %d %s", malformed, httpserver.CodeInvalidRequest, resp.StatusCode, body.Code)
+			}
+			if prefix := domain.MalformedMessage(malformed, "", expected); !strings.HasPrefix(body.Message, prefix) || body.Message == prefix {
+				t.Fatalf("Accept-Language %q: expected message %q followed by the parser error, got %q", req.Header.Get(httpserver.AcceptLanguageHeader), prefix, body.Message)
+			}
+			return
+		}
+		if body.Code != domain.ErrorCode(want) {
+			t.Fatalf("Expected code %s, got %s", domain.ErrorCode(want), body.Code)
+		}
+		if msg := domain.Message(want, expected); body.Message != msg {
+			t.Fatalf("Accept-Language %q: expected message %q, got %q", req.Header.Get(httpserver.AcceptLanguageHeader), msg, body.Message)
+		}
+
+		loc := rapid.SampledFrom(domain.Locales()).Draw(t, "client_locale")
+		client := httpclient.New(server.URL, httpclient.Config{Locale: loc})
+		_, err = client.GetUser("missing")
+		var apiErr *httpclient.APIError
+		if !errors.As(err, &apiErr) || !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("Expected a not found APIError, got %v", err)
+		}
+		if apiErr.Message != domain.Message(domain.ErrNotFound, loc) {
+			t.Fatalf("Client with locale %s got message %q", loc, apiErr.Message)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package user_test
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/metrics"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+)
+
+type operationTally struct {
+	calls  map[[2]string]int
+	errors map[[2]string]int
+}
+
+func newOperationTally() *operationTally {
+	return &operationTally{calls: make(map[[2]string]int), errors: make(map[[2]string]int)}
+}
+
+func (o *operationTally) record(operation string, err error) {
+	if err != nil {
+		o.calls[[2]string{operation, metrics.OutcomeError}]++
+		o.errors[[2]string{operation, domain.ErrorCode(err)}]++
+		return
+	}
+	o.calls[[2]string{operation, metrics.OutcomeOK}]++
+}
+
+// runRandomOperations ejecuta una secuencia aleatoria de operaciones y anota el resultado esperado
+func runRandomOperations(t *rapid.T, svc service.UserOperations, tally *operationTally) {
+	var ids []string
+
+	opCount := rapid.IntRange(1, 30).Draw(t, "op_count")
+	for i := 0; i < opCount; i++ {
+		switch rapid.IntRange(0, 5).Draw(t, "op") {
+		case 0:
+			data := generators.ValidUserStruct().Draw(t, "valid")
+			user, err := svc.CreateUser(data.Name, data.Email, data.Age)
+			tally.record(service.OpCreateUser, err)
+			if err == nil {
+				ids = append(ids, user.ID)
+			}
+		case 1:
+			data := generators.InvalidUserStruct().Draw(t, "invalid")
+			_, err := svc.CreateUser(data.Name, data.Email, data.Age)
+			tally.record(service.OpCreateUser, err)
+		case 2:
+			id := "missing"
+			if len(ids) > 0 && rapid.Bool().Draw(t, "existing") {
+				id = rapid.SampledFrom(ids).Draw(t, "get_id")
+			}
+			_, err := svc.GetUser(id)
+			tally.record(service.OpGetUser, err)
+		case 3:
+			id := "missing"
+			if len(ids) > 0 {
+				id = rapid.SampledFrom(ids).Draw(t, "delete_id")
+			}
+			tally.record(service.OpDeleteUser, svc.DeleteUser(id))
+		case 4:
+			_, err := svc.GetAllUsers()
+			tally.record(service.OpGetAllUsers, err)
+		case 5:
+			svc.CountUsers()
+			tally.record(service.OpCountUsers, nil)
+		}
+	}
+}
+
+// TestProperty_UserMetrics_CountersMatchOutcomes
+// Invariante: Cada llamada suma exactamente una vez en su operación y resultado
+// Relación: calls(op, ok) + calls(op, error) == observaciones de latencia(op); errors(op, code) == fallos con ese código
+// Bordes: Validación vs duplicados vs inexistentes, operaciones sin error (CountUsers)
+func TestProperty_UserMetrics_CountersMatchOutcomes(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		reg := metrics.NewRegistry()
+		ops := metrics.NewOperations(reg, "user_service")
+		svc := service.NewInstrumentedUserService(service.NewUserService(repository.NewInMemoryUserRepository()), ops)
+
+		tally := newOperationTally()
+		runRandomOperations(t, svc, tally)
+
+		for key, expected := range tally.calls {
+			if got := ops.Calls(key[0], key[1]); got != float64(expected) {
+				t.Fatalf("calls(%s, %s): expected %d, got %v", key[0], key[1], expected, got)
+			}
+		}
+		for key, expected := range tally.errors {
+			if got := ops.Errors(key[0], key[1]); got != float64(expected) {
+				t.Fatalf("errors(%s, %s): expected %d, got %v", key[0], key[1], expected, got)
+			}
+		}
+		for _, op := range []string{service.OpCreateUser, service.OpGetUser, service.OpDeleteUser, service.OpGetAllUsers, service.OpCountUsers} {
+			total := tally.calls[[2]string{op, metrics.OutcomeOK}] + tally.calls[[2]string{op, metrics.OutcomeError}]
+			if got := ops.Observations(op); got != uint64(total) {
+				t.Fatalf("latency observations for %s: expected %d, got %d", op, total, got)
+			}
+		}
+	})
+}
+
+func scrapeMetrics(t *rapid.T, handler http.Handler) map[string]float64 {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics returned status %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Unexpected content type %q", ct)
+	}
+
+	samples := make(map[string]float64)
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sep := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[sep+1:], 64)
+		if err != nil {
+			t.Fatalf("Malformed sample %q: %v", line, err)
+		}
+		samples[line[:sep]] = value
+	}
+	return samples
+}
+
+// TestProperty_UserMetrics_ExposedInPrometheusFormat
+// Invariante: /metrics expone los mismos valores que registran los decoradores
+// Relación: muestra{operation, outcome} == llamadas; _count == _bucket{le="+Inf"}
+// Bordes: Métricas de servicio y repositorio en el mismo registro
+func TestProperty_UserMetrics_ExposedInPrometheusFormat(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		reg := metrics.NewRegistry()
+		repo := repository.NewInstrumentedUserRepository(repository.NewInMemoryUserRepository(), metrics.NewOperations(reg, "user_repository"))
+		svc := service.NewInstrumentedUserService(service.NewUserService(repo), metrics.NewOperations(reg, "user_service"))
+
+		tally := newOperationTally()
+		runRandomOperations(t, svc, tally)
+
+		samples := scrapeMetrics(t, reg)
+
+		for key, expected := range tally.calls {
+			name := fmt.Sprintf(`user_service_operations_total{operation=%q,outcome=%q}`, key[0], key[1])
+			if samples[name] != float64(expected) {
+				t.Fatalf("%s: expected %d, got %v", name, expected, samples[name])
+			}
+		}
+		for key, expected := range tally.errors {
+			name := fmt.Sprintf(`user_service_errors_total{operation=%q,code=%q}`, key[0], key[1])
+			if samples[name] != float64(expected) {
+				t.Fatalf("%s: expected %d, got %v", name, expected, samples[name])
+			}
+		}
+
+		for name, value := range samples {
+			if !strings.HasSuffix(strings.SplitN(name, "{", 2)[0], "_count") {
+				continue
+			}
+			base := strings.TrimSuffix(strings.SplitN(name, "{", 2)[0], "_count")
+			labels := strings.TrimSuffix(strings.SplitN(name, "{", 2)[1], "}")
+			inf := fmt.Sprintf(`%s_bucket{%s,le="+Inf"}`, base, labels)
+			if samples[inf] != value {
+				t.Fatalf("%s: _count %v disagrees with +Inf bucket %v", base, value, samples[inf])
+			}
+		}
+
+		storeOps := samples[`user_repository_operations_total{operation="Create",outcome="ok"}`]
+		if storeOps != float64(tally.calls[[2]string{service.OpCreateUser, metrics.OutcomeOK}]) {
+			t.Fatalf("Repository creates %v should match successful service creates", storeOps)
+		}
+	})
+}
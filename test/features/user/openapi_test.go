@@ -0,0 +1,320 @@
+package user_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/internal/transport/httpserver"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// fetchOpenAPI descarga y decodifica el documento servido en /openapi.json
+func fetchOpenAPI(t helpers.TestingT, baseURL string) map[string]any {
+	t.Helper()
+
+	resp, err := http.Get(baseURL + "/openapi.json")
+	helpers.AssertNoError(t, err, "GET /openapi.json")
+	defer resp.Body.Close()
+
+	var doc map[string]any
+	helpers.AssertNoError(t, json.NewDecoder(resp.Body).Decode(&doc), "Decode OpenAPI document")
+	return doc
+}
+
+// checkSchema valida value contra el subconjunto de JSON Schema que usa el documento
+func checkSchema(doc, schema map[string]any, value any, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		resolved, ok := doc["components"].(map[string]any)["schemas"].(map[string]any)[name].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: unresolvable $ref %s", at, ref)
+		}
+		return checkSchema(doc, resolved, value, at)
+	}
+	if value == nil && schema["nullable"] == true {
+		return nil
+	}
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			if err := checkSchema(doc, sub.(map[string]any), value, at); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", at, value)
+		}
+		props, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required property %q", at, name)
+			}
+		}
+		for name, v := range obj {
+			sub, ok := props[name].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: undocumented property %q", at, name)
+				}
+				if sub, ok = schema["additionalProperties"].(map[string]any); !ok {
+					continue
+				}
+			}
+			if err := checkSchema(doc, sub, v, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", at, value)
+		}
+		for i, item := range items {
+			if err := checkSchema(doc, schema["items"].(map[string]any), item, at+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", at, value)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return fmt.Errorf("%s: invalid date-time %q", at, s)
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected integer, got %v", at, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected number, got %T", at, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", at, value)
+		}
+	case nil:
+		// El esquema vacío admite cualquier valor
+		if len(schema) > 0 {
+			return fmt.Errorf("%s: unsupported schema %v", at, schema)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported schema %v", at, schema)
+	}
+	return nil
+}
+
+// assertDocumentedResponse comprueba que la respuesta esté documentada para la
+// operación y que su cuerpo cumpla el esquema
+func assertDocumentedResponse(t *rapid.T, doc map[string]any, method, template string, resp *http.Response) {
+	t.Helper()
+
+	op, ok := doc["paths"].(map[string]any)[template].(map[string]any)[strings.ToLower(method)].(map[string]any)
+	if !ok {
+		t.Fatalf("%s %s is not documented", method, template)
+	}
+	documented, ok := op["responses"].(map[string]any)[strconv.Itoa(resp.StatusCode)].(map[string]any)
+	if !ok {
+		t.Fatalf("%s %s answered undocumented status %d", method, template, resp.StatusCode)
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	helpers.AssertNoError(t, err, "Read response body")
+
+	content, hasContent := documented["content"].(map[string]any)
+	if !hasContent {
+		if len(raw) != 0 {
+			t.Fatalf("%s %s %d: documented without body, got %q", method, template, resp.StatusCode, raw)
+		}
+		return
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("%s %s %d: expected application/json, got %q", method, template, resp.StatusCode, ct)
+	}
+	var body any
+	helpers.AssertNoError(t, json.Unmarshal(raw, &body), "Decode response body")
+	schema := content["application/json"].(map[string]any)["schema"].(map[string]any)
+	if err := checkSchema(doc, schema, body, "body"); err != nil {
+		t.Fatalf("%s %s %d: %v (body %s)", method, template, resp.StatusCode, err, raw)
+	}
+}
+
+// TestProperty_UserOpenAPI_ResponsesMatchDocument
+// Invariante: Toda respuesta real del servidor está documentada y cumple su esquema
+// Relación: ∀ petición a op: status ∈ op.responses ∧ cuerpo ⊨ op.responses[status].schema
+// Bordes: 201/204, 400 por cuerpo malformado o datos inválidos, 404, 409, listados vacíos
+func TestProperty_UserOpenAPI_ResponsesMatchDocument(t *testing.T) {
+	server := httptest.NewServer(httpserver.NewHandler(service.NewUserService(repository.NewInMemoryUserRepository())))
+	defer server.Close()
+	doc := fetchOpenAPI(t, server.URL)
+
+	rapid.Check(t, func(t *rapid.T) {
+		ids := []string{"missing"}
+		emails := []string{"a@example.com", "b@example.com"}
+
+		opCount := rapid.IntRange(1, 20).Draw(t, "op_count")
+		for i := 0; i < opCount; i++ {
+			id := rapid.SampledFrom(ids).Draw(t, "id")
+			email := rapid.SampledFrom(emails).Draw(t, "email")
+
+			var body string
+			switch rapid.IntRange(0, 2).Draw(t, "body_kind") {
+			case 0:
+				data := drawUserData(t)
+				if rapid.Bool().Draw(t, "reuse_email") {
+					data.Email = email
+				}
+				encoded, _ := json.Marshal(httpserver.CreateUserRequest{Name: data.Name, Email: data.Email, Age: data.Age})
+				body = string(encoded)
+			case 1:
+				patch := generators.ValidUserPatch().Draw(t, "patch")
+				encoded, _ := json.Marshal(httpserver.PatchUserRequest{Name: patch.Name, Email: patch.Email, Age: patch.Age})
+				body = string(encoded)
+			case 2:
+				body = rapid.SampledFrom([]string{"", "{", `{"unknown": 1}`, `{"age": "old"}`}).Draw(t, "malformed")
+			}
+
+			type call struct{ method, template, path string }
+			c := rapid.SampledFrom([]call{
+				{http.MethodPost, "/users", "/users"},
+				{http.MethodGet, "/users", "/users"},
+				{http.MethodGet, "/users/count", "/users/count"},
+				{http.MethodGet, "/users/by-email/{email}", "/users/by-email/" + url.PathEscape(email)},
+				{http.MethodGet, "/users/{id}", "/users/" + id},
+				{http.MethodPut, "/users/{id}", "/users/" + id},
+				{http.MethodPatch, "/users/{id}", "/users/" + id},
+				{http.MethodDelete, "/users/{id}", "/users/" + id},
+			}).Draw(t, "call")
+
+			req, err := http.NewRequest(c.method, server.URL+c.path, strings.NewReader(body))
+			helpers.AssertNoError(t, err, "Build request")
+			resp, err := http.DefaultClient.Do(req)
+			helpers.AssertNoError(t, err, c.method+" "+c.path)
+
+			if c.method == http.MethodPost && resp.StatusCode == http.StatusCreated {
+				var created httpserver.User
+				raw, _ := io.ReadAll(resp.Body)
+				helpers.AssertNoError(t, json.Unmarshal(raw, &created), "Decode created user")
+				ids = append(ids, created.ID)
+				resp.Body = io.NopCloser(strings.NewReader(string(raw)))
+			}
+			assertDocumentedResponse(t, doc, c.method, c.template, resp)
+			resp.Body.Close()
+		}
+
+		// Deja el servidor vacío para la siguiente ejecución
+		for _, id := range ids[1:] {
+			req, _ := http.NewRequest(http.MethodDelete, server.URL+"/users/"+id, nil)
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	})
+}
+
+// TestProperty_UserOpenAPI_SchemaCoversDomainUser
+// Invariante: El esquema User documenta todos los campos de domain.User y el servidor los envía
+// Relación: ∀ campo f de domain.User: ∃ propiedad p en User y en la respuesta con p sin "_" == minúsculas(f)
+// Bordes: Campos booleanos y de fecha, usuario recién creado
+func TestProperty_UserOpenAPI_SchemaCoversDomainUser(t *testing.T) {
+	server := httptest.NewServer(httpserver.NewHandler(service.NewUserService(repository.NewInMemoryUserRepository())))
+	defer server.Close()
+	doc := fetchOpenAPI(t, server.URL)
+
+	if doc["openapi"] != httpserver.OpenAPIVersion {
+		t.Fatalf("Expected OpenAPI %s, got %v", httpserver.OpenAPIVersion, doc["openapi"])
+	}
+	props := doc["components"].(map[string]any)["schemas"].(map[string]any)["User"].(map[string]any)["properties"].(map[string]any)
+
+	rapid.Check(t, func(t *rapid.T) {
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		payload, _ := json.Marshal(httpserver.CreateUserRequest{Name: data.Name, Email: data.Email, Age: data.Age})
+		resp, err := http.Post(server.URL+"/users", "application/json", strings.NewReader(string(payload)))
+		helpers.AssertNoError(t, err, "POST /users")
+		defer resp.Body.Close()
+
+		var sent map[string]any
+		helpers.AssertNoError(t, json.NewDecoder(resp.Body).Decode(&sent), "Decode created user")
+		defer func() {
+			req, _ := http.NewRequest(http.MethodDelete, server.URL+"/users/"+sent["id"].(string), nil)
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}()
+
+		normalized := func(keys map[string]any) map[string]bool {
+			out := map[string]bool{}
+			for name := range keys {
+				out[strings.ReplaceAll(name, "_", "")] = true
+			}
+			return out
+		}
+		documented, present := normalized(props), normalized(sent)
+
+		userType := reflect.TypeOf(domain.User{})
+		for i := 0; i < userType.NumField(); i++ {
+			field := strings.ToLower(userType.Field(i).Name)
+			if !documented[field] {
+				t.Fatalf("domain.User.%s is missing from the User schema", userType.Field(i).Name)
+			}
+			if !present[field] {
+				t.Fatalf("domain.User.%s is missing from the response", userType.Field(i).Name)
+			}
+		}
+	})
+}
+
+// TestProperty_UserOpenAPI_PatchNullsMatchDocument
+// Invariante: Los null que acepta PATCH /users/{id} están documentados como nullable
+// Relación: ∀ parche p: json(p) con campos y atributos a null cumple el esquema PatchUserRequest
+// Bordes: Atributo borrado (null), campos básicos a null, parche vacío
+func TestProperty_UserOpenAPI_PatchNullsMatchDocument(t *testing.T) {
+	server := httptest.NewServer(httpserver.NewHandler(service.NewUserService(repository.NewInMemoryUserRepository())))
+	defer server.Close()
+	doc := fetchOpenAPI(t, server.URL)
+	schema := map[string]any{"$ref": "#/components/schemas/PatchUserRequest"}
+
+	rapid.Check(t, func(t *rapid.T) {
+		patch := generators.ValidAttributePatchFor(generators.AttributeSchema().Draw(t, "schema")).Draw(t, "patch")
+		encoded, err := json.Marshal(httpserver.PatchUserRequestFromDomain(patch))
+		helpers.AssertNoError(t, err, "Marshal patch")
+
+		var body map[string]any
+		helpers.AssertNoError(t, json.Unmarshal(encoded, &body), "Decode patch")
+		for _, field := range []string{"name", "email", "age"} {
+			if rapid.Bool().Draw(t, "null_"+field) {
+				body[field] = nil
+			}
+		}
+
+		if err := checkSchema(doc, schema, body, "PatchUserRequest"); err != nil {
+			t.Fatalf("Patch %s does not match the document: %v", encoded, err)
+		}
+	})
+}
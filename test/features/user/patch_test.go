@@ -0,0 +1,242 @@
+package user_test
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/metrics"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// TestProperty_UserPatch_OnlyProvidedFieldsChange
+// Invariante: Los campos ausentes del parche conservan su valor; ID y CreatedAt inmutables
+// Relación: PatchUser(id, p) == original con p aplicado ∧ GetUser(id) == resultado
+// Bordes: Parche vacío, un solo campo, todos los campos
+func TestProperty_UserPatch_OnlyProvidedFieldsChange(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		svc := service.NewUserService(repository.NewInMemoryUserRepository())
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		created, err := svc.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+
+		patch := generators.ValidUserPatch().Draw(t, "patch")
+		patched, err := svc.PatchUser(created.ID, patch)
+		helpers.AssertNoError(t, err, "Patch user")
+
+		expected := created.Clone()
+		patch.ApplyTo(expected)
+		helpers.AssertUserEquals(t, expected, patched, "Patched user")
+
+		if !patched.CreatedAt.Equal(created.CreatedAt) {
+			t.Fatal("CreatedAt should be immutable")
+		}
+
+		retrieved, err := svc.GetUser(created.ID)
+		helpers.AssertNoError(t, err, "GetUser after patch")
+		helpers.AssertUserEquals(t, patched, retrieved, "Patch persisted")
+	})
+}
+
+// TestProperty_UserPatch_NoChange_KeepsUpdatedAt
+// Invariante: Un parche que no cambia nada no toca UpdatedAt
+// Relación: PatchUser(id, campos == actuales) ⟹ UpdatedAt == anterior
+// Bordes: Parche vacío, mismos valores, email con mayúsculas/espacios (igual tras normalizar)
+func TestProperty_UserPatch_NoChange_KeepsUpdatedAt(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		svc := service.NewUserService(repository.NewInMemoryUserRepository())
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		created, err := svc.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+
+		var patch domain.UserPatch
+		if rapid.Bool().Draw(t, "same_name") {
+			name := " " + created.Name + " "
+			patch.Name = &name
+		}
+		if rapid.Bool().Draw(t, "same_email") {
+			email := "  " + created.Email
+			if rapid.Bool().Draw(t, "upper_email") {
+				email = strings.ToUpper(email)
+			}
+			patch.Email = &email
+		}
+		if rapid.Bool().Draw(t, "same_age") {
+			age := created.Age
+			patch.Age = &age
+		}
+
+		patched, err := svc.PatchUser(created.ID, patch)
+		helpers.AssertNoError(t, err, "No-op patch")
+		helpers.AssertUserEquals(t, created, patched, "No-op patch result")
+
+		if !patched.UpdatedAt.Equal(created.UpdatedAt) {
+			t.Fatalf("UpdatedAt changed on no-op patch: %v -> %v", created.UpdatedAt, patched.UpdatedAt)
+		}
+	})
+}
+
+// TestProperty_UserPatch_InvalidField_FailsWithoutModifying
+// Invariante: El usuario resultante se revalida completo; un campo inválido aborta todo el parche
+// Relación: PatchUser(id, p inválido) ⟹ error ∧ GetUser(id) == estado previo
+// Bordes: Campo inválido combinado con campos válidos, email duplicado
+func TestProperty_UserPatch_InvalidField_FailsWithoutModifying(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		svc := service.NewUserService(repository.NewInMemoryUserRepository())
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		created, err := svc.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		otherData := generators.ValidUserStruct().Draw(t, "other_data")
+		other, err := svc.CreateUser(otherData.Name, otherData.Email, otherData.Age)
+		helpers.AssertNoError(t, err, "Create other user")
+
+		patch := generators.ValidUserPatch().Draw(t, "valid_part")
+		var expectedErr error
+		switch rapid.IntRange(0, 3).Draw(t, "invalid_field") {
+		case 0:
+			name := generators.InvalidName().Draw(t, "invalid_name")
+			patch.Name, expectedErr = &name, domain.ErrInvalidUserName
+		case 1:
+			email := generators.InvalidEmail().Draw(t, "invalid_email")
+			patch.Email, expectedErr = &email, domain.ErrInvalidUserEmail
+			patch.Name = nil
+		case 2:
+			age := generators.InvalidAge().Draw(t, "invalid_age")
+			patch.Age, expectedErr = &age, domain.ErrInvalidUserAge
+			patch.Name, patch.Email = nil, nil
+		case 3:
+			patch.Email, expectedErr = &other.Email, domain.ErrAlreadyExists
+		}
+
+		patched, err := svc.PatchUser(created.ID, patch)
+		helpers.AssertErrorIs(t, err, expectedErr, "Invalid patch")
+		if patched != nil {
+			t.Fatalf("PatchUser should return nil on error, got %+v", patched)
+		}
+
+		retrieved, err := svc.GetUser(created.ID)
+		helpers.AssertNoError(t, err, "GetUser after failed patch")
+		helpers.AssertUserEquals(t, created, retrieved, "User unchanged")
+	})
+}
+
+// TestProperty_UserPatch_ConcurrentDisjointPatches_AllSurvive
+// Invariante: Parches concurrentes sobre campos distintos no se pisan entre sí
+// Relación: PatchUser(age) ∥ PatchUser(email) ∥ PatchUser(name) ⟹ estado final contiene los tres
+// Bordes: 2-10 rondas de parches simultáneos sobre el mismo usuario
+func TestProperty_UserPatch_ConcurrentDisjointPatches_AllSurvive(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		svc := service.NewUserService(repository.NewInMemoryUserRepository())
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		created, err := svc.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+
+		rounds := rapid.IntRange(2, 10).Draw(t, "rounds")
+		for i := 0; i < rounds; i++ {
+			name := generators.ValidName().Draw(t, "name")
+			email := generators.ValidEmail().Draw(t, "email")
+			age := generators.ValidAge().Draw(t, "age")
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 3)
+			for _, patch := range []domain.UserPatch{{Name: &name}, {Email: &email}, {Age: &age}} {
+				wg.Add(1)
+				go func(p domain.UserPatch) {
+					defer wg.Done()
+					_, err := svc.PatchUser(created.ID, p)
+					errs <- err
+				}(patch)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				helpers.AssertNoError(t, err, "Concurrent patch")
+			}
+
+			retrieved, err := svc.GetUser(created.ID)
+			helpers.AssertNoError(t, err, "GetUser after concurrent patches")
+			expected := &domain.User{ID: created.ID, Name: name, Email: email, Age: age}
+			helpers.AssertUserEquals(t, expected, retrieved, "All concurrent patches applied")
+		}
+	})
+}
+
+// interleavingRepository aplica una escritura ajena justo después de la
+// próxima lectura por ID, como un escritor concurrente en el peor momento
+type interleavingRepository struct {
+	*repository.InMemoryUserRepository
+	write func(id string)
+}
+
+func (r *interleavingRepository) GetByID(id string) (*domain.User, error) {
+	user, err := r.InMemoryUserRepository.GetByID(id)
+	if write := r.write; err == nil && write != nil {
+		r.write = nil
+		write(id)
+	}
+	return user, err
+}
+
+// TestProperty_UserPatch_DecoratedRepositoryStaysAtomic
+// Invariante: Los decoradores de repositorio no degradan PatchUser a leer y luego escribir
+// Relación: PatchUser(age) nunca lee fuera de Modify, así que un cambio de email intercalado tras esa lectura no puede perderse
+// Bordes: Repositorio con métricas (como en cmd/main.go), con caché, con fallos inyectables y apilados
+func TestProperty_UserPatch_DecoratedRepositoryStaysAtomic(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		inner := &interleavingRepository{InMemoryUserRepository: repository.NewInMemoryUserRepository()}
+		decorate := map[string]func(repository.UserRepository) repository.UserRepository{
+			"instrumented": func(r repository.UserRepository) repository.UserRepository {
+				return repository.NewInstrumentedUserRepository(r, metrics.NewOperations(metrics.NewRegistry(), "user_repository"))
+			},
+			"caching": func(r repository.UserRepository) repository.UserRepository {
+				return repository.NewCachingUserRepository(r, repository.CacheConfig{Capacity: 4, TTL: time.Minute})
+			},
+			"faulty": func(r repository.UserRepository) repository.UserRepository {
+				return repository.NewFaultyUserRepository(r, repository.FaultFunc(func(repository.Operation) repository.Fault { return repository.Fault{} }))
+			},
+		}
+		layers := rapid.SliceOfN(rapid.SampledFrom(slices.Sorted(maps.Keys(decorate))), 1, 3).Draw(t, "layers")
+		var repo repository.UserRepository = inner
+		for _, layer := range layers {
+			repo = decorate[layer](repo)
+		}
+		svc := service.NewUserService(repo)
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		created, err := svc.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		email := generators.ValidEmail().Draw(t, "concurrent_email")
+		age := generators.ValidAge().Draw(t, "patched_age")
+
+		inner.write = func(id string) {
+			_, err := inner.Modify(id, func(u *domain.User) (bool, error) {
+				u.Email = email
+				return true, nil
+			})
+			helpers.AssertNoError(t, err, "Interleaved email change")
+		}
+		_, err = svc.PatchUser(created.ID, domain.UserPatch{Age: &age})
+		helpers.AssertNoError(t, err, "Age-only patch")
+		if inner.write == nil {
+			t.Fatalf("Layers %v: PatchUser read the user outside an atomic Modify", layers)
+		}
+
+		got, err := inner.InMemoryUserRepository.GetByID(created.ID)
+		helpers.AssertNoError(t, err, "GetByID after patch")
+		expected := &domain.User{ID: created.ID, Name: data.Name, Email: strings.ToLower(strings.TrimSpace(data.Email)), Age: age}
+		helpers.AssertUserEquals(t, expected, got, "Patched user")
+	})
+}
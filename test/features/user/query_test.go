@@ -0,0 +1,154 @@
+package user_test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+func bruteForce(t *rapid.T, repo repository.UserRepository, match func(*domain.User) bool, key func(*domain.User) string) []*domain.User {
+	all, err := repo.GetAll()
+	helpers.AssertNoError(t, err, "GetAll for brute force")
+
+	var users []*domain.User
+	for _, u := range all {
+		if match(u) {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if ki, kj := key(users[i]), key(users[j]); ki != kj {
+			return ki < kj
+		}
+		return users[i].ID < users[j].ID
+	})
+	return users
+}
+
+func assertSameUsers(t *rapid.T, expected, actual []*domain.User, context string) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Fatalf("%s: expected %d users, got %d", context, len(expected), len(actual))
+	}
+	for i := range expected {
+		helpers.AssertUserEquals(t, expected[i], actual[i], context)
+	}
+}
+
+// mutateRandomly aplica creaciones, actualizaciones y borrados aleatorios a través del servicio
+func mutateRandomly(t *rapid.T, svc *service.UserService) {
+	var ids []string
+
+	opCount := rapid.IntRange(0, 30).Draw(t, "op_count")
+	for i := 0; i < opCount; i++ {
+		op := rapid.IntRange(0, 2).Draw(t, "op")
+		if op == 0 || len(ids) == 0 {
+			data := generators.ValidUserStruct().Draw(t, "create")
+			user, err := svc.CreateUser(data.Name, data.Email, data.Age)
+			helpers.AssertNoError(t, err, "Create user")
+			ids = append(ids, user.ID)
+			continue
+		}
+
+		idx := rapid.IntRange(0, len(ids)-1).Draw(t, "target")
+		if op == 1 {
+			data := generators.ValidUserStruct().Draw(t, "update")
+			_, err := svc.UpdateUser(ids[idx], data.Name, data.Email, data.Age)
+			helpers.AssertNoError(t, err, "Update user")
+			continue
+		}
+		helpers.AssertNoError(t, svc.DeleteUser(ids[idx]), "Delete user")
+		ids = append(ids[:idx], ids[idx+1:]...)
+	}
+}
+
+// TestProperty_UserQuery_AgeRange_MatchesBruteForce
+// Invariante: El índice de edad se mantiene consistente tras Create/Update/Delete
+// Relación: FindByAgeRange(min, max) == filtro de GetAll() por min ≤ age ≤ max, ordenado por (age, id)
+// Bordes: Rango vacío (min > max), rango de un solo valor, límites 1 y 150, fuera de dominio
+func TestProperty_UserQuery_AgeRange_MatchesBruteForce(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		svc := service.NewUserService(repo)
+
+		mutateRandomly(t, svc)
+
+		min := rapid.IntRange(-5, 155).Draw(t, "min")
+		max := rapid.IntRange(-5, 155).Draw(t, "max")
+
+		expected := bruteForce(t, repo,
+			func(u *domain.User) bool { return u.Age >= min && u.Age <= max },
+			func(u *domain.User) string { return fmt.Sprintf("%03d", u.Age) })
+
+		actual, err := svc.FindUsersByAgeRange(min, max)
+		helpers.AssertNoError(t, err, "FindUsersByAgeRange")
+		assertSameUsers(t, expected, actual, "Age range query")
+	})
+}
+
+// TestProperty_UserQuery_NamePrefix_MatchesBruteForce
+// Invariante: El índice de nombre es case-insensitive y consistente tras Update/Delete
+// Relación: FindByNamePrefix(p) == filtro de GetAll() por lower(name) empieza con lower(p), ordenado por (lower(name), id)
+// Bordes: Prefijo vacío (todos), nombre completo, mayúsculas/minúsculas mezcladas, sin coincidencias
+func TestProperty_UserQuery_NamePrefix_MatchesBruteForce(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		svc := service.NewUserService(repo)
+
+		mutateRandomly(t, svc)
+
+		source := rapid.OneOf(generators.ValidName(), rapid.Just("Zz")).Draw(t, "prefix_source")
+		prefix := source[:rapid.IntRange(0, len(source)).Draw(t, "prefix_len")]
+		if rapid.Bool().Draw(t, "upper") {
+			prefix = strings.ToUpper(prefix)
+		}
+
+		lowered := strings.ToLower(prefix)
+		expected := bruteForce(t, repo,
+			func(u *domain.User) bool { return strings.HasPrefix(strings.ToLower(u.Name), lowered) },
+			func(u *domain.User) string { return strings.ToLower(u.Name) })
+
+		actual, err := svc.FindUsersByNamePrefix(prefix)
+		helpers.AssertNoError(t, err, "FindUsersByNamePrefix")
+		assertSameUsers(t, expected, actual, "Name prefix query")
+	})
+}
+
+// TestProperty_UserQuery_UnindexedRepository_FallsBackToScan
+// Invariante: El servicio responde igual con o sin índices secundarios
+// Relación: svc(repo indexado).Find*(x) == svc(repo sin índices).Find*(x)
+// Bordes: Decorador que oculta los índices (caché), consultas sin resultados
+func TestProperty_UserQuery_UnindexedRepository_FallsBackToScan(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		indexed := service.NewUserService(repo)
+		unindexed := service.NewUserService(repository.NewCachingUserRepository(repo, repository.CacheConfig{Capacity: 4}))
+
+		mutateRandomly(t, indexed)
+
+		min := rapid.IntRange(0, 151).Draw(t, "min")
+		max := rapid.IntRange(min, 151).Draw(t, "max")
+		expected, err := indexed.FindUsersByAgeRange(min, max)
+		helpers.AssertNoError(t, err, "Indexed age query")
+		actual, err := unindexed.FindUsersByAgeRange(min, max)
+		helpers.AssertNoError(t, err, "Scanned age query")
+		assertSameUsers(t, expected, actual, "Age range fallback")
+
+		prefix := generators.ValidName().Draw(t, "name")[:1]
+		expected, err = indexed.FindUsersByNamePrefix(prefix)
+		helpers.AssertNoError(t, err, "Indexed name query")
+		actual, err = unindexed.FindUsersByNamePrefix(prefix)
+		helpers.AssertNoError(t, err, "Scanned name query")
+		assertSameUsers(t, expected, actual, "Name prefix fallback")
+	})
+}
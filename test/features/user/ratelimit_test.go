@@ -0,0 +1,252 @@
+package user_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/auth"
+	"property-based/internal/client/httpclient"
+	"property-based/internal/domain"
+	"property-based/internal/ratelimit"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/internal/transport/httpserver"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// drawLimit genera un presupuesto de token bucket pequeño para agotarlo en pocas llamadas
+func drawLimit(t *rapid.T, label string) ratelimit.Limit {
+	return ratelimit.Limit{
+		Rate:  float64(rapid.IntRange(1, 20).Draw(t, label+"_rate")) / 2,
+		Burst: rapid.IntRange(1, 5).Draw(t, label+"_burst"),
+	}
+}
+
+// TestProperty_UserRateLimit_TokenBucketBounds
+// Invariante: Ninguna clave obtiene más de burst + rate·Δt tokens en ningún intervalo
+// Relación: aceptadas(k) ≤ burst + rate·(t_final − t_inicial); denegada ⟹ esperar RetryAfter basta y esperar menos no
+// Bordes: Ráfaga inicial completa, llamadas sin avanzar el reloj, claves que no interfieren entre sí
+func TestProperty_UserRateLimit_TokenBucketBounds(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		limit := drawLimit(t, "limit")
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		start := now
+		limiter := ratelimit.NewLimiter(limit, func() time.Time { return now })
+
+		keys := []string{"alice", "bob"}
+		accepted := map[string]int{}
+
+		steps := rapid.IntRange(1, 60).Draw(t, "steps")
+		for i := 0; i < steps; i++ {
+			now = now.Add(time.Duration(rapid.IntRange(0, 500).Draw(t, "advance_ms")) * time.Millisecond)
+			key := rapid.SampledFrom(keys).Draw(t, "key")
+
+			ok, wait := limiter.Take(key)
+			if ok {
+				accepted[key]++
+				continue
+			}
+			if wait <= 0 {
+				t.Fatalf("Denied take must report a positive wait, got %v", wait)
+			}
+			if rapid.Bool().Draw(t, "wait_out") {
+				if wait > time.Millisecond {
+					now = now.Add(wait - time.Millisecond)
+					if ok, _ := limiter.Take(key); ok {
+						t.Fatalf("Take succeeded %v before the reported wait", time.Millisecond)
+					}
+					now = now.Add(time.Millisecond)
+				} else {
+					now = now.Add(wait)
+				}
+				if ok, _ := limiter.Take(key); !ok {
+					t.Fatalf("Take still denied after waiting %v", wait)
+				}
+				accepted[key]++
+			}
+		}
+
+		bound := float64(limit.Burst) + limit.Rate*now.Sub(start).Seconds()
+		for key, n := range accepted {
+			if float64(n) > bound+1e-6 {
+				t.Fatalf("Key %s got %d tokens, bound is %.3f", key, n, bound)
+			}
+		}
+	})
+}
+
+// TestProperty_UserRateLimit_SeparateReadAndWriteBudgets
+// Invariante: Lecturas y escrituras tienen presupuestos independientes por clave
+// Relación: tras agotar escrituras: write == ErrRateLimited (sin cambiar estado) ∧ read permitido ∧ otra clave permitida
+// Bordes: Burst 1, error con RetryAfter > 0, la escritura rechazada no llega al repositorio
+func TestProperty_UserRateLimit_SeparateReadAndWriteBudgets(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		reads, writes := drawLimit(t, "reads"), drawLimit(t, "writes")
+		limits := service.NewRateLimits(service.RateLimitConfig{Reads: reads, Writes: writes, Now: func() time.Time { return now }})
+
+		repo := repository.NewInMemoryUserRepository()
+		base := service.NewUserService(repo)
+		tenant := rapid.SampledFrom([]string{"acme", "globex"}).Draw(t, "tenant")
+		svc := service.NewRateLimitedUserService(base, limits, tenant)
+
+		var last *domain.User
+		for i := 0; i < writes.Burst; i++ {
+			data := generators.ValidUserStruct().Draw(t, "user_data")
+			user, err := svc.CreateUser(data.Name, data.Email, data.Age)
+			helpers.AssertNoError(t, err, "Write within budget")
+			last = user
+		}
+
+		before := snapshotUsers(t, repo)
+		var err error
+		switch rapid.IntRange(0, 3).Draw(t, "write") {
+		case 0:
+			data := generators.ValidUserStruct().Draw(t, "extra")
+			_, err = svc.CreateUser(data.Name, data.Email, data.Age)
+		case 1:
+			data := generators.ValidUserStruct().Draw(t, "extra")
+			_, err = svc.UpdateUser(last.ID, data.Name, data.Email, data.Age)
+		case 2:
+			_, err = svc.PatchUser(last.ID, generators.ValidUserPatch().Draw(t, "patch"))
+		case 3:
+			err = svc.DeleteUser(last.ID)
+		}
+		if !errors.Is(err, domain.ErrRateLimited) {
+			t.Fatalf("Write over budget: expected ErrRateLimited, got %v", err)
+		}
+		var limited *domain.RateLimitError
+		if !errors.As(err, &limited) || limited.RetryAfter <= 0 {
+			t.Fatalf("Expected RateLimitError with positive RetryAfter, got %v", err)
+		}
+		if !sameSnapshot(before, snapshotUsers(t, repo)) {
+			t.Fatal("Rate-limited write modified the repository")
+		}
+
+		for i := 0; i < reads.Burst; i++ {
+			_, err := svc.GetUser(last.ID)
+			helpers.AssertNoError(t, err, "Reads keep their own budget")
+		}
+		if _, err := svc.GetAllUsers(); !errors.Is(err, domain.ErrRateLimited) {
+			t.Fatalf("Read over budget: expected ErrRateLimited, got %v", err)
+		}
+
+		other := service.NewRateLimitedUserService(base, limits, tenant+"-other")
+		data := generators.ValidUserStruct().Draw(t, "other_data")
+		_, err = other.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Other key keeps its budget")
+
+		now = now.Add(limited.RetryAfter)
+		_, err = svc.PatchUser(last.ID, domain.UserPatch{})
+		helpers.AssertNoError(t, err, "Write after waiting RetryAfter")
+	})
+}
+
+// TestProperty_UserRateLimit_HTTPAnswers429
+// Invariante: Por HTTP un cliente sin presupuesto recibe 429 con Retry-After
+// Relación: burst+1 escrituras ⟹ última == 429 ∧ code == rate_limited ∧ Retry-After ≥ 1 ∧ errors.Is(ErrRateLimited)
+// Bordes: Lecturas no consumen presupuesto de escritura, clave por cabecera
+func TestProperty_UserRateLimit_HTTPAnswers429(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		writes := drawLimit(t, "writes")
+		limits := service.NewRateLimits(service.RateLimitConfig{Writes: writes, Now: func() time.Time { return now }})
+
+		handler := httpserver.NewHandler(service.NewUserService(repository.NewInMemoryUserRepository()),
+			httpserver.WithRateLimits(limits, func(r *http.Request) string { return r.Header.Get("X-Caller") }))
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		client := httpclient.New(server.URL, noRetry)
+
+		for i := 0; i < writes.Burst; i++ {
+			data := generators.ValidUserStruct().Draw(t, "user_data")
+			_, err := client.CreateUser(data.Name, data.Email, data.Age)
+			helpers.AssertNoError(t, err, "Write within budget")
+			_, err = client.GetAllUsers()
+			helpers.AssertNoError(t, err, "Unlimited reads")
+		}
+
+		data := generators.ValidUserStruct().Draw(t, "extra")
+		_, err := client.CreateUser(data.Name, data.Email, data.Age)
+		var apiErr *httpclient.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Code != domain.CodeRateLimited {
+			t.Fatalf("Expected 429 rate_limited, got %v", err)
+		}
+		if !errors.Is(err, domain.ErrRateLimited) {
+			t.Fatalf("errors.Is(%v, ErrRateLimited) should hold across the wire", err)
+		}
+		if apiErr.RetryAfter < time.Second {
+			t.Fatalf("Expected Retry-After of at least 1s, got %v", apiErr.RetryAfter)
+		}
+
+		req, _ := http.NewRequest(http.MethodDelete, server.URL+"/users/missing", nil)
+		req.Header.Set("X-Caller", "someone-else")
+		resp, err := http.DefaultClient.Do(req)
+		helpers.AssertNoError(t, err, "Other caller")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("Other caller should not be limited, got HTTP %d (Retry-After %s)", resp.StatusCode, strconv.Quote(resp.Header.Get("Retry-After")))
+		}
+	})
+}
+
+// TestProperty_UserRateLimit_AuthenticatedCallersHaveOwnBudgets
+// Invariante: Con autenticación el presupuesto es del principal, y la IP solo tiene un tope grueso de intentos
+// Relación: A agota escrituras ⟹ A == 429 ∧ B (misma IP) permitido; tokens inválidos ⟹ 401 hasta agotar
+// los intentos de la IP ⟹ 429 incluso con token válido, hasta esperar Retry-After
+// Bordes: Ráfaga 1, un solo intento fallido, principales con el mismo rol
+func TestProperty_UserRateLimit_AuthenticatedCallersHaveOwnBudgets(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		writes := drawLimit(t, "writes")
+		guesses := rapid.IntRange(1, 5).Draw(t, "guesses")
+		// A agota sus escrituras y recibe un 429, B escribe una vez, y el resto son intentos
+		attempts := ratelimit.Limit{Rate: 0.5, Burst: writes.Burst + 2 + guesses}
+		limits := service.NewRateLimits(service.RateLimitConfig{Writes: writes, Attempts: attempts, Now: func() time.Time { return now }})
+
+		authenticator := auth.NewTokenAuthenticator()
+		authenticator.Register("token-a", auth.Principal{UserID: "admin-a", Role: auth.RoleAdmin})
+		authenticator.Register("token-b", auth.Principal{UserID: "admin-b", Role: auth.RoleAdmin})
+		server := httptest.NewServer(httpserver.NewHandler(service.NewUserService(repository.NewInMemoryUserRepository()),
+			httpserver.WithAuthenticator(authenticator),
+			httpserver.WithRateLimits(limits, nil),
+		))
+		defer server.Close()
+		clientFor := func(token string) *httpclient.Client {
+			return httpclient.New(server.URL, httpclient.Config{Sleep: noRetry.Sleep, Token: token})
+		}
+		a, b := clientFor("token-a"), clientFor("token-b")
+
+		for i := 0; i < writes.Burst; i++ {
+			data := generators.ValidUserStruct().Draw(t, "user_data")
+			_, err := a.CreateUser(data.Name, data.Email, data.Age)
+			helpers.AssertNoError(t, err, "A writes within budget")
+		}
+		data := generators.ValidUserStruct().Draw(t, "extra")
+		_, err := a.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertErrorIs(t, err, domain.ErrRateLimited, "A over budget")
+		_, err = b.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "B from the same IP keeps its budget")
+
+		for i := 0; i < guesses; i++ {
+			_, err := clientFor("guess-" + strconv.Itoa(i)).GetAllUsers()
+			helpers.AssertErrorIs(t, err, domain.ErrUnauthenticated, "Wrong token within attempt budget")
+		}
+		_, err = b.GetAllUsers()
+		var apiErr *httpclient.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("Expected 429 once the IP spent its attempts, got %v", err)
+		}
+
+		now = now.Add(apiErr.RetryAfter)
+		_, err = b.GetAllUsers()
+		helpers.AssertNoError(t, err, "Valid token after Retry-After")
+	})
+}
@@ -0,0 +1,194 @@
+package user_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+type storedUser struct {
+	Name  string
+	Email string
+	Age   int
+}
+
+func snapshotUsers(t *rapid.T, repo repository.UserRepository) map[string]storedUser {
+	users, err := repo.GetAll()
+	helpers.AssertNoError(t, err, "Snapshot users")
+
+	snapshot := make(map[string]storedUser, len(users))
+	for _, u := range users {
+		snapshot[u.ID] = storedUser{Name: u.Name, Email: u.Email, Age: u.Age}
+	}
+	return snapshot
+}
+
+func sameSnapshot(a, b map[string]storedUser) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id, u := range a {
+		if other, ok := b[id]; !ok || other != u {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedIDs(snapshot map[string]storedUser) []string {
+	ids := make([]string, 0, len(snapshot))
+	for id := range snapshot {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func assertRepositoryConsistent(t *rapid.T, repo repository.UserRepository) {
+	t.Helper()
+
+	users, err := repo.GetAll()
+	helpers.AssertNoError(t, err, "GetAll for consistency check")
+
+	if count := repo.Count(); count != len(users) {
+		t.Fatalf("Count %d disagrees with GetAll length %d", count, len(users))
+	}
+	for _, u := range users {
+		byEmail, err := repo.GetByEmail(u.Email)
+		helpers.AssertNoError(t, err, "Email index lookup")
+		helpers.AssertUserEquals(t, u, byEmail, "Email index points to owner")
+		if err := u.Validate(); err != nil {
+			t.Fatalf("Stored user %s fails validation: %v", u.ID, err)
+		}
+	}
+}
+
+// TestProperty_UserFaults_InjectedErrorsPropagate
+// Invariante: Un fallo inyectado nunca se convierte en éxito ni en un usuario parcial
+// Relación: repo falla ⟹ errors.Is(err, ErrInjectedFault) ∧ resultado == nil ∧ estado intacto
+// Bordes: Primera llamada de cada operación falla, UpdateUser falla en la lectura previa
+func TestProperty_UserFaults_InjectedErrorsPropagate(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		inner := repository.NewInMemoryUserRepository()
+		seed := service.NewUserService(inner)
+
+		existingData := generators.ValidUserStruct().Draw(t, "existing")
+		existing, err := seed.CreateUser(existingData.Name, existingData.Email, existingData.Age)
+		helpers.AssertNoError(t, err, "Seed user")
+
+		script := make(map[repository.Operation][]repository.Fault)
+		for _, op := range repository.Operations {
+			script[op] = []repository.Fault{{Err: repository.ErrInjectedFault}}
+		}
+		svc := service.NewUserService(repository.NewFaultyUserRepository(inner, repository.NewScriptedFaults(script)))
+
+		before := snapshotUsers(t, inner)
+
+		newData := generators.ValidUserStruct().Draw(t, "new_user")
+		created, err := svc.CreateUser(newData.Name, newData.Email, newData.Age)
+		if !errors.Is(err, repository.ErrInjectedFault) || created != nil {
+			t.Fatalf("CreateUser should fail with injected fault, got %v, %+v", err, created)
+		}
+
+		if user, err := svc.GetUser(existing.ID); !errors.Is(err, repository.ErrInjectedFault) || user != nil {
+			t.Fatalf("GetUser should fail with injected fault, got %v", err)
+		}
+		if user, err := svc.GetUserByEmail(existing.Email); !errors.Is(err, repository.ErrInjectedFault) || user != nil {
+			t.Fatalf("GetUserByEmail should fail with injected fault, got %v", err)
+		}
+		if users, err := svc.GetAllUsers(); !errors.Is(err, repository.ErrInjectedFault) || users != nil {
+			t.Fatalf("GetAllUsers should fail with injected fault, got %v", err)
+		}
+
+		updated, err := svc.UpdateUser(existing.ID, newData.Name, newData.Email, newData.Age)
+		if !errors.Is(err, repository.ErrInjectedFault) || updated != nil {
+			t.Fatalf("UpdateUser should fail with injected fault, got %v", err)
+		}
+		if err := svc.DeleteUser(existing.ID); !errors.Is(err, repository.ErrInjectedFault) {
+			t.Fatalf("DeleteUser should fail with injected fault, got %v", err)
+		}
+
+		if !sameSnapshot(before, snapshotUsers(t, inner)) {
+			t.Fatal("Failed operations must not modify the repository")
+		}
+	})
+}
+
+// TestProperty_UserFaults_NoHalfAppliedState
+// Invariante: Tras cualquier secuencia con fallos, el estado es el previo o el completo de cada operación
+// Relación: err == nil ⟹ estado == aplicado; err != nil ⟹ estado ∈ {previo, aplicado}
+// Bordes: Fallos parciales (aplicado + error), latencia, fallos en lecturas previas a escrituras
+func TestProperty_UserFaults_NoHalfAppliedState(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		inner := repository.NewInMemoryUserRepository()
+		schedule := repository.FaultFunc(func(op repository.Operation) repository.Fault {
+			return generators.Fault().Draw(t, string(op))
+		})
+		svc := service.NewUserService(repository.NewFaultyUserRepository(inner, schedule))
+
+		opCount := rapid.IntRange(5, 20).Draw(t, "op_count")
+		for i := 0; i < opCount; i++ {
+			before := snapshotUsers(t, inner)
+			ids := sortedIDs(before)
+
+			expected := make(map[string]storedUser, len(before)+1)
+			for id, u := range before {
+				expected[id] = u
+			}
+
+			var err error
+			switch op := rapid.IntRange(0, 2).Draw(t, "op"); {
+			case op == 0 || len(ids) == 0:
+				data := generators.ValidUserStruct().Draw(t, "create")
+				user, createErr := svc.CreateUser(data.Name, data.Email, data.Age)
+				err = createErr
+				if err == nil {
+					expected[user.ID] = storedUser{user.Name, user.Email, user.Age}
+					break
+				}
+				if user != nil {
+					t.Fatalf("CreateUser returned user alongside error: %+v", user)
+				}
+				// El ID lo genera el servicio: si la escritura llegó a aplicarse, se adopta
+				for id, u := range snapshotUsers(t, inner) {
+					if _, existed := before[id]; !existed && u == (storedUser{data.Name, data.Email, data.Age}) {
+						expected[id] = u
+					}
+				}
+
+			case op == 1:
+				id := rapid.SampledFrom(ids).Draw(t, "update_id")
+				data := generators.ValidUserStruct().Draw(t, "update")
+				_, err = svc.UpdateUser(id, data.Name, data.Email, data.Age)
+				expected[id] = storedUser{data.Name, data.Email, data.Age}
+
+			default:
+				id := rapid.SampledFrom(ids).Draw(t, "delete_id")
+				err = svc.DeleteUser(id)
+				delete(expected, id)
+			}
+
+			after := snapshotUsers(t, inner)
+			if err == nil && !sameSnapshot(after, expected) {
+				t.Fatalf("Successful operation not fully applied: expected %+v, got %+v", expected, after)
+			}
+			if err != nil {
+				if !errors.Is(err, repository.ErrInjectedFault) {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if !sameSnapshot(after, before) && !sameSnapshot(after, expected) {
+					t.Fatalf("Failed operation left half-applied state: before %+v, after %+v", before, after)
+				}
+			}
+
+			assertRepositoryConsistent(t, inner)
+		}
+	})
+}
@@ -0,0 +1,329 @@
+package user_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/auth"
+	"property-based/internal/client/rpcclient"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/internal/transport/rpcserver"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// dialRPC sirve svc por JSON-RPC sobre una conexión en memoria y devuelve el cliente
+func dialRPC(svc service.UserOperations) *rpcclient.Client {
+	serverConn, clientConn := net.Pipe()
+	go rpcserver.ServeConn(rpcserver.NewServer(svc), serverConn)
+	return rpcclient.NewClient(clientConn)
+}
+
+// domainSentinels son todos los errores de dominio con código estable
+var domainSentinels = []error{
+	domain.ErrInvalidUserName, domain.ErrInvalidUserEmail, domain.ErrInvalidUserAge,
+	domain.ErrNotFound, domain.ErrAlreadyExists, domain.ErrInvalidTenant,
+	domain.ErrUnauthenticated, domain.ErrForbidden, domain.ErrWeakPassword,
+	domain.ErrInvalidCredentials, domain.ErrAccountLocked, domain.ErrInvalidToken,
+	domain.ErrTokenExpired, domain.ErrEmailAlreadyVerified,
+}
+
+// failingOperations devuelve siempre el mismo error, para probar su paso por el cable
+type failingOperations struct {
+	err error
+}
+
+func (f failingOperations) CreateUser(string, string, int) (*domain.User, error) { return nil, f.err }
+func (f failingOperations) CreateUserWithAttributes(string, string, int, domain.Attributes) (*domain.User, error) {
+	return nil, f.err
+}
+func (f failingOperations) GetUser(string) (*domain.User, error)        { return nil, f.err }
+func (f failingOperations) GetUserByEmail(string) (*domain.User, error) { return nil, f.err }
+func (f failingOperations) FindUsersByAttributes(...domain.AttributeFilter) ([]*domain.User, error) {
+	return nil, f.err
+}
+func (f failingOperations) GetAllUsers() ([]*domain.User, error) { return nil, f.err }
+func (f failingOperations) UpdateUser(string, string, string, int) (*domain.User, error) {
+	return nil, f.err
+}
+func (f failingOperations) PatchUser(string, domain.UserPatch) (*domain.User, error) {
+	return nil, f.err
+}
+func (f failingOperations) DeleteUser(string) error { return f.err }
+func (f failingOperations) CountUsers() int         { return 0 }
+
+// TestProperty_UserRPC_EquivalentToInProcessService
+// Invariante: El cliente JSON-RPC se comporta igual que el servicio en proceso
+// Relación: ∀ secuencia: rpc.op(x) ≅ local.op(x) (mismos datos, errors.Is con el mismo centinela)
+// Bordes: IDs inexistentes, emails duplicados, datos inválidos, parches vacíos
+func TestProperty_UserRPC_EquivalentToInProcessService(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		client := dialRPC(service.NewUserService(repository.NewInMemoryUserRepository()))
+		defer client.Close()
+
+		assertRemoteEquivalent(t, client)
+	})
+}
+
+// remoteUserOperations es el conjunto de métodos común a los clientes remotos
+type remoteUserOperations interface {
+	CreateUser(name, email string, age int) (*domain.User, error)
+	GetUser(id string) (*domain.User, error)
+	GetUserByEmail(email string) (*domain.User, error)
+	GetAllUsers() ([]*domain.User, error)
+	FindUsersByAttributes(filters ...domain.AttributeFilter) ([]*domain.User, error)
+	UpdateUser(id, name, email string, age int) (*domain.User, error)
+	PatchUser(id string, patch domain.UserPatch) (*domain.User, error)
+	DeleteUser(id string) error
+	CountUsers() (int, error)
+}
+
+// assertRemoteEquivalent ejecuta una secuencia aleatoria contra client y contra un
+// servicio local vacío, exigiendo los mismos datos y los mismos centinelas de error
+func assertRemoteEquivalent(t *rapid.T, client remoteUserOperations) {
+	local := service.NewUserService(repository.NewInMemoryUserRepository())
+
+	// IDs remotos indexados por el ID local equivalente
+	remoteIDs := map[string]string{"missing": "missing"}
+	localIDs := []string{"missing"}
+	emails := []string{"a@example.com", "b@example.com", "c@example.com"}
+
+	sameResult := func(op string, expected, actual *domain.User, expectedErr, actualErr error) {
+		if !sameError(expectedErr, actualErr) {
+			t.Fatalf("%s: expected error %v, got %v", op, expectedErr, actualErr)
+		}
+		if expected == nil {
+			return
+		}
+		if actual.Name != expected.Name || actual.Email != expected.Email || actual.Age != expected.Age ||
+			actual.ID != remoteIDs[expected.ID] {
+			t.Fatalf("%s: expected %+v, got %+v", op, expected, actual)
+		}
+	}
+
+	opCount := rapid.IntRange(1, 30).Draw(t, "op_count")
+	for i := 0; i < opCount; i++ {
+		id := rapid.SampledFrom(localIDs).Draw(t, "id")
+		email := rapid.SampledFrom(emails).Draw(t, "email")
+
+		switch rapid.IntRange(0, 6).Draw(t, "op") {
+		case 0:
+			data := drawUserData(t)
+			if rapid.Bool().Draw(t, "reuse_email") {
+				data.Email = email
+			}
+			expected, expectedErr := local.CreateUser(data.Name, data.Email, data.Age)
+			actual, actualErr := client.CreateUser(data.Name, data.Email, data.Age)
+			if expectedErr == nil && actualErr == nil {
+				remoteIDs[expected.ID] = actual.ID
+				localIDs = append(localIDs, expected.ID)
+			}
+			sameResult("CreateUser", expected, actual, expectedErr, actualErr)
+		case 1:
+			expected, expectedErr := local.GetUser(id)
+			actual, actualErr := client.GetUser(remoteIDs[id])
+			sameResult("GetUser", expected, actual, expectedErr, actualErr)
+		case 2:
+			expected, expectedErr := local.GetUserByEmail(email)
+			actual, actualErr := client.GetUserByEmail(email)
+			sameResult("GetUserByEmail", expected, actual, expectedErr, actualErr)
+		case 3:
+			data := drawUserData(t)
+			expected, expectedErr := local.UpdateUser(id, data.Name, data.Email, data.Age)
+			actual, actualErr := client.UpdateUser(remoteIDs[id], data.Name, data.Email, data.Age)
+			sameResult("UpdateUser", expected, actual, expectedErr, actualErr)
+		case 4:
+			patch := generators.ValidUserPatch().Draw(t, "patch")
+			expected, expectedErr := local.PatchUser(id, patch)
+			actual, actualErr := client.PatchUser(remoteIDs[id], patch)
+			sameResult("PatchUser", expected, actual, expectedErr, actualErr)
+		case 5:
+			expectedErr := local.DeleteUser(id)
+			actualErr := client.DeleteUser(remoteIDs[id])
+			sameResult("DeleteUser", nil, nil, expectedErr, actualErr)
+		case 6:
+			all, err := client.GetAllUsers()
+			helpers.AssertNoError(t, err, "GetAllUsers")
+			count, err := client.CountUsers()
+			helpers.AssertNoError(t, err, "CountUsers")
+			if count != local.CountUsers() || len(all) != count {
+				t.Fatalf("Expected %d users, got count %d and %d listed", local.CountUsers(), count, len(all))
+			}
+		}
+	}
+}
+
+// drawUserData genera datos de usuario válidos o inválidos
+func drawUserData(t *rapid.T) generators.ValidUserData {
+	if rapid.Bool().Draw(t, "valid") {
+		return generators.ValidUserStruct().Draw(t, "data")
+	}
+	invalid := generators.InvalidUserStruct().Draw(t, "data")
+	return generators.ValidUserData{Name: invalid.Name, Email: invalid.Email, Age: invalid.Age}
+}
+
+// sameError compara un error local con uno recibido por el cable a través de su centinela
+func sameError(expected, actual error) bool {
+	if expected == nil || actual == nil {
+		return expected == nil && actual == nil
+	}
+	sentinel := domain.ErrorForCode(domain.ErrorCode(expected))
+	return sentinel != nil && errors.Is(actual, sentinel)
+}
+
+// TestProperty_UserRPC_ErrorsKeepSentinelIdentity
+// Invariante: Todo error de dominio llega al cliente con su código estable y su centinela
+// Relación: servidor devuelve e (posiblemente envuelto) ⟹ errors.Is(err_cliente, centinela(e)) ∧ ErrorCode igual
+// Bordes: Centinela sin envolver, envuelto con contexto, error interno (sin filtrar el mensaje)
+func TestProperty_UserRPC_ErrorsKeepSentinelIdentity(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		internal := rapid.Bool().Draw(t, "internal")
+		var sentinel, served error
+		if internal {
+			served = fmt.Errorf("disk on fire: %s", rapid.StringMatching(`[a-z]{4,12}`).Draw(t, "secret"))
+		} else {
+			sentinel = rapid.SampledFrom(domainSentinels).Draw(t, "sentinel")
+			served = sentinel
+			if rapid.Bool().Draw(t, "wrapped") {
+				served = fmt.Errorf("context: %w", sentinel)
+			}
+		}
+
+		client := dialRPC(failingOperations{err: served})
+		defer client.Close()
+
+		var err error
+		switch rapid.IntRange(0, 2).Draw(t, "op") {
+		case 0:
+			_, err = client.GetUser("any")
+		case 1:
+			_, err = client.GetAllUsers()
+		case 2:
+			err = client.DeleteUser("any")
+		}
+
+		var remote *rpcserver.RemoteError
+		if !errors.As(err, &remote) {
+			t.Fatalf("Expected *rpcserver.RemoteError, got %T: %v", err, err)
+		}
+		if remote.Code != domain.ErrorCode(served) {
+			t.Fatalf("Expected code %s, got %s", domain.ErrorCode(served), remote.Code)
+		}
+		if internal {
+			if strings.Contains(err.Error(), "disk on fire") {
+				t.Fatalf("Internal error message leaked: %v", err)
+			}
+			return
+		}
+		if !errors.Is(err, sentinel) {
+			t.Fatalf("errors.Is(%v, %v) should hold across the wire", err, sentinel)
+		}
+	})
+}
+
+// TestProperty_UserRPC_AuthenticatesAndAuthorizesEveryCall
+// Invariante: Sin un token válido ninguna llamada llega al servicio; con él, se aplica la política del rol
+// Relación: token inválido ⟹ ErrUnauthenticated; válido ⟹ permitido(rol, op, propio) ? éxito : ErrForbidden
+// Bordes: Token vacío, token desconocido, usuario sobre registro ajeno, auditor escribiendo, CountUsers
+func TestProperty_UserRPC_AuthenticatesAndAuthorizesEveryCall(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		assertRemoteAuthorization(t, func(svc service.UserOperations, a auth.Authenticator, token string) (remoteUserOperations, func()) {
+			serverConn, clientConn := net.Pipe()
+			go rpcserver.ServeConn(rpcserver.NewServer(svc, rpcserver.WithAuthenticator(a)), serverConn)
+			client := rpcclient.NewClient(clientConn, rpcclient.WithToken(token))
+			return client, func() { client.Close() }
+		})
+	})
+}
+
+// connectRemote sirve svc autenticando con a y devuelve un cliente que envía token
+type connectRemote func(svc service.UserOperations, a auth.Authenticator, token string) (remoteUserOperations, func())
+
+// assertRemoteAuthorization ejecuta una operación aleatoria a través de connect con un
+// token registrado o no, y comprueba autenticación, política de roles y que los
+// rechazos no cambian el repositorio
+func assertRemoteAuthorization(t *rapid.T, connect connectRemote) {
+	repo := repository.NewInMemoryUserRepository()
+	base := service.NewUserService(repo)
+
+	selfData := generators.ValidUserStruct().Draw(t, "self")
+	self, err := base.CreateUser(selfData.Name, selfData.Email, selfData.Age)
+	helpers.AssertNoError(t, err, "Create self")
+	otherData := generators.ValidUserStruct().Draw(t, "other")
+	other, err := base.CreateUser(otherData.Name, otherData.Email, otherData.Age)
+	helpers.AssertNoError(t, err, "Create other")
+
+	role := rapid.SampledFrom([]auth.Role{auth.RoleAdmin, auth.RoleUser, auth.RoleAuditor}).Draw(t, "role")
+	token := rapid.StringMatching(`[a-zA-Z0-9]{16,32}`).Draw(t, "token")
+	authenticator := auth.NewTokenAuthenticator()
+	authenticator.Register(token, auth.Principal{UserID: self.ID, Role: role})
+
+	authenticated := rapid.Bool().Draw(t, "authenticated")
+	sent := token
+	if !authenticated {
+		sent = rapid.SampledFrom([]string{"", token + "x", token[1:]}).Draw(t, "bad_token")
+	}
+
+	client, closeClient := connect(base, authenticator, sent)
+	defer closeClient()
+
+	target := other
+	own := rapid.Bool().Draw(t, "own_record")
+	if own {
+		target = self
+	}
+
+	before := snapshotUsers(t, repo)
+	data := generators.ValidUserStruct().Draw(t, "data")
+
+	op := rapid.SampledFrom([]string{
+		service.OpCreateUser, service.OpGetUser, service.OpGetUserByEmail, service.OpGetAllUsers,
+		service.OpFindUsersByAttributes, service.OpUpdateUser, service.OpPatchUser, service.OpDeleteUser,
+		service.OpCountUsers,
+	}).Draw(t, "op")
+
+	var opErr error
+	switch op {
+	case service.OpCreateUser:
+		_, opErr = client.CreateUser(data.Name, data.Email, data.Age)
+		own = false
+	case service.OpGetUser:
+		_, opErr = client.GetUser(target.ID)
+	case service.OpGetUserByEmail:
+		_, opErr = client.GetUserByEmail(target.Email)
+	case service.OpGetAllUsers:
+		_, opErr = client.GetAllUsers()
+		own = false
+	case service.OpFindUsersByAttributes:
+		_, opErr = client.FindUsersByAttributes(domain.AttributeFilter{Name: "plan"})
+		own = false
+	case service.OpUpdateUser:
+		_, opErr = client.UpdateUser(target.ID, data.Name, data.Email, data.Age)
+	case service.OpPatchUser:
+		_, opErr = client.PatchUser(target.ID, domain.UserPatch{Age: &data.Age})
+	case service.OpDeleteUser:
+		opErr = client.DeleteUser(target.ID)
+	case service.OpCountUsers:
+		_, opErr = client.CountUsers()
+	}
+
+	switch {
+	case !authenticated:
+		helpers.AssertErrorIs(t, opErr, domain.ErrUnauthenticated, op+" with a bad token")
+	case op == service.OpCountUsers || allowed(role, op, own):
+		helpers.AssertNoError(t, opErr, "Allowed "+op+" for "+string(role))
+		return
+	default:
+		helpers.AssertErrorIs(t, opErr, domain.ErrForbidden, op+" by "+string(role))
+	}
+	if !sameSnapshot(before, snapshotUsers(t, repo)) {
+		t.Fatalf("Rejected %s (authenticated=%v, role=%s) modified the repository", op, authenticated, role)
+	}
+}
@@ -0,0 +1,211 @@
+package user_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// TestProperty_UserSharded_EquivalentToSingleLock
+// Invariante: El repositorio particionado se comporta igual que el de un solo lock
+// Relación: ∀ secuencia: sharded.op(x) == inmemory.op(x) (mismo resultado y mismo error)
+// Bordes: Un solo shard, más shards que usuarios, cambio de email a uno ocupado, IDs repetidos
+func TestProperty_UserSharded_EquivalentToSingleLock(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		model := repository.NewInMemoryUserRepository()
+		sharded := repository.NewShardedUserRepository(rapid.IntRange(1, 8).Draw(t, "shards"))
+
+		ids := []string{"u1", "u2", "u3", "u4"}
+		emails := []string{"a@example.com", "b@example.com", "c@example.com"}
+
+		opCount := rapid.IntRange(1, 50).Draw(t, "op_count")
+		for i := 0; i < opCount; i++ {
+			id := rapid.SampledFrom(ids).Draw(t, "id")
+			email := rapid.SampledFrom(emails).Draw(t, "email")
+			user := &domain.User{ID: id, Name: generators.ValidName().Draw(t, "name"), Email: email, Age: generators.ValidAge().Draw(t, "age")}
+
+			var expectedErr, actualErr error
+			var expected, actual *domain.User
+			switch op := rapid.IntRange(0, 5).Draw(t, "op"); op {
+			case 0:
+				expectedErr, actualErr = model.Create(user), sharded.Create(user)
+			case 1:
+				expected, expectedErr = model.GetByID(id)
+				actual, actualErr = sharded.GetByID(id)
+			case 2:
+				expected, expectedErr = model.GetByEmail(email)
+				actual, actualErr = sharded.GetByEmail(email)
+			case 3:
+				expectedErr, actualErr = model.Update(user), sharded.Update(user)
+			case 4:
+				age := user.Age
+				modify := func(u *domain.User) (bool, error) {
+					changed := u.Email != email || u.Age != age
+					u.Email, u.Age = email, age
+					return changed, nil
+				}
+				expected, expectedErr = model.Modify(id, modify)
+				actual, actualErr = sharded.Modify(id, modify)
+			case 5:
+				expectedErr, actualErr = model.Delete(id), sharded.Delete(id)
+			}
+
+			if !errors.Is(actualErr, expectedErr) {
+				t.Fatalf("Step %d: expected error %v, got %v", i, expectedErr, actualErr)
+			}
+			if expected != nil {
+				helpers.AssertUserEquals(t, expected, actual, fmt.Sprintf("Step %d", i))
+			}
+			if model.Count() != sharded.Count() {
+				t.Fatalf("Step %d: expected count %d, got %d", i, model.Count(), sharded.Count())
+			}
+		}
+
+		if !sameSnapshot(snapshotUsers(t, model), snapshotUsers(t, sharded)) {
+			t.Fatal("Final contents differ from the single-lock repository")
+		}
+	})
+}
+
+// TestProperty_UserSharded_ConcurrentCreatesKeepEmailsUnique
+// Invariante: Con creaciones concurrentes cada email tiene exactamente un dueño
+// Relación: creaciones exitosas == emails distintos ∧ cada perdedor recibe ErrAlreadyExists
+// Bordes: Todos compiten por el mismo email, emails en el mismo o distinto shard
+func TestProperty_UserSharded_ConcurrentCreatesKeepEmailsUnique(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewShardedUserRepository(rapid.IntRange(1, 8).Draw(t, "shards"))
+		svc := service.NewUserService(repo)
+
+		emailCount := rapid.IntRange(1, 4).Draw(t, "email_count")
+		workers := rapid.IntRange(2, 24).Draw(t, "workers")
+		name := generators.ValidName().Draw(t, "name")
+
+		var mu sync.Mutex
+		wins := map[string]int{}
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				email := fmt.Sprintf("shared%d@example.com", w%emailCount)
+				_, err := svc.CreateUser(name, email, 30)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					wins[email]++
+				case !errors.Is(err, domain.ErrAlreadyExists):
+					wins["unexpected: "+err.Error()]++
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		expected := min(emailCount, workers)
+		if len(wins) != expected {
+			t.Fatalf("Expected %d emails with an owner, got %v", expected, wins)
+		}
+		for email, n := range wins {
+			if n != 1 {
+				t.Fatalf("Email %s was created %d times", email, n)
+			}
+		}
+		assertRepositoryConsistent(t, repo)
+	})
+}
+
+// TestProperty_UserSharded_ConcurrentEmailSwapsStayConsistent
+// Invariante: Cambios de email concurrentes nunca dejan dos usuarios con el mismo email ni el índice desalineado
+// Relación: ∀ lectura concurrente GetByEmail(e) == u ⟹ u.Email == e; al final índice == contenido
+// Bordes: Más usuarios que emails libres, Update y Patch (Modify) mezclados, lectores simultáneos
+func TestProperty_UserSharded_ConcurrentEmailSwapsStayConsistent(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewShardedUserRepository(rapid.IntRange(1, 8).Draw(t, "shards"))
+		svc := service.NewUserService(repo)
+
+		pool := make([]string, rapid.IntRange(2, 8).Draw(t, "pool"))
+		for i := range pool {
+			pool[i] = fmt.Sprintf("pool%d@example.com", i)
+		}
+		userCount := rapid.IntRange(1, len(pool)).Draw(t, "users")
+		users := make([]*domain.User, userCount)
+		for i := range users {
+			user, err := svc.CreateUser(generators.ValidName().Draw(t, "name"), pool[i], 30)
+			helpers.AssertNoError(t, err, "Seed user")
+			users[i] = user
+		}
+
+		// Cada escritor recibe su guion de antemano: rapid.T no es seguro entre goroutines
+		rounds := rapid.IntRange(1, 30).Draw(t, "rounds")
+		scripts := make([][]int, userCount)
+		for i := range scripts {
+			scripts[i] = rapid.SliceOfN(rapid.IntRange(0, 2*len(pool)-1), rounds, rounds).Draw(t, "script")
+		}
+
+		var wg sync.WaitGroup
+		failures := make(chan string, userCount+1)
+		done := make(chan struct{})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, email := range pool {
+					if u, err := repo.GetByEmail(email); err == nil && u.Email != email {
+						failures <- fmt.Sprintf("GetByEmail(%s) returned user with email %s", email, u.Email)
+						return
+					}
+				}
+				time.Sleep(time.Microsecond)
+			}
+		}()
+
+		var writers sync.WaitGroup
+		for i, user := range users {
+			writers.Add(1)
+			go func(user *domain.User, script []int) {
+				defer writers.Done()
+				for _, step := range script {
+					email := pool[step%len(pool)]
+					var err error
+					if step < len(pool) {
+						_, err = svc.UpdateUser(user.ID, user.Name, email, user.Age)
+					} else {
+						_, err = svc.PatchUser(user.ID, domain.UserPatch{Email: &email})
+					}
+					if err != nil && !errors.Is(err, domain.ErrAlreadyExists) {
+						failures <- fmt.Sprintf("Unexpected error moving %s to %s: %v", user.ID, email, err)
+						return
+					}
+				}
+			}(user, scripts[i])
+		}
+		writers.Wait()
+		close(done)
+		wg.Wait()
+		close(failures)
+
+		for failure := range failures {
+			t.Fatal(failure)
+		}
+		if repo.Count() != userCount {
+			t.Fatalf("Expected %d users, got %d", userCount, repo.Count())
+		}
+		assertRepositoryConsistent(t, repo)
+	})
+}
@@ -0,0 +1,202 @@
+package user_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// populateRepository aplica una secuencia aleatoria de altas, cambios y bajas por el servicio
+func populateRepository(t *rapid.T, repo *repository.InMemoryUserRepository) {
+	svc := service.NewUserService(repo)
+	var ids []string
+	opCount := rapid.IntRange(0, 25).Draw(t, "op_count")
+	for i := 0; i < opCount; i++ {
+		if len(ids) == 0 || rapid.IntRange(0, 2).Draw(t, "op") == 0 {
+			data := generators.ValidUserStruct().Draw(t, "user_data")
+			if user, err := svc.CreateUser(data.Name, data.Email, data.Age); err == nil {
+				ids = append(ids, user.ID)
+			}
+			continue
+		}
+		id := rapid.SampledFrom(ids).Draw(t, "id")
+		if rapid.Bool().Draw(t, "delete") {
+			_ = svc.DeleteUser(id)
+			continue
+		}
+		data := generators.ValidUserStruct().Draw(t, "update_data")
+		_, _ = svc.UpdateUser(id, data.Name, data.Email, data.Age)
+	}
+}
+
+func encodeSnapshot(t *rapid.T, snap *repository.Snapshot) []byte {
+	var buf bytes.Buffer
+	_, err := snap.WriteTo(&buf)
+	helpers.AssertNoError(t, err, "Write snapshot")
+	return buf.Bytes()
+}
+
+// TestProperty_UserSnapshot_RestoreRoundTrip
+// Invariante: Restaurar una copia reproduce exactamente los usuarios y sus índices
+// Relación: Restore(Read(Write(Snapshot(r)))) ⟹ GetAll ≡ r ∧ GetByEmail y FindByAgeRange iguales ∧ emails siguen ocupados
+// Bordes: Repositorio vacío, usuarios borrados y con email cambiado, restaurar sobre un repositorio con datos
+func TestProperty_UserSnapshot_RestoreRoundTrip(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		source := repository.NewInMemoryUserRepository()
+		populateRepository(t, source)
+
+		restored, err := repository.ReadSnapshot(bytes.NewReader(encodeSnapshot(t, source.Snapshot())))
+		helpers.AssertNoError(t, err, "Read snapshot")
+
+		target := repository.NewInMemoryUserRepository()
+		populateRepository(t, target)
+		helpers.AssertNoError(t, target.Restore(restored), "Restore")
+
+		if !sameSnapshot(snapshotUsers(t, source), snapshotUsers(t, target)) {
+			t.Fatal("Restored users differ from the source")
+		}
+		assertRepositoryConsistent(t, target)
+
+		users, err := source.GetAll()
+		helpers.AssertNoError(t, err, "GetAll")
+		for _, u := range users {
+			got, err := target.GetByID(u.ID)
+			helpers.AssertNoError(t, err, "GetByID after restore")
+			if got.EmailVerified != u.EmailVerified || !got.CreatedAt.Equal(u.CreatedAt) || !got.UpdatedAt.Equal(u.UpdatedAt) {
+				t.Fatalf("User %s lost fields in the round trip: %+v vs %+v", u.ID, got, u)
+			}
+			dup := &domain.User{ID: "other-" + u.ID, Name: u.Name, Email: u.Email, Age: u.Age}
+			helpers.AssertErrorIs(t, target.Create(dup), domain.ErrAlreadyExists, "Restored email stays taken")
+		}
+
+		lo := generators.ValidAge().Draw(t, "lo")
+		hi := rapid.IntRange(lo, 150).Draw(t, "hi")
+		want, _ := source.FindByAgeRange(lo, hi)
+		got, _ := target.FindByAgeRange(lo, hi)
+		if len(want) != len(got) {
+			t.Fatalf("FindByAgeRange(%d, %d): expected %d users, got %d", lo, hi, len(want), len(got))
+		}
+		for i := range want {
+			helpers.AssertUserEquals(t, want[i], got[i], "Age index order")
+		}
+	})
+}
+
+// TestProperty_UserSnapshot_PointInTimeUnderConcurrentWrites
+// Invariante: La copia refleja un único instante aunque haya escritores concurrentes
+// Relación: snapshot tomado ⟹ escrituras posteriores no aparecen ∧ índice de emails coherente ∧ Restore acepta la copia
+// Bordes: Escritores creando y cambiando emails mientras se toma y se codifica la copia
+func TestProperty_UserSnapshot_PointInTimeUnderConcurrentWrites(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		populateRepository(t, repo)
+		svc := service.NewUserService(repo)
+
+		writers := rapid.IntRange(1, 6).Draw(t, "writers")
+		rounds := rapid.IntRange(1, 30).Draw(t, "rounds")
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				<-start
+				for i := 0; i < rounds; i++ {
+					user, err := svc.CreateUser("Concurrent Writer", fmt.Sprintf("w%d-%d@example.com", w, i), 30)
+					if err != nil {
+						continue
+					}
+					_, _ = svc.UpdateUser(user.ID, user.Name, fmt.Sprintf("moved%d-%d@example.com", w, i), 31)
+				}
+			}(w)
+		}
+
+		close(start)
+		snap := repo.Snapshot()
+		frozen := snap.Users()
+		encoded := encodeSnapshot(t, snap)
+		wg.Wait()
+
+		decoded, err := repository.ReadSnapshot(bytes.NewReader(encoded))
+		helpers.AssertNoError(t, err, "Snapshot taken under concurrent writes must be consistent")
+		if decoded.Len() != len(frozen) {
+			t.Fatalf("Encoded snapshot has %d users, expected %d", decoded.Len(), len(frozen))
+		}
+		for i, u := range decoded.Users() {
+			helpers.AssertUserEquals(t, frozen[i], u, "Writes after Snapshot must not leak into it")
+		}
+
+		restored := repository.NewInMemoryUserRepository()
+		helpers.AssertNoError(t, restored.Restore(decoded), "Restore")
+		assertRepositoryConsistent(t, restored)
+	})
+}
+
+// TestProperty_UserSnapshot_CorruptionDetected
+// Invariante: Una copia alterada, de otro formato o de otra versión nunca se carga
+// Relación: payload ≠ checksum ⟹ ErrSnapshotCorrupt; version > SnapshotVersion ⟹ ErrSnapshotUnsupported; índice de emails incoherente ⟹ ErrSnapshotCorrupt
+// Bordes: Un byte cambiado en el payload, checksum recalculado sobre un índice falso, archivo truncado
+func TestProperty_UserSnapshot_CorruptionDetected(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		_, err := service.NewUserService(repo).CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		encoded := encodeSnapshot(t, repo.Snapshot())
+
+		var env map[string]json.RawMessage
+		helpers.AssertNoError(t, json.Unmarshal(encoded, &env), "Decode envelope")
+
+		var expected error
+		switch rapid.IntRange(0, 3).Draw(t, "corruption") {
+		case 0:
+			tampered := bytes.Clone(encoded)
+			start := bytes.Index(tampered, []byte(`"payload":`)) + len(`"payload":`)
+			i := rapid.IntRange(start, len(tampered)-3).Draw(t, "offset")
+			tampered[i] ^= byte(rapid.IntRange(1, 255).Draw(t, "flip"))
+			_, err := repository.ReadSnapshot(bytes.NewReader(tampered))
+			if !errors.Is(err, repository.ErrSnapshotCorrupt) {
+				t.Fatalf("Flipped byte %d: expected ErrSnapshotCorrupt, got %v", i, err)
+			}
+			return
+		case 1:
+			env["version"] = json.RawMessage(fmt.Sprint(repository.SnapshotVersion + rapid.IntRange(1, 5).Draw(t, "bump")))
+			expected = repository.ErrSnapshotUnsupported
+		case 2:
+			var payload map[string]json.RawMessage
+			helpers.AssertNoError(t, json.Unmarshal(env["payload"], &payload), "Decode payload")
+			payload["emails"] = json.RawMessage(`{"someone@else.com":"nobody"}`)
+			raw, _ := json.Marshal(payload)
+			env["payload"] = raw
+			sum := sha256.Sum256(raw)
+			env["checksum"], _ = json.Marshal("sha256:" + hex.EncodeToString(sum[:]))
+			expected = repository.ErrSnapshotCorrupt
+		case 3:
+			cut := rapid.IntRange(0, len(encoded)-2).Draw(t, "cut")
+			_, err := repository.ReadSnapshot(bytes.NewReader(encoded[:cut]))
+			if !errors.Is(err, repository.ErrSnapshotCorrupt) {
+				t.Fatalf("Truncated snapshot: expected ErrSnapshotCorrupt, got %v", err)
+			}
+			return
+		}
+
+		tampered, _ := json.Marshal(env)
+		_, err = repository.ReadSnapshot(bytes.NewReader(tampered))
+		if !errors.Is(err, expected) {
+			t.Fatalf("Expected %v, got %v", expected, err)
+		}
+	})
+}
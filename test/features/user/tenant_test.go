@@ -0,0 +1,255 @@
+package user_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+func newTenantService() *service.TenantUserService {
+	return service.NewTenantUserService(repository.NewTenantRepositories(func() repository.UserRepository {
+		return repository.NewInMemoryUserRepository()
+	}))
+}
+
+// TestProperty_UserTenant_BehavesLikeIsolatedDeployments
+// Invariante: Cada tenant se comporta como un despliegue independiente
+// Relación: ∀ secuencia intercalada: tenant.op(x) == servicio_aislado[tenant].op(x)
+// Bordes: Mismo email en varios tenants, borrados y conteos intercalados entre tenants
+func TestProperty_UserTenant_BehavesLikeIsolatedDeployments(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		svc := newTenantService()
+		tenants := []string{"acme", "globex", "initech"}
+		isolated := make(map[string]*service.UserService)
+		for _, tenant := range tenants {
+			isolated[tenant] = service.NewUserService(repository.NewInMemoryUserRepository())
+		}
+		emails := []string{"a@example.com", "b@example.com", "c@example.com"}
+
+		opCount := rapid.IntRange(1, 40).Draw(t, "op_count")
+		for i := 0; i < opCount; i++ {
+			tenant := rapid.SampledFrom(tenants).Draw(t, "tenant")
+			email := rapid.SampledFrom(emails).Draw(t, "email")
+			model := isolated[tenant]
+
+			switch rapid.IntRange(0, 3).Draw(t, "op") {
+			case 0:
+				name := generators.ValidName().Draw(t, "name")
+				age := generators.ValidAge().Draw(t, "age")
+				_, expectedErr := model.CreateUser(name, email, age)
+				_, actualErr := svc.CreateUser(tenant, name, email, age)
+				if !errors.Is(actualErr, expectedErr) {
+					t.Fatalf("CreateUser(%s, %s): expected %v, got %v", tenant, email, expectedErr, actualErr)
+				}
+			case 1:
+				expected, expectedErr := model.GetUserByEmail(email)
+				actual, actualErr := svc.GetUserByEmail(tenant, email)
+				if !errors.Is(actualErr, expectedErr) {
+					t.Fatalf("GetUserByEmail(%s, %s): expected %v, got %v", tenant, email, expectedErr, actualErr)
+				}
+				if expected != nil && (actual.Name != expected.Name || actual.Age != expected.Age) {
+					t.Fatalf("GetUserByEmail(%s, %s): expected %+v, got %+v", tenant, email, expected, actual)
+				}
+			case 2:
+				if target, err := model.GetUserByEmail(email); err == nil {
+					helpers.AssertNoError(t, model.DeleteUser(target.ID), "Model delete")
+					actual, err := svc.GetUserByEmail(tenant, email)
+					helpers.AssertNoError(t, err, "Tenant lookup before delete")
+					helpers.AssertNoError(t, svc.DeleteUser(tenant, actual.ID), "Tenant delete")
+				}
+			case 3:
+				count, err := svc.CountUsers(tenant)
+				helpers.AssertNoError(t, err, "CountUsers")
+				all, err := svc.GetAllUsers(tenant)
+				helpers.AssertNoError(t, err, "GetAllUsers")
+				if count != model.CountUsers() || len(all) != count {
+					t.Fatalf("Tenant %s: expected %d users, got count %d and %d listed", tenant, model.CountUsers(), count, len(all))
+				}
+			}
+		}
+	})
+}
+
+// TestProperty_UserTenant_CannotReachOtherTenantsByID
+// Invariante: Conocer el ID de un usuario de otro tenant no permite leerlo ni modificarlo
+// Relación: u ∈ A ⟹ B.GetUser(u.ID) == B.UpdateUser(u.ID) == B.DeleteUser(u.ID) == ErrNotFound ∧ A.u intacto
+// Bordes: Tenant vacío aún sin usuarios, tenant con usuario del mismo email
+func TestProperty_UserTenant_CannotReachOtherTenantsByID(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		svc := newTenantService()
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		owned, err := svc.CreateUser("tenant-a", data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create in tenant A")
+
+		if rapid.Bool().Draw(t, "same_email_in_b") {
+			_, err := svc.CreateUser("tenant-b", data.Name, data.Email, data.Age)
+			helpers.AssertNoError(t, err, "Same email in tenant B")
+		}
+
+		_, err = svc.GetUser("tenant-b", owned.ID)
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Cross-tenant GetUser")
+
+		update := generators.ValidUserStruct().Draw(t, "update")
+		_, err = svc.UpdateUser("tenant-b", owned.ID, update.Name, update.Email, update.Age)
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Cross-tenant UpdateUser")
+
+		_, err = svc.PatchUser("tenant-b", owned.ID, generators.ValidUserPatch().Draw(t, "patch"))
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Cross-tenant PatchUser")
+
+		err = svc.DeleteUser("tenant-b", owned.ID)
+		helpers.AssertErrorIs(t, err, domain.ErrNotFound, "Cross-tenant DeleteUser")
+
+		retrieved, err := svc.GetUser("tenant-a", owned.ID)
+		helpers.AssertNoError(t, err, "Owner tenant still sees user")
+		helpers.AssertUserEquals(t, owned, retrieved, "User untouched by other tenant")
+	})
+}
+
+// TestProperty_UserTenant_EmptyTenant_Rejected
+// Invariante: Toda operación exige un tenant explícito
+// Relación: tenant ∈ {"", "   "} ⟹ ErrInvalidTenant sin tocar ningún tenant
+// Bordes: Tenant vacío y solo espacios
+func TestProperty_UserTenant_EmptyTenant_Rejected(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		svc := newTenantService()
+		tenant := rapid.SampledFrom([]string{"", " ", "   "}).Draw(t, "tenant")
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		_, err := svc.CreateUser(tenant, data.Name, data.Email, data.Age)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidTenant, "CreateUser without tenant")
+
+		_, err = svc.GetAllUsers(tenant)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidTenant, "GetAllUsers without tenant")
+
+		_, err = svc.CountUsers(tenant)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidTenant, "CountUsers without tenant")
+	})
+}
+
+// TestProperty_UserTenant_ReadsDoNotCreateTenants
+// Invariante: Solo un alta crea el almacenamiento de un tenant; las lecturas de tenants desconocidos no reservan nada
+// Relación: ∀ op ≠ CreateUser sobre tenant nuevo: Tenants() sin cambios ∧ lecturas == ErrNotFound o vacío
+// Bordes: Listado y conteo (vacíos), búsquedas por ID y email, modificaciones y borrados (ErrNotFound)
+func TestProperty_UserTenant_ReadsDoNotCreateTenants(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repos := repository.NewTenantRepositories(func() repository.UserRepository {
+			return repository.NewInMemoryUserRepository()
+		})
+		svc := service.NewTenantUserService(repos)
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		owned, err := svc.CreateUser("known", data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create in known tenant")
+
+		tenant := rapid.StringMatching(`[a-z]{1,12}`).Filter(func(s string) bool { return s != "known" }).Draw(t, "tenant")
+		for i := rapid.IntRange(1, 10).Draw(t, "op_count"); i > 0; i-- {
+			switch rapid.IntRange(0, 6).Draw(t, "op") {
+			case 0:
+				_, err = svc.GetUser(tenant, owned.ID)
+				helpers.AssertErrorIs(t, err, domain.ErrNotFound, "GetUser on unknown tenant")
+			case 1:
+				_, err = svc.GetUserByEmail(tenant, data.Email)
+				helpers.AssertErrorIs(t, err, domain.ErrNotFound, "GetUserByEmail on unknown tenant")
+			case 2:
+				users, err := svc.GetAllUsers(tenant)
+				helpers.AssertNoError(t, err, "GetAllUsers on unknown tenant")
+				if len(users) != 0 {
+					t.Fatalf("Unknown tenant listed %d users", len(users))
+				}
+			case 3:
+				count, err := svc.CountUsers(tenant)
+				helpers.AssertNoError(t, err, "CountUsers on unknown tenant")
+				if count != 0 {
+					t.Fatalf("Unknown tenant counted %d users", count)
+				}
+			case 4:
+				_, err = svc.UpdateUser(tenant, owned.ID, data.Name, data.Email, data.Age)
+				helpers.AssertErrorIs(t, err, domain.ErrNotFound, "UpdateUser on unknown tenant")
+			case 5:
+				_, err = svc.PatchUser(tenant, owned.ID, generators.ValidUserPatch().Draw(t, "patch"))
+				helpers.AssertErrorIs(t, err, domain.ErrNotFound, "PatchUser on unknown tenant")
+			case 6:
+				helpers.AssertErrorIs(t, svc.DeleteUser(tenant, owned.ID), domain.ErrNotFound, "DeleteUser on unknown tenant")
+			}
+			if tenants := repos.Tenants(); len(tenants) != 1 || tenants[0] != "known" {
+				t.Fatalf("Reading tenant %q created storage: %v", tenant, tenants)
+			}
+		}
+
+		_, err = svc.CreateUser(tenant, data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "First create opens the tenant")
+		if count, _ := svc.CountUsers(tenant); count != 1 || len(repos.Tenants()) != 2 {
+			t.Fatalf("Create did not open tenant %q: count %d, tenants %v", tenant, count, repos.Tenants())
+		}
+	})
+}
+
+// TestProperty_UserTenant_EachTenantEnforcesItsOwnPolicy
+// Invariante: Cada tenant valida con su propia política, no con la de otro ni con la por defecto
+// Relación: ∀ tenant, edad: CreateUser(tenant, edad) ok ⟺ política(tenant).AgeMin ≤ edad ≤ política(tenant).AgeMax,
+// con o sin espacios alrededor del ID, y UpdateUser aplica la misma política
+// Bordes: Los límites de edad de todas las políticas y sus vecinos, tenant sin política propia (por defecto),
+// ID con espacios o tabuladores alrededor
+func TestProperty_UserTenant_EachTenantEnforcesItsOwnPolicy(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		acme := generators.ValidationPolicy().Draw(t, "acme_policy")
+		globex := generators.ValidationPolicy().Filter(func(p *domain.ValidationPolicy) bool {
+			return p.AgeMin != acme.AgeMin || p.AgeMax != acme.AgeMax
+		}).Draw(t, "globex_policy")
+		policies := map[string]*domain.ValidationPolicy{"acme": acme, "globex": globex}
+		svc := service.NewTenantUserService(
+			repository.NewTenantRepositories(func() repository.UserRepository {
+				return repository.NewInMemoryUserRepository()
+			}),
+			service.WithTenantValidationPolicies(func(tenantID string) *domain.ValidationPolicy {
+				if tenantID != strings.TrimSpace(tenantID) {
+					t.Fatalf("Policy looked up with the raw tenant ID %q", tenantID)
+				}
+				return policies[tenantID]
+			}),
+		)
+		padded := func(tenant, label string) string {
+			pad := rapid.SampledFrom([]string{"", " ", "\t", "  "})
+			return pad.Draw(t, label+"_before") + tenant + pad.Draw(t, label+"_after")
+		}
+
+		var ages []int
+		for _, p := range []*domain.ValidationPolicy{acme, globex, domain.DefaultValidationPolicy} {
+			ages = append(ages, p.AgeMin-1, p.AgeMin, p.AgeMax, p.AgeMax+1)
+		}
+
+		for _, tenant := range []string{"acme", "globex", "initech"} {
+			policy := domain.DefaultValidationPolicy
+			if p, ok := policies[tenant]; ok {
+				policy = p
+			}
+			data := generators.ValidUserStructFor(policy).Draw(t, "data_"+tenant)
+			data.Age = rapid.SampledFrom(ages).Draw(t, "age_"+tenant)
+
+			user, err := svc.CreateUser(padded(tenant, "create_"+tenant), data.Name, data.Email, data.Age)
+			if data.Age >= policy.AgeMin && data.Age <= policy.AgeMax {
+				helpers.AssertNoError(t, err, "Age within "+tenant+"'s bounds")
+				if user.Age != data.Age {
+					t.Fatalf("%s: expected age %d, got %d", tenant, data.Age, user.Age)
+				}
+				age := rapid.SampledFrom(ages).Draw(t, "update_age_"+tenant)
+				_, err = svc.UpdateUser(padded(tenant, "update_"+tenant), user.ID, user.Name, user.Email, age)
+				if age >= policy.AgeMin && age <= policy.AgeMax {
+					helpers.AssertNoError(t, err, "Update within "+tenant+"'s bounds")
+				} else {
+					helpers.AssertErrorIs(t, err, domain.ErrInvalidUserAge, "Update outside "+tenant+"'s bounds")
+				}
+				continue
+			}
+			helpers.AssertErrorIs(t, err, domain.ErrInvalidUserAge, "Age outside "+tenant+"'s bounds")
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package user_test
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/test/generators"
+)
+
+var statedRange = regexp.MustCompile(`between (-?\d+) and (-?\d+)`)
+
+// parseRange extrae los límites "between X and Y" que anuncia un mensaje.
+func parseRange(t *rapid.T, msg string) (int, int) {
+	m := statedRange.FindStringSubmatch(msg)
+	if m == nil {
+		t.Fatalf("Message %q does not state its bounds", msg)
+	}
+	lo, _ := strconv.Atoi(m[1])
+	hi, _ := strconv.Atoi(m[2])
+	return lo, hi
+}
+
+// checkedPattern compila el patrón que lleva el error, que debe ser el de la
+// política, y exige que el mensaje lo describa en palabras en vez de citarlo.
+func checkedPattern(t *rapid.T, verr *domain.ValidationError, policyPattern, msg string) *regexp.Regexp {
+	if verr.Pattern != policyPattern {
+		t.Fatalf("Error carries pattern %q, the policy checks %q", verr.Pattern, policyPattern)
+	}
+	if strings.Contains(msg, verr.Pattern) {
+		t.Fatalf("Message %q shows the raw pattern %s", msg, verr.Pattern)
+	}
+	return regexp.MustCompile(verr.Pattern)
+}
+
+// nameOfLength construye un nombre de n caracteres con la forma que aceptan
+// tanto el patrón por defecto como el de palabras capitalizadas.
+func nameOfLength(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return "A" + strings.Repeat("a", n-1)
+}
+
+// passwordOfLength construye una contraseña de n caracteres con letra y dígito.
+func passwordOfLength(n int) string {
+	if n < 2 {
+		return strings.Repeat("1", n)
+	}
+	return "a1" + strings.Repeat("b", n-2)
+}
+
+// assertBoundary comprueba que lo y hi se aceptan y que sus vecinos exteriores
+// se rechazan con el centinela del campo, tal como dice el mensaje.
+func assertBoundary(t *rapid.T, msg string, lo, hi int, check func(int) error, sentinel error) {
+	for _, n := range []int{lo, hi} {
+		if err := check(n); err != nil {
+			t.Fatalf("Message %q says %d is allowed, but it fails with %v", msg, n, err)
+		}
+	}
+	for _, n := range []int{lo - 1, hi + 1} {
+		if err := check(n); !errors.Is(err, sentinel) {
+			t.Fatalf("Message %q says %d is not allowed, but got %v", msg, n, err)
+		}
+	}
+}
+
+// TestProperty_UserValidationMessages_StatedBoundsMatchRule
+// Invariante: Todo error de validación anuncia exactamente los límites que Validate aplica y describe su patrón sin citarlo
+// Relación: mensaje "between X and Y" ⟹ X e Y aceptados ∧ X-1 e Y+1 rechazados con el mismo centinela; Pattern == el de la política ∉ mensaje
+// Bordes: Edad 0 con la política por defecto, longitud mínima 1, contraseñas de 7/8/128/129 caracteres, patrones propios
+func TestProperty_UserValidationMessages_StatedBoundsMatchRule(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		policy := rapid.OneOf(rapid.Just(domain.DefaultValidationPolicy), generators.ValidationPolicy()).Draw(t, "policy")
+		invalid := generators.InvalidUserStructFor(policy).Draw(t, "invalid")
+
+		err := policy.Validate(&domain.User{Name: invalid.Name, Email: invalid.Email, Age: invalid.Age})
+		var verr *domain.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("Expected a *ValidationError for %+v, got %v", invalid, err)
+		}
+		msg := err.Error()
+
+		switch {
+		case errors.Is(err, domain.ErrInvalidUserAge):
+			lo, hi := parseRange(t, msg)
+			assertBoundary(t, msg, lo, hi, policy.CheckAge, domain.ErrInvalidUserAge)
+			if invalid.Age >= lo && invalid.Age <= hi {
+				t.Fatalf("Age %d was rejected but lies within the stated %d..%d", invalid.Age, lo, hi)
+			}
+
+		case errors.Is(err, domain.ErrInvalidUserName):
+			lo, hi := parseRange(t, msg)
+			pattern := checkedPattern(t, verr, policy.NamePattern, msg)
+			if policy == domain.DefaultValidationPolicy && !strings.Contains(msg, "only letters and spaces, starting and ending with a letter") {
+				t.Fatalf("Message %q does not state the default name rule in words", msg)
+			}
+			assertBoundary(t, msg, lo, hi, func(n int) error { return policy.CheckName(nameOfLength(n)) }, domain.ErrInvalidUserName)
+			name := strings.TrimSpace(invalid.Name)
+			if len(name) >= lo && len(name) <= hi && pattern.MatchString(name) {
+				t.Fatalf("Name %q was rejected but satisfies the stated length and pattern", name)
+			}
+
+		case errors.Is(err, domain.ErrInvalidUserEmail):
+			pattern := checkedPattern(t, verr, policy.EmailPattern, msg)
+			if pattern.MatchString(strings.TrimSpace(strings.ToLower(invalid.Email))) {
+				t.Fatalf("Email %q was rejected but matches the stated pattern", invalid.Email)
+			}
+			if valid := generators.ValidEmailFor(policy).Draw(t, "valid_email"); !pattern.MatchString(valid) {
+				t.Fatalf("Email %q is accepted but does not match the stated pattern", valid)
+			}
+
+		default:
+			t.Fatalf("Unexpected validation error %v", err)
+		}
+
+		weak := generators.InvalidPassword().Draw(t, "weak_password")
+		err = domain.ValidatePassword(weak)
+		if !errors.As(err, &verr) || !errors.Is(err, domain.ErrWeakPassword) {
+			t.Fatalf("Expected a weak password *ValidationError for %q, got %v", weak, err)
+		}
+		lo, hi := parseRange(t, err.Error())
+		assertBoundary(t, err.Error(), lo, hi, func(n int) error { return domain.ValidatePassword(passwordOfLength(n)) }, domain.ErrWeakPassword)
+	})
+}
+
+// TestProperty_UserValidationMessages_DocsAndSentinelsDoNotRestateBounds
+// Invariante: Los límites solo se escriben en la política; ni los centinelas ni la documentación los contradicen
+// Relación: centinela de validación sin dígitos ∧ tabla del README == DefaultValidationPolicy
+// Bordes: Centinelas de nombre, email, edad y contraseña
+func TestProperty_UserValidationMessages_DocsAndSentinelsDoNotRestateBounds(t *testing.T) {
+	readme, err := os.ReadFile("../../../README.md")
+	if err != nil {
+		t.Fatalf("Reading README: %v", err)
+	}
+
+	rapid.Check(t, func(t *rapid.T) {
+		sentinel := rapid.SampledFrom([]error{
+			domain.ErrInvalidUserName, domain.ErrInvalidUserEmail, domain.ErrInvalidUserAge, domain.ErrWeakPassword,
+		}).Draw(t, "sentinel")
+		if strings.ContainsAny(sentinel.Error(), "0123456789") {
+			t.Fatalf("Sentinel %q states bounds that the policy may not enforce", sentinel)
+		}
+
+		p := domain.DefaultValidationPolicy
+		row := rapid.SampledFrom([]struct {
+			field  string
+			lo, hi int
+		}{
+			{"Name", p.NameMinLength, p.NameMaxLength},
+			{"Age", p.AgeMin, p.AgeMax},
+			{"Password", domain.PasswordMinLength, domain.PasswordMaxLength},
+		}).Draw(t, "row")
+		m := regexp.MustCompile(`\| \*\*` + row.field + `\*\* \| (\d+)-(\d+) `).FindSubmatch(readme)
+		if m == nil {
+			t.Fatalf("README business rules do not state bounds for %s", row.field)
+		}
+		if lo, hi := string(m[1]), string(m[2]); lo != strconv.Itoa(row.lo) || hi != strconv.Itoa(row.hi) {
+			t.Fatalf("README says %s is %s-%s, the rule is %d-%d", row.field, lo, hi, row.lo, row.hi)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package user_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+var invalidFieldErrors = []error{domain.ErrInvalidUserName, domain.ErrInvalidUserEmail, domain.ErrInvalidUserAge}
+
+// TestProperty_UserValidationPolicy_ServiceEnforcesInjectedPolicy
+// Invariante: El servicio valida contra la política inyectada, no contra la por defecto
+// Relación: datos válidos para p ⟹ Create/Update/Patch aceptan; inválidos en un campo ⟹ error de ese campo y nada cambia
+// Bordes: Edad mínima 0 o 21, longitud mínima de nombre 1, patrones más estrictos que los por defecto
+func TestProperty_UserValidationPolicy_ServiceEnforcesInjectedPolicy(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		policy := generators.ValidationPolicy().Draw(t, "policy")
+		svc := service.NewUserService(repository.NewInMemoryUserRepository(), service.WithValidationPolicy(policy))
+
+		valid := generators.ValidUserStructFor(policy).Draw(t, "valid")
+		created, err := svc.CreateUser(valid.Name, valid.Email, valid.Age)
+		helpers.AssertNoError(t, err, "CreateUser with data valid for the policy")
+
+		invalid := generators.InvalidUserStructFor(policy).Draw(t, "invalid")
+		expected := invalidFieldErrors[invalid.CaseType]
+
+		_, err = svc.CreateUser(invalid.Name, invalid.Email, invalid.Age)
+		if !errors.Is(err, expected) {
+			t.Fatalf("CreateUser(%q, %q, %d): expected %v, got %v", invalid.Name, invalid.Email, invalid.Age, expected, err)
+		}
+		_, err = svc.UpdateUser(created.ID, invalid.Name, invalid.Email, invalid.Age)
+		if !errors.Is(err, expected) {
+			t.Fatalf("UpdateUser(%q, %q, %d): expected %v, got %v", invalid.Name, invalid.Email, invalid.Age, expected, err)
+		}
+		_, err = svc.PatchUser(created.ID, domain.UserPatch{Name: &invalid.Name, Email: &invalid.Email, Age: &invalid.Age})
+		if !errors.Is(err, expected) {
+			t.Fatalf("PatchUser(%q, %q, %d): expected %v, got %v", invalid.Name, invalid.Email, invalid.Age, expected, err)
+		}
+
+		stored, err := svc.GetUser(created.ID)
+		helpers.AssertNoError(t, err, "GetUser after rejected writes")
+		helpers.AssertUserEquals(t, created, stored, "User after rejected writes")
+
+		if svc.CountUsers() != 1 {
+			t.Fatalf("Expected 1 user, got %d", svc.CountUsers())
+		}
+	})
+}
+
+// TestProperty_UserValidationPolicy_GeneratorsAgreeWithPolicy
+// Invariante: Los generators derivados de una política producen exactamente lo que ella acepta o rechaza
+// Relación: p.Validate(ValidUserStructFor(p)) == nil ∧ p.Validate(InvalidUserStructFor(p)) == error del campo CaseType
+// Bordes: Política por defecto, AgeMin-1 y AgeMax+1, nombres en el límite de longitud
+func TestProperty_UserValidationPolicy_GeneratorsAgreeWithPolicy(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		policy := rapid.OneOf(rapid.Just(domain.DefaultValidationPolicy), generators.ValidationPolicy()).Draw(t, "policy")
+
+		valid := generators.ValidUserStructFor(policy).Draw(t, "valid")
+		if err := policy.Validate(&domain.User{Name: valid.Name, Email: valid.Email, Age: valid.Age}); err != nil {
+			t.Fatalf("Valid data %+v rejected by %+v: %v", valid, *policy, err)
+		}
+
+		invalid := generators.InvalidUserStructFor(policy).Draw(t, "invalid")
+		err := policy.Validate(&domain.User{Name: invalid.Name, Email: invalid.Email, Age: invalid.Age})
+		if expected := invalidFieldErrors[invalid.CaseType]; !errors.Is(err, expected) {
+			t.Fatalf("Invalid data %+v: expected %v, got %v", invalid, expected, err)
+		}
+
+		// La política por defecto es la que aplican User.Validate y NewUser
+		if policy == domain.DefaultValidationPolicy {
+			u := &domain.User{Name: invalid.Name, Email: invalid.Email, Age: invalid.Age}
+			if got := u.Validate(); got == nil || got.Error() != err.Error() {
+				t.Fatalf("User.Validate disagrees with DefaultValidationPolicy on %+v", invalid)
+			}
+		}
+	})
+}
+
+// TestProperty_UserValidationPolicy_LoadKeepsDefaultsForOmittedFields
+// Invariante: Cargar una política solo cambia los campos presentes en el archivo
+// Relación: Load({campo: v}).campo == v ∧ resto == DefaultValidationPolicy; límites incoherentes o regex inválida ⟹ error
+// Bordes: Objeto vacío, AgeMax < AgeMin, NameMinLength 0, patrón que no compila, campo desconocido
+func TestProperty_UserValidationPolicy_LoadKeepsDefaultsForOmittedFields(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		def := domain.DefaultValidationPolicy
+		want := *def
+		var fields []string
+		if rapid.Bool().Draw(t, "set_age_min") {
+			want.AgeMin = rapid.IntRange(0, def.AgeMax).Draw(t, "age_min")
+			fields = append(fields, fmt.Sprintf(`"age_min": %d`, want.AgeMin))
+		}
+		if rapid.Bool().Draw(t, "set_name_max") {
+			want.NameMaxLength = rapid.IntRange(def.NameMinLength, 200).Draw(t, "name_max_length")
+			fields = append(fields, fmt.Sprintf(`"name_max_length": %d`, want.NameMaxLength))
+		}
+		if rapid.Bool().Draw(t, "set_email_pattern") {
+			want.EmailPattern = `^[a-z]+@example\.com$`
+			fields = append(fields, `"email_pattern": "^[a-z]+@example\\.com$"`)
+		}
+
+		loaded, err := domain.LoadValidationPolicy(strings.NewReader("{" + strings.Join(fields, ", ") + "}"))
+		helpers.AssertNoError(t, err, "LoadValidationPolicy")
+		if loaded.NameMinLength != want.NameMinLength || loaded.NameMaxLength != want.NameMaxLength ||
+			loaded.NamePattern != want.NamePattern || loaded.EmailPattern != want.EmailPattern ||
+			loaded.AgeMin != want.AgeMin || loaded.AgeMax != want.AgeMax {
+			t.Fatalf("Expected %+v, got %+v", want, *loaded)
+		}
+		if err := loaded.CheckAge(want.AgeMin); err != nil {
+			t.Fatalf("Loaded policy rejects its own minimum age: %v", err)
+		}
+
+		bad := rapid.SampledFrom([]string{
+			`{"age_min": 10, "age_max": 5}`,
+			`{"name_min_length": 0}`,
+			`{"name_min_length": 10, "name_max_length": 3}`,
+			`{"name_pattern": "[a-z"}`,
+			`{"email_pattern": "(unclosed"}`,
+			`{"min_age": 18}`,
+			`not json`,
+		}).Draw(t, "bad")
+		if _, err := domain.LoadValidationPolicy(strings.NewReader(bad)); err == nil {
+			t.Fatalf("LoadValidationPolicy(%s) should fail", bad)
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package user_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+	"property-based/internal/mail"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// lastToken extrae el token del último mensaje enviado (última línea del cuerpo)
+func lastToken(t *rapid.T, mailer *mail.InMemoryMailer, to string) string {
+	t.Helper()
+
+	messages := mailer.Messages()
+	if len(messages) == 0 {
+		t.Fatal("No verification message sent")
+	}
+	msg := messages[len(messages)-1]
+	if msg.To != to {
+		t.Fatalf("Verification sent to %s, expected %s", msg.To, to)
+	}
+	lines := strings.Split(strings.TrimSpace(msg.Body), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// TestProperty_UserVerification_TokenIsSingleUse
+// Invariante: Un token válido verifica el email una sola vez
+// Relación: Verify(token) ⟹ EmailVerified ∧ Verify(token) otra vez == ErrInvalidToken
+// Bordes: Usuario recién creado (no verificado), solicitar de nuevo tras verificar, varios tokens emitidos
+func TestProperty_UserVerification_TokenIsSingleUse(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		mailer := mail.NewInMemoryMailer()
+		users := service.NewUserService(repo)
+		verify := service.NewVerificationService(repo, repository.NewInMemoryVerificationTokenRepository(), mailer, service.DefaultVerificationConfig)
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := users.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		if user.EmailVerified {
+			t.Fatal("New users must start unverified")
+		}
+
+		requests := rapid.IntRange(1, 3).Draw(t, "requests")
+		var tokens []string
+		for i := 0; i < requests; i++ {
+			helpers.AssertNoError(t, verify.RequestVerification(user.ID), "Request verification")
+			tokens = append(tokens, lastToken(t, mailer, user.Email))
+		}
+
+		token := rapid.SampledFrom(tokens).Draw(t, "token")
+		verified, err := verify.VerifyEmail(token)
+		helpers.AssertNoError(t, err, "Verify email")
+		if !verified.EmailVerified {
+			t.Fatal("User should be verified")
+		}
+
+		retrieved, err := users.GetUser(user.ID)
+		helpers.AssertNoError(t, err, "GetUser after verification")
+		if !retrieved.EmailVerified {
+			t.Fatal("Verification should persist")
+		}
+
+		_, err = verify.VerifyEmail(token)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidToken, "Reused token")
+
+		err = verify.RequestVerification(user.ID)
+		helpers.AssertErrorIs(t, err, domain.ErrEmailAlreadyVerified, "Request after verification")
+	})
+}
+
+// TestProperty_UserVerification_ExpiredTokenRejected
+// Invariante: Un token caducado no verifica y el usuario sigue sin verificar
+// Relación: now ≥ emitido + TTL ⟹ Verify(token) == ErrTokenExpired; now < emitido + TTL ⟹ éxito;
+// la siguiente solicitud purga los caducados ⟹ Verify(token) == ErrInvalidToken ∧ no queda ninguno que purgar
+// Bordes: Justo antes del vencimiento, exactamente en el vencimiento, mucho después
+func TestProperty_UserVerification_ExpiredTokenRejected(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		ttl := time.Duration(rapid.IntRange(1, 48).Draw(t, "ttl_hours")) * time.Hour
+		repo := repository.NewInMemoryUserRepository()
+		tokens := repository.NewInMemoryVerificationTokenRepository()
+		mailer := mail.NewInMemoryMailer()
+		users := service.NewUserService(repo)
+		verify := service.NewVerificationService(repo, tokens, mailer,
+			service.VerificationConfig{TokenTTL: ttl, Now: func() time.Time { return now }})
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := users.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		helpers.AssertNoError(t, verify.RequestVerification(user.ID), "Request verification")
+		token := lastToken(t, mailer, user.Email)
+
+		elapsed := time.Duration(rapid.Int64Range(0, int64(3*ttl)).Draw(t, "elapsed"))
+		now = now.Add(elapsed)
+
+		_, err = verify.VerifyEmail(token)
+		if elapsed < ttl {
+			helpers.AssertNoError(t, err, "Token within TTL")
+			return
+		}
+		helpers.AssertErrorIs(t, err, domain.ErrTokenExpired, "Expired token")
+
+		retrieved, err := users.GetUser(user.ID)
+		helpers.AssertNoError(t, err, "GetUser")
+		if retrieved.EmailVerified {
+			t.Fatal("Expired token must not verify the user")
+		}
+
+		other := generators.ValidUserStruct().Filter(func(d generators.ValidUserData) bool {
+			return !strings.EqualFold(strings.TrimSpace(d.Email), user.Email)
+		}).Draw(t, "other_user")
+		next, err := users.CreateUser(other.Name, other.Email, other.Age)
+		helpers.AssertNoError(t, err, "Create other user")
+		helpers.AssertNoError(t, verify.RequestVerification(next.ID), "Request verification after expiry")
+		_, err = verify.VerifyEmail(token)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidToken, "Purged token")
+		if removed := tokens.Purge(now); removed != 0 {
+			t.Fatalf("RequestVerification left %d expired tokens behind", removed)
+		}
+	})
+}
+
+// TestProperty_UserVerification_EmailChangeResetsVerification
+// Invariante: Cambiar el email anula la verificación y los tokens emitidos para el email anterior
+// Relación: UpdateUser/PatchUser(email nuevo) ⟹ ¬EmailVerified; mismo email ⟹ se conserva
+// Bordes: Update con mismo email (en mayúsculas), Patch solo de edad, token viejo tras el cambio
+func TestProperty_UserVerification_EmailChangeResetsVerification(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		mailer := mail.NewInMemoryMailer()
+		users := service.NewUserService(repo)
+		verify := service.NewVerificationService(repo, repository.NewInMemoryVerificationTokenRepository(), mailer, service.DefaultVerificationConfig)
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := users.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		helpers.AssertNoError(t, verify.RequestVerification(user.ID), "Request verification")
+		redeemed := lastToken(t, mailer, user.Email)
+		helpers.AssertNoError(t, verify.RequestVerification(user.ID), "Request second token")
+		staleToken := lastToken(t, mailer, user.Email)
+		_, err = verify.VerifyEmail(redeemed)
+		helpers.AssertNoError(t, err, "Verify email")
+
+		age := generators.ValidAge().Draw(t, "age")
+		kept, err := users.PatchUser(user.ID, domain.UserPatch{Age: &age})
+		helpers.AssertNoError(t, err, "Patch age")
+		kept, err = users.UpdateUser(user.ID, kept.Name, strings.ToUpper(kept.Email), kept.Age)
+		helpers.AssertNoError(t, err, "Update with same email")
+		if !kept.EmailVerified {
+			t.Fatal("Keeping the email must keep verification")
+		}
+
+		newEmail := generators.ValidEmail().Filter(func(e string) bool {
+			return !strings.EqualFold(strings.TrimSpace(e), kept.Email)
+		}).Draw(t, "new_email")
+		var changed *domain.User
+		if rapid.Bool().Draw(t, "use_patch") {
+			changed, err = users.PatchUser(user.ID, domain.UserPatch{Email: &newEmail})
+		} else {
+			changed, err = users.UpdateUser(user.ID, kept.Name, newEmail, kept.Age)
+		}
+		helpers.AssertNoError(t, err, "Change email")
+		if changed.EmailVerified {
+			t.Fatal("Changing the email must reset verification")
+		}
+
+		_, err = verify.VerifyEmail(staleToken)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidToken, "Token issued for the previous email")
+
+		retrieved, err := users.GetUser(user.ID)
+		helpers.AssertNoError(t, err, "GetUser")
+		if retrieved.EmailVerified {
+			t.Fatal("Stale token must not verify the new email")
+		}
+	})
+}
+
+// TestProperty_UserVerification_UnknownTokenRejected
+// Invariante: Solo los tokens emitidos por el servicio son canjeables
+// Relación: token ∉ emitidos ⟹ Verify(token) == ErrInvalidToken
+// Bordes: Cadena vacía, token con formato válido pero aleatorio, token de usuario eliminado
+func TestProperty_UserVerification_UnknownTokenRejected(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		mailer := mail.NewInMemoryMailer()
+		users := service.NewUserService(repo)
+		verify := service.NewVerificationService(repo, repository.NewInMemoryVerificationTokenRepository(), mailer, service.DefaultVerificationConfig)
+
+		forged := rapid.OneOf(rapid.Just(""), rapid.StringMatching(`[A-Za-z0-9_-]{43}`)).Draw(t, "forged")
+		_, err := verify.VerifyEmail(forged)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidToken, "Forged token")
+
+		data := generators.ValidUserStruct().Draw(t, "user_data")
+		user, err := users.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create user")
+		helpers.AssertNoError(t, verify.RequestVerification(user.ID), "Request verification")
+		token := lastToken(t, mailer, user.Email)
+		helpers.AssertNoError(t, users.DeleteUser(user.ID), "Delete user")
+
+		_, err = verify.VerifyEmail(token)
+		helpers.AssertErrorIs(t, err, domain.ErrInvalidToken, "Token of deleted user")
+	})
+}
+
+// TestProperty_UserVerification_FileMailerRecordsMessages
+// Invariante: El mailer de archivo conserva cada mensaje enviado, en orden
+// Relación: Send(m1..mn) ⟹ archivo contiene To/Subject/Body de cada mi en orden
+// Bordes: Un solo mensaje, varios mensajes al mismo archivo
+func TestProperty_UserVerification_FileMailerRecordsMessages(t *testing.T) {
+	dir := t.TempDir()
+	run := 0
+
+	rapid.Check(t, func(t *rapid.T) {
+		run++
+		path := filepath.Join(dir, fmt.Sprintf("outbox-%d.txt", run))
+		mailer := mail.NewFileMailer(path)
+
+		count := rapid.IntRange(1, 5).Draw(t, "count")
+		var sent []mail.Message
+		for i := 0; i < count; i++ {
+			msg := mail.Message{
+				To:      generators.ValidEmail().Draw(t, "to"),
+				Subject: service.VerificationSubject,
+				Body:    rapid.StringMatching(`[A-Za-z0-9_-]{43}`).Draw(t, "body"),
+			}
+			helpers.AssertNoError(t, mailer.Send(msg), "Send")
+			sent = append(sent, msg)
+		}
+
+		content, err := os.ReadFile(path)
+		helpers.AssertNoError(t, err, "Read outbox")
+
+		rest := string(content)
+		for _, msg := range sent {
+			for _, part := range []string{"To: " + msg.To, "Subject: " + msg.Subject, msg.Body} {
+				idx := strings.Index(rest, part)
+				if idx < 0 {
+					t.Fatalf("Outbox missing %q in order", part)
+				}
+				rest = rest[idx+len(part):]
+			}
+		}
+	})
+}
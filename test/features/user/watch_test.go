@@ -0,0 +1,421 @@
+package user_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/auth"
+	"property-based/internal/client/httpclient"
+	"property-based/internal/domain"
+	"property-based/internal/repository"
+	"property-based/internal/service"
+	"property-based/internal/transport/httpserver"
+	"property-based/test/generators"
+	"property-based/test/helpers"
+)
+
+// watchTimeout acota la espera de cada cambio para que un fallo no cuelgue la suite
+const watchTimeout = 5 * time.Second
+
+// nextChange lee el siguiente cambio de sub o falla si el canal se cierra o tarda demasiado
+func nextChange(t *rapid.T, sub *repository.Subscription) domain.UserChange {
+	t.Helper()
+	select {
+	case change, ok := <-sub.C():
+		if !ok {
+			t.Fatalf("Subscription closed early: %v", sub.Err())
+		}
+		return change
+	case <-time.After(watchTimeout):
+		t.Fatal("Timed out waiting for a change")
+	}
+	return domain.UserChange{}
+}
+
+// collectChanges lee cambios hasta alcanzar head
+func collectChanges(t *rapid.T, sub *repository.Subscription, head uint64) []domain.UserChange {
+	t.Helper()
+	var changes []domain.UserChange
+	for len(changes) == 0 || changes[len(changes)-1].Seq < head {
+		changes = append(changes, nextChange(t, sub))
+	}
+	return changes
+}
+
+// replayChanges reconstruye el contenido del repositorio aplicando los cambios en orden
+func replayChanges(changes []domain.UserChange) map[string]storedUser {
+	state := map[string]storedUser{}
+	for _, c := range changes {
+		if c.Op == domain.ChangeDeleted {
+			delete(state, c.User.ID)
+			continue
+		}
+		state[c.User.ID] = storedUser{Name: c.User.Name, Email: c.User.Email, Age: c.User.Age}
+	}
+	return state
+}
+
+// TestProperty_UserWatch_FeedReplaysToCurrentState
+// Invariante: El feed contiene cada escritura confirmada, en orden y sin huecos
+// Relación: seq = 1..Head consecutivos ∧ replay(cambios) == GetAll ∧ #cambios == #escrituras con éxito
+// Bordes: Escrituras fallidas (no publican), PatchUser sin cambios, borrar y recrear el mismo email
+func TestProperty_UserWatch_FeedReplaysToCurrentState(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		sub, err := repo.Watch(repo.Head())
+		helpers.AssertNoError(t, err, "Watch")
+		defer sub.Close()
+
+		populateRepository(t, repo)
+		if repo.Head() == 0 {
+			return
+		}
+
+		changes := collectChanges(t, sub, repo.Head())
+		for i, c := range changes {
+			if c.Seq != uint64(i+1) {
+				t.Fatalf("Change %d has seq %d", i, c.Seq)
+			}
+		}
+		if !sameSnapshot(replayChanges(changes), snapshotUsers(t, repo)) {
+			t.Fatal("Replaying the feed does not reproduce the repository")
+		}
+	})
+}
+
+// TestProperty_UserWatch_ResumeFromSequence
+// Invariante: Reanudar desde k entrega exactamente los cambios posteriores a k, o falla si ya no están
+// Relación: base ≤ k ≤ Head ⟹ Watch(k) == cambios[k+1..Head]; k < base ∨ k > Head ∨ tras Restore ⟹ ErrChangesUnavailable
+// Bordes: k = 0, k = Head (solo cambios nuevos), retención menor que el historial, Restore con suscriptores vivos
+func TestProperty_UserWatch_ResumeFromSequence(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		retention := rapid.IntRange(1, 40).Draw(t, "retention")
+		repo := repository.NewInMemoryUserRepository(repository.WithChangeRetention(retention))
+		full, err := repo.Watch(0)
+		helpers.AssertNoError(t, err, "Watch from the start")
+		defer full.Close()
+
+		svc := service.NewUserService(repo)
+		writes := rapid.IntRange(1, 2*retention).Draw(t, "writes")
+		var all []domain.UserChange
+		for i := 0; i < writes; i++ {
+			_, err := svc.CreateUser(generators.ValidName().Draw(t, "name"), fmt.Sprintf("resume%d@example.com", i), 30)
+			helpers.AssertNoError(t, err, "Create user")
+			// Leer al ritmo de las escrituras evita que la suscripción completa se quede atrás
+			all = append(all, nextChange(t, full))
+		}
+
+		head := repo.Head()
+		base := uint64(max(0, writes-retention))
+		k := rapid.Uint64Range(0, head+2).Draw(t, "after")
+		resumed, err := repo.Watch(k)
+		if k < base || k > head {
+			helpers.AssertErrorIs(t, err, domain.ErrChangesUnavailable, "Cursor outside the retained window")
+		} else {
+			helpers.AssertNoError(t, err, "Resume within the retained window")
+			if k < head {
+				got := collectChanges(t, resumed, head)
+				if len(got) != int(head-k) {
+					t.Fatalf("Resuming after %d delivered %d changes, expected %d", k, len(got), head-k)
+				}
+				for i, c := range got {
+					if want := all[int(k)+i]; c.Seq != want.Seq || c.Op != want.Op || c.User.Email != want.User.Email {
+						t.Fatalf("Resumed change %d: expected %+v, got %+v", i, want, c)
+					}
+				}
+			}
+			resumed.Close()
+		}
+
+		helpers.AssertNoError(t, repo.Restore(repo.Snapshot()), "Restore")
+		select {
+		case _, ok := <-full.C():
+			if ok {
+				t.Fatal("No change should be published by Restore")
+			}
+		case <-time.After(watchTimeout):
+			t.Fatal("Restore must end live subscriptions")
+		}
+		helpers.AssertErrorIs(t, full.Err(), domain.ErrChangesUnavailable, "Subscription ended by Restore")
+		_, err = repo.Watch(head)
+		helpers.AssertErrorIs(t, err, domain.ErrChangesUnavailable, "Cursor issued before Restore")
+	})
+}
+
+// TestProperty_UserWatch_SlowConsumerIsDropped
+// Invariante: Un suscriptor que no lee nunca bloquea a los escritores y se desconecta al quedarse atrás
+// Relación: escrituras > 2·retención sin leer ⟹ escritores terminan ∧ C se cierra con ErrChangesUnavailable tras un prefijo ordenado
+// Bordes: Retención 1, escrituras justo por encima de 2·retención
+func TestProperty_UserWatch_SlowConsumerIsDropped(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		retention := rapid.IntRange(1, 16).Draw(t, "retention")
+		repo := repository.NewInMemoryUserRepository(repository.WithChangeRetention(retention))
+		svc := service.NewUserService(repo)
+		sub, err := repo.Watch(0)
+		helpers.AssertNoError(t, err, "Watch")
+		defer sub.Close()
+
+		writes := 2*retention + 1 + rapid.IntRange(0, 10).Draw(t, "extra")
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < writes; i++ {
+				_, _ = svc.CreateUser("Slow Reader", fmt.Sprintf("slow%d@example.com", i), 30)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(watchTimeout):
+			t.Fatal("Writers blocked on a subscriber that does not read")
+		}
+
+		var last uint64
+		for {
+			select {
+			case c, ok := <-sub.C():
+				if !ok {
+					helpers.AssertErrorIs(t, sub.Err(), domain.ErrChangesUnavailable, "Dropped subscriber")
+					return
+				}
+				if c.Seq != last+1 {
+					t.Fatalf("Expected seq %d, got %d", last+1, c.Seq)
+				}
+				last = c.Seq
+			case <-time.After(watchTimeout):
+				t.Fatal("Slow subscriber was never dropped")
+			}
+		}
+	})
+}
+
+// sseEvent es un evento Server-Sent Events ya separado en campos
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// readEvents envía a out cada evento leído de r, ignorando comentarios
+func readEvents(r io.Reader, out chan<- sseEvent) {
+	defer close(out)
+	scanner := bufio.NewScanner(r)
+	var ev sseEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if ev.event != "" {
+				out <- ev
+			}
+			ev = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// openStream abre GET /users/changes con el cursor dado en la cabecera o en la query
+func openStream(t *rapid.T, baseURL, cursor string, header bool) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/users/changes", nil)
+	helpers.AssertNoError(t, err, "Build stream request")
+	if cursor != "" {
+		if header {
+			req.Header.Set(httpserver.LastEventIDHeader, cursor)
+		} else {
+			req.URL.RawQuery = "after=" + cursor
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	helpers.AssertNoError(t, err, "Open stream")
+	return resp
+}
+
+// TestProperty_UserWatch_ServerSentEventsMatchFeed
+// Invariante: El stream SSE entrega el mismo feed que Watch, con id = seq, y cumple el documento OpenAPI
+// Relación: eventos(after=k) == repo.Watch(k) ∧ data ⊨ ChangeEvent; cursor caducado ⟹ 410; cursor no numérico ⟹ 400
+// Bordes: Reanudar por Last-Event-ID o por ?after=, stream sin cursor (solo cambios nuevos)
+func TestProperty_UserWatch_ServerSentEventsMatchFeed(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository(repository.WithChangeRetention(8))
+		server := httptest.NewServer(httpserver.NewHandler(service.NewUserService(repo), httpserver.WithChangeFeed(repo)))
+		defer server.Close()
+		client := httpclient.New(server.URL, noRetry)
+		doc := fetchOpenAPI(t, server.URL)
+		schema := doc["paths"].(map[string]any)["/users/changes"].(map[string]any)["get"].(map[string]any)["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)["text/event-stream"].(map[string]any)["schema"].(map[string]any)
+
+		live := openStream(t, server.URL, "", false)
+		defer live.Body.Close()
+		if live.StatusCode != http.StatusOK || live.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("Expected an event stream, got %d %q", live.StatusCode, live.Header.Get("Content-Type"))
+		}
+		events := make(chan sseEvent, 16)
+		go readEvents(live.Body, events)
+
+		writes := rapid.IntRange(1, 6).Draw(t, "writes")
+		var ids []string
+		for i := 0; i < writes; i++ {
+			if len(ids) > 0 && rapid.Bool().Draw(t, "delete") {
+				helpers.AssertNoError(t, client.DeleteUser(ids[len(ids)-1]), "Delete over HTTP")
+				ids = ids[:len(ids)-1]
+				continue
+			}
+			user, err := client.CreateUser(generators.ValidName().Draw(t, "name"), fmt.Sprintf("sse%d@example.com", i), 30)
+			helpers.AssertNoError(t, err, "Create over HTTP")
+			ids = append(ids, user.ID)
+		}
+
+		sub, err := repo.Watch(0)
+		helpers.AssertNoError(t, err, "Watch")
+		defer sub.Close()
+		expected := collectChanges(t, sub, repo.Head())
+		for _, want := range expected {
+			var ev sseEvent
+			select {
+			case ev = <-events:
+			case <-time.After(watchTimeout):
+				t.Fatalf("Timed out waiting for event %d", want.Seq)
+			}
+			var body any
+			helpers.AssertNoError(t, json.Unmarshal([]byte(ev.data), &body), "Decode event data")
+			if err := checkSchema(doc, schema, body, "data"); err != nil {
+				t.Fatalf("Event data does not match the document: %v", err)
+			}
+			var got httpserver.ChangeEvent
+			helpers.AssertNoError(t, json.Unmarshal([]byte(ev.data), &got), "Decode ChangeEvent")
+			if ev.id != fmt.Sprint(want.Seq) || ev.event != string(want.Op) || got.Seq != want.Seq || got.User.ID != want.User.ID {
+				t.Fatalf("Expected change %+v, got event %+v", want, ev)
+			}
+		}
+
+		head := repo.Head()
+		k := rapid.Uint64Range(head-uint64(min(int(head), 8)), head).Draw(t, "after")
+		resumed := openStream(t, server.URL, fmt.Sprint(k), rapid.Bool().Draw(t, "header"))
+		if resumed.StatusCode != http.StatusOK {
+			t.Fatalf("Resuming after %d: expected 200, got %d", k, resumed.StatusCode)
+		}
+		if k < head {
+			replayed := make(chan sseEvent, 16)
+			go readEvents(resumed.Body, replayed)
+			select {
+			case ev := <-replayed:
+				if ev.id != fmt.Sprint(k+1) {
+					t.Fatalf("Resuming after %d started at event %s", k, ev.id)
+				}
+			case <-time.After(watchTimeout):
+				t.Fatalf("Timed out waiting for the first event after %d", k)
+			}
+		}
+		resumed.Body.Close()
+
+		for cursor, status := range map[string]int{"not-a-seq": http.StatusBadRequest, fmt.Sprint(head + 1): http.StatusGone} {
+			resp := openStream(t, server.URL, cursor, rapid.Bool().Draw(t, "header"))
+			var body httpserver.ErrorResponse
+			helpers.AssertNoError(t, json.NewDecoder(resp.Body).Decode(&body), "Decode error body")
+			resp.Body.Close()
+			if resp.StatusCode != status {
+				t.Fatalf("Cursor %q: expected %d, got %d", cursor, status, resp.StatusCode)
+			}
+			if status == http.StatusGone && !errors.Is(domain.ErrorForCode(body.Code), domain.ErrChangesUnavailable) {
+				t.Fatalf("Expected code %s, got %s", domain.CodeChangesGone, body.Code)
+			}
+		}
+	})
+}
+
+// TestProperty_UserWatch_StreamRequiresListPermission
+// Invariante: El stream de cambios solo se abre a quien puede listar usuarios, y muestra lo mismo que una lectura
+// Relación: token inválido ⟹ 401; rol user ⟹ 403; admin/auditor ⟹ 200 ∧ evento.user == GET /users/{id}
+// Bordes: Sin cabecera Authorization, usuario sobre su propio registro, auditor (solo lectura)
+func TestProperty_UserWatch_StreamRequiresListPermission(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		repo := repository.NewInMemoryUserRepository()
+		authenticator := auth.NewTokenAuthenticator()
+		authenticator.Register("admin-token", auth.Principal{Role: auth.RoleAdmin})
+		server := httptest.NewServer(httpserver.NewHandler(service.NewUserService(repo),
+			httpserver.WithAuthenticator(authenticator),
+			httpserver.WithChangeFeed(repo),
+		))
+		defer server.Close()
+
+		admin := httpclient.New(server.URL, httpclient.Config{Sleep: noRetry.Sleep, Token: "admin-token"})
+		data := generators.ValidUserStruct().Draw(t, "self")
+		self, err := admin.CreateUser(data.Name, data.Email, data.Age)
+		helpers.AssertNoError(t, err, "Create self")
+
+		role := rapid.SampledFrom([]auth.Role{auth.RoleAdmin, auth.RoleAuditor, auth.RoleUser, ""}).Draw(t, "role")
+		token := ""
+		if role != "" {
+			token = "token-" + string(role)
+			authenticator.Register(token, auth.Principal{UserID: self.ID, Role: role})
+		}
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/users/changes", nil)
+		helpers.AssertNoError(t, err, "Build stream request")
+		if token != "" {
+			req.Header.Set(httpserver.AuthorizationHeader, "Bearer "+token)
+		}
+		stream, err := http.DefaultClient.Do(req)
+		helpers.AssertNoError(t, err, "Open stream")
+		defer stream.Body.Close()
+
+		switch role {
+		case "":
+			if stream.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("Stream without a token: expected 401, got %d", stream.StatusCode)
+			}
+			return
+		case auth.RoleUser:
+			if stream.StatusCode != http.StatusForbidden {
+				t.Fatalf("Stream for role user: expected 403, got %d", stream.StatusCode)
+			}
+			return
+		}
+		if stream.StatusCode != http.StatusOK {
+			t.Fatalf("Stream for role %s: expected 200, got %d", role, stream.StatusCode)
+		}
+		events := make(chan sseEvent, 4)
+		go readEvents(stream.Body, events)
+
+		age := generators.ValidAge().Filter(func(age int) bool { return age != self.Age }).Draw(t, "age")
+		_, err = admin.PatchUser(self.ID, domain.UserPatch{Age: &age})
+		helpers.AssertNoError(t, err, "Patch self")
+
+		var ev sseEvent
+		select {
+		case ev = <-events:
+		case <-time.After(watchTimeout):
+			t.Fatalf("Timed out waiting for the update event")
+		}
+		var change struct {
+			User json.RawMessage `json:"user"`
+		}
+		helpers.AssertNoError(t, json.Unmarshal([]byte(ev.data), &change), "Decode event")
+
+		req, err = http.NewRequest(http.MethodGet, server.URL+"/users/"+self.ID, nil)
+		helpers.AssertNoError(t, err, "Build read request")
+		req.Header.Set(httpserver.AuthorizationHeader, "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		helpers.AssertNoError(t, err, "Read self")
+		read, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		helpers.AssertNoError(t, err, "Read body")
+		if strings.TrimSpace(string(read)) != string(change.User) {
+			t.Fatalf("Event shows %s, a read shows %s", change.User, read)
+		}
+	})
+}
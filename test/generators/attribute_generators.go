@@ -0,0 +1,151 @@
+package generators
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+)
+
+var attributeTypes = []domain.AttributeType{
+	domain.AttributeString, domain.AttributeNumber, domain.AttributeBool, domain.AttributeDate,
+}
+
+// AttributeSchema genera esquemas de 1 a 5 atributos con nombres y tipos aleatorios
+func AttributeSchema() *rapid.Generator[domain.AttributeSchema] {
+	return rapid.Custom(func(t *rapid.T) domain.AttributeSchema {
+		names := rapid.SliceOfNDistinct(AttributeName(), 1, 5, rapid.ID[string]).Draw(t, "names")
+		schema := make(domain.AttributeSchema, len(names))
+		for _, name := range names {
+			schema[name] = rapid.SampledFrom(attributeTypes).Draw(t, "type_"+name)
+		}
+		return schema
+	})
+}
+
+// AttributeName genera nombres de atributo válidos
+func AttributeName() *rapid.Generator[string] {
+	return rapid.StringMatching(`[a-z][a-z0-9_]{0,11}`)
+}
+
+// AttributeValueOf genera valores del tipo typ, con vacíos, negativos, cero y
+// fechas en los extremos del siglo como casos borde
+func AttributeValueOf(typ domain.AttributeType) *rapid.Generator[domain.AttributeValue] {
+	return rapid.Custom(func(t *rapid.T) domain.AttributeValue {
+		switch typ {
+		case domain.AttributeNumber:
+			n := rapid.OneOf(
+				rapid.Float64Range(-1e6, 1e6),
+				rapid.Float64().Filter(func(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }),
+				rapid.Just(0.0),
+			).Draw(t, "number")
+			return domain.NumberAttribute(n)
+		case domain.AttributeBool:
+			return domain.BoolAttribute(rapid.Bool().Draw(t, "bool"))
+		case domain.AttributeDate:
+			day := rapid.IntRange(0, 200*366).Draw(t, "day")
+			return domain.DateAttribute(time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, day))
+		}
+		return domain.StringAttribute(rapid.OneOf(rapid.Just(""), rapid.StringN(1, 20, -1)).Draw(t, "string"))
+	})
+}
+
+// AttributesFor genera un subconjunto aleatorio de los atributos declarados en
+// schema, cada uno con un valor de su tipo
+func AttributesFor(schema domain.AttributeSchema) *rapid.Generator[domain.Attributes] {
+	return rapid.Custom(func(t *rapid.T) domain.Attributes {
+		attrs := domain.Attributes{}
+		for _, name := range schemaNames(schema) {
+			if rapid.Bool().Draw(t, "has_"+name) {
+				attrs[name] = AttributeValueOf(schema[name]).Draw(t, "value_"+name)
+			}
+		}
+		return attrs
+	})
+}
+
+// ValidAttributePatchFor genera parches de atributos VÁLIDOS para schema:
+// cada atributo declarado se fija, se borra (nil) o no se toca
+func ValidAttributePatchFor(schema domain.AttributeSchema) *rapid.Generator[domain.UserPatch] {
+	return rapid.Custom(func(t *rapid.T) domain.UserPatch {
+		patch := domain.UserPatch{Attributes: map[string]*domain.AttributeValue{}}
+		for _, name := range schemaNames(schema) {
+			switch rapid.IntRange(0, 2).Draw(t, "action_"+name) {
+			case 1: // Fijar
+				v := AttributeValueOf(schema[name]).Draw(t, "value_"+name)
+				patch.Attributes[name] = &v
+			case 2: // Borrar
+				patch.Attributes[name] = nil
+			}
+		}
+		return patch
+	})
+}
+
+// InvalidAttributeData son atributos con exactamente un atributo INVÁLIDO
+type InvalidAttributeData struct {
+	Attributes domain.Attributes
+	Name       string
+	Undeclared bool // true: no está en el esquema; false: tiene otro tipo
+}
+
+// InvalidAttributesFor genera atributos válidos para schema salvo uno, que no
+// está declarado o cuyo valor es de un tipo distinto al declarado
+func InvalidAttributesFor(schema domain.AttributeSchema) *rapid.Generator[InvalidAttributeData] {
+	return rapid.Custom(func(t *rapid.T) InvalidAttributeData {
+		data := InvalidAttributeData{
+			Attributes: AttributesFor(schema).Draw(t, "valid_attributes"),
+			Undeclared: rapid.Bool().Draw(t, "undeclared"),
+		}
+		if data.Undeclared {
+			data.Name = AttributeName().Filter(func(name string) bool {
+				_, declared := schema[name]
+				return !declared
+			}).Draw(t, "undeclared_name")
+			typ := rapid.SampledFrom(attributeTypes).Draw(t, "undeclared_type")
+			data.Attributes[data.Name] = AttributeValueOf(typ).Draw(t, "undeclared_value")
+			return data
+		}
+
+		data.Name = rapid.SampledFrom(schemaNames(schema)).Draw(t, "mistyped_name")
+		typ := rapid.SampledFrom(attributeTypes).Filter(func(typ domain.AttributeType) bool {
+			return typ != schema[data.Name]
+		}).Draw(t, "mistyped_type")
+		data.Attributes[data.Name] = AttributeValueOf(typ).Draw(t, "mistyped_value")
+		return data
+	})
+}
+
+// AttributeFilterFor genera filtros sobre atributos de schema (o uno no
+// declarado) con cualquier operador; el valor suele ser del tipo declarado y a
+// veces de otro, para cubrir comparaciones imposibles
+func AttributeFilterFor(schema domain.AttributeSchema) *rapid.Generator[domain.AttributeFilter] {
+	return rapid.Custom(func(t *rapid.T) domain.AttributeFilter {
+		name := rapid.OneOf(rapid.SampledFrom(schemaNames(schema)), AttributeName()).Draw(t, "filter_name")
+		f := domain.AttributeFilter{
+			Name: name,
+			Op: rapid.SampledFrom([]domain.FilterOp{
+				domain.FilterExists, domain.FilterEqual, domain.FilterNotEqual,
+				domain.FilterLess, domain.FilterLessEqual, domain.FilterGreater, domain.FilterGreaterEqual,
+			}).Draw(t, "filter_op"),
+		}
+		if f.Op == domain.FilterExists {
+			return f
+		}
+		typ, declared := schema[name]
+		if !declared || rapid.IntRange(0, 4).Draw(t, "other_type") == 0 {
+			typ = rapid.SampledFrom(attributeTypes).Draw(t, "filter_type")
+		}
+		f.Value = AttributeValueOf(typ).Draw(t, "filter_value").String()
+		return f
+	})
+}
+
+// schemaNames ordena los nombres para que los draws sean reproducibles
+func schemaNames(schema domain.AttributeSchema) []string {
+	return slices.Sorted(maps.Keys(schema))
+}
@@ -0,0 +1,25 @@
+package generators
+
+import (
+	"pgregory.net/rapid"
+)
+
+// ValidPassword genera contraseñas que cumplen la política (8-128, letra y dígito)
+func ValidPassword() *rapid.Generator[string] {
+	return rapid.Custom(func(t *rapid.T) string {
+		letters := rapid.StringMatching(`[a-zA-Z]{1,20}`).Draw(t, "letters")
+		digits := rapid.StringMatching(`[0-9]{1,10}`).Draw(t, "digits")
+		filler := rapid.StringMatching(`[a-zA-Z0-9!@#$%^&*_ -]{6,40}`).Draw(t, "filler")
+		return letters + filler + digits
+	})
+}
+
+// InvalidPassword genera contraseñas que la política debe rechazar
+func InvalidPassword() *rapid.Generator[string] {
+	return rapid.OneOf(
+		rapid.StringMatching(`[a-zA-Z0-9]{0,7}`),         // Muy corta
+		rapid.StringMatching(`[a-zA-Z!@#]{8,30}`),        // Sin dígitos
+		rapid.StringMatching(`[0-9!@#]{8,30}`),           // Sin letras
+		rapid.StringMatching(`[a-z][0-9][a-z]{127,140}`), // Muy larga
+	)
+}
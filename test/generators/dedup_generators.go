@@ -0,0 +1,53 @@
+package generators
+
+import (
+	"strings"
+
+	"pgregory.net/rapid"
+)
+
+// GmailAddress genera direcciones de gmail.com, donde los puntos de la parte local no cuentan
+func GmailAddress() *rapid.Generator[string] {
+	return rapid.StringMatching(`[a-z]{2,8}(\.[a-z]{2,8})?@gmail\.com`)
+}
+
+// MailboxAlias genera otra dirección que entrega en el mismo buzón que email
+// (mayúsculas, sufijo +etiqueta y, en gmail, puntos y googlemail.com)
+func MailboxAlias(email string) *rapid.Generator[string] {
+	return rapid.Custom(func(t *rapid.T) string {
+		local, host, _ := strings.Cut(email, "@")
+		if host == "gmail.com" {
+			local = strings.ReplaceAll(local, ".", "")
+			if len(local) > 1 && rapid.Bool().Draw(t, "dot") {
+				i := rapid.IntRange(1, len(local)-1).Draw(t, "dot_at")
+				local = local[:i] + "." + local[i:]
+			}
+			host = rapid.SampledFrom([]string{"gmail.com", "googlemail.com"}).Draw(t, "host")
+		}
+		if rapid.Bool().Draw(t, "tag") {
+			local += "+" + rapid.StringMatching(`[a-z0-9]{1,8}`).Draw(t, "tag_value")
+		}
+		alias := local + "@" + host
+		if rapid.Bool().Draw(t, "upper") {
+			alias = strings.ToUpper(alias)
+		}
+		return alias
+	})
+}
+
+// NameTypo genera un nombre válido a distancia de edición 1 de name
+// (una letra sustituida o insertada), como "Jon Doe" frente a "John Doe"
+func NameTypo(name string) *rapid.Generator[string] {
+	return rapid.Custom(func(t *rapid.T) string {
+		letter := rapid.StringMatching(`[a-z]`).Draw(t, "letter")
+		if len(name) < 50 && rapid.Bool().Draw(t, "insert") {
+			i := rapid.IntRange(0, len(name)).Draw(t, "insert_at")
+			return name[:i] + letter + name[i:]
+		}
+		i := rapid.IntRange(0, len(name)-1).Draw(t, "replace_at")
+		for strings.EqualFold(name[i:i+1], letter) {
+			letter = string(rune('a' + (letter[0]-'a'+1)%26))
+		}
+		return name[:i] + letter + name[i+1:]
+	})
+}
@@ -0,0 +1,51 @@
+package generators
+
+import (
+	"time"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/repository"
+)
+
+// Fault genera un fallo para una llamada al repositorio: sin fallo, error,
+// latencia o fallo parcial (la operación se aplica pero se reporta error)
+func Fault() *rapid.Generator[repository.Fault] {
+	return rapid.Custom(func(t *rapid.T) repository.Fault {
+		var fault repository.Fault
+		// Latencia pequeña para no ralentizar la suite
+		latency := func() time.Duration {
+			return time.Duration(rapid.IntRange(1, 200).Draw(t, "latency_us")) * time.Microsecond
+		}
+
+		switch rapid.IntRange(0, 3).Draw(t, "fault_kind") {
+		case 0: // Sin fallo
+			return fault
+		case 1: // Error antes de aplicar la operación
+			fault.Err = repository.ErrInjectedFault
+		case 2: // Error después de aplicar la operación
+			fault.Err = repository.ErrInjectedFault
+			fault.Partial = true
+		case 3: // Solo latencia
+			fault.Latency = latency()
+			return fault
+		}
+
+		// Los errores pueden llegar además con retraso
+		if rapid.Bool().Draw(t, "with_latency") {
+			fault.Latency = latency()
+		}
+		return fault
+	})
+}
+
+// FaultScript genera un guion de fallos por operación, consumido en orden
+func FaultScript() *rapid.Generator[map[repository.Operation][]repository.Fault] {
+	return rapid.Custom(func(t *rapid.T) map[repository.Operation][]repository.Fault {
+		script := make(map[repository.Operation][]repository.Fault)
+		for _, op := range repository.Operations {
+			script[op] = rapid.SliceOfN(Fault(), 0, 5).Draw(t, string(op))
+		}
+		return script
+	})
+}
@@ -0,0 +1,32 @@
+package generators
+
+import (
+	"strings"
+
+	"pgregory.net/rapid"
+)
+
+// IdempotencyKey genera claves como las que enviaría un cliente (UUID o token opaco)
+func IdempotencyKey() *rapid.Generator[string] {
+	return rapid.OneOf(
+		rapid.StringMatching(`[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}`),
+		rapid.StringMatching(`[A-Za-z0-9_-]{16,64}`),
+	)
+}
+
+// EquivalentUserData genera una variante de data que la validación normaliza a lo mismo
+// (espacios alrededor del nombre, email en otro caso y con espacios)
+func EquivalentUserData(data ValidUserData) *rapid.Generator[ValidUserData] {
+	return rapid.Custom(func(t *rapid.T) ValidUserData {
+		pad := rapid.SampledFrom([]string{"", " ", "  "})
+		email := data.Email
+		if rapid.Bool().Draw(t, "upper_email") {
+			email = strings.ToUpper(email)
+		}
+		return ValidUserData{
+			Name:  pad.Draw(t, "name_left") + data.Name + pad.Draw(t, "name_right"),
+			Email: pad.Draw(t, "email_left") + email + pad.Draw(t, "email_right"),
+			Age:   data.Age,
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package generators
+
+import (
+	"strings"
+
+	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
+)
+
+// Los generators de este archivo derivan los datos de una ValidationPolicy en
+// lugar de fijar las reglas, de modo que siguen siendo correctos cuando la
+// política activa cambia.
+
+// ValidationPolicy genera políticas coherentes que difieren de la por defecto
+// en longitudes, patrones y rango de edad
+func ValidationPolicy() *rapid.Generator[*domain.ValidationPolicy] {
+	return rapid.Custom(func(t *rapid.T) *domain.ValidationPolicy {
+		namePattern := rapid.SampledFrom([]string{
+			domain.DefaultValidationPolicy.NamePattern,
+			// Palabras capitalizadas: exige al menos 2 caracteres
+			`^[A-Z][a-z]+( [A-Z][a-z]+)*$`,
+		}).Draw(t, "name_pattern")
+		nameMin := rapid.IntRange(1, 8).Draw(t, "name_min_length")
+		if namePattern != domain.DefaultValidationPolicy.NamePattern {
+			// Que la longitud mínima anunciada sea alcanzable con el patrón
+			nameMin = max(nameMin, 2)
+		}
+		ageMin := rapid.IntRange(0, 21).Draw(t, "age_min")
+		policy, err := domain.NewValidationPolicy(domain.ValidationPolicy{
+			NameMinLength: nameMin,
+			NameMaxLength: nameMin + rapid.IntRange(8, 42).Draw(t, "name_extra_length"),
+			NamePattern:   namePattern,
+			EmailPattern: rapid.SampledFrom([]string{
+				domain.DefaultValidationPolicy.EmailPattern,
+				// Solo dominios corporativos
+				`^[a-z0-9._%+-]+@(example\.com|test\.org)$`,
+			}).Draw(t, "email_pattern"),
+			AgeMin: ageMin,
+			AgeMax: ageMin + rapid.IntRange(0, 150).Draw(t, "age_span"),
+		})
+		if err != nil {
+			t.Fatalf("Generated policy is invalid: %v", err)
+		}
+		return policy
+	})
+}
+
+// ValidUserStructFor genera datos VÁLIDOS según la política p
+func ValidUserStructFor(p *domain.ValidationPolicy) *rapid.Generator[ValidUserData] {
+	return rapid.Custom(func(t *rapid.T) ValidUserData {
+		return ValidUserData{
+			Name:  ValidNameFor(p).Draw(t, "name"),
+			Email: ValidEmailFor(p).Draw(t, "email"),
+			Age:   ValidAgeFor(p).Draw(t, "age"),
+		}
+	})
+}
+
+// InvalidUserStructFor genera datos INVÁLIDOS según la política p; CaseType
+// indica el único campo que la incumple, igual que en InvalidUserStruct
+func InvalidUserStructFor(p *domain.ValidationPolicy) *rapid.Generator[InvalidUserData] {
+	return rapid.Custom(func(t *rapid.T) InvalidUserData {
+		data := InvalidUserData{
+			Name:     ValidNameFor(p).Draw(t, "name"),
+			Email:    ValidEmailFor(p).Draw(t, "email"),
+			Age:      ValidAgeFor(p).Draw(t, "age"),
+			CaseType: rapid.IntRange(0, 2).Draw(t, "case_type"),
+		}
+
+		switch data.CaseType {
+		case 0: // Nombre inválido
+			data.Name = InvalidNameFor(p).Draw(t, "invalid_name")
+		case 1: // Email inválido
+			data.Email = InvalidEmailFor(p).Draw(t, "invalid_email")
+		case 2: // Edad inválida
+			data.Age = InvalidAgeFor(p).Draw(t, "invalid_age")
+		}
+		return data
+	})
+}
+
+// ValidNameFor genera nombres que cumplen el patrón y las longitudes de p:
+// primero los nombres conocidos que la política acepta y, si no hay, cadenas
+// derivadas del propio patrón
+func ValidNameFor(p *domain.ValidationPolicy) *rapid.Generator[string] {
+	accepted := acceptedBy(validNames, func(name string) bool { return p.CheckName(name) == nil })
+	fromPattern := rapid.StringMatching(p.NamePattern).Filter(func(name string) bool {
+		return name == strings.TrimSpace(name) && p.CheckName(name) == nil
+	})
+	if len(accepted) == 0 {
+		return fromPattern
+	}
+	return rapid.OneOf(rapid.SampledFrom(accepted), fromPattern)
+}
+
+// InvalidNameFor genera nombres que p rechaza DESPUÉS del trim: demasiado
+// cortos, demasiado largos o fuera del patrón
+func InvalidNameFor(p *domain.ValidationPolicy) *rapid.Generator[string] {
+	letters := rapid.RuneFrom([]rune("abcdefghijklmnopqrstuvwxyz"))
+	rejected := acceptedBy(append(append([]string(nil), invalidNames...), validNames...), func(name string) bool {
+		return p.CheckName(strings.TrimSpace(name)) != nil
+	})
+
+	gens := []*rapid.Generator[string]{
+		rapid.SampledFrom(rejected),
+		// Muy largo
+		rapid.StringOfN(letters, p.NameMaxLength+1, p.NameMaxLength+20, -1),
+	}
+	if p.NameMinLength > 1 {
+		// Muy corto
+		gens = append(gens, rapid.StringOfN(letters, 1, p.NameMinLength-1, -1))
+	}
+	return rapid.OneOf(gens...)
+}
+
+// ValidEmailFor genera emails que p acepta, únicos mientras la política
+// admita el formato de ValidEmail y derivados del patrón en caso contrario
+func ValidEmailFor(p *domain.ValidationPolicy) *rapid.Generator[string] {
+	return rapid.Custom(func(t *rapid.T) string {
+		email := ValidEmail().Draw(t, "email")
+		if p.CheckEmail(email) == nil {
+			return email
+		}
+		return rapid.StringMatching(p.EmailPattern).Filter(func(email string) bool {
+			return p.CheckEmail(strings.TrimSpace(strings.ToLower(email))) == nil
+		}).Draw(t, "pattern_email")
+	})
+}
+
+// InvalidEmailFor genera emails que p rechaza
+func InvalidEmailFor(p *domain.ValidationPolicy) *rapid.Generator[string] {
+	return rapid.SampledFrom(acceptedBy(invalidEmails, func(email string) bool {
+		return p.CheckEmail(strings.TrimSpace(strings.ToLower(email))) != nil
+	}))
+}
+
+// ValidAgeFor genera edades dentro de [AgeMin, AgeMax]
+func ValidAgeFor(p *domain.ValidationPolicy) *rapid.Generator[int] {
+	return rapid.IntRange(p.AgeMin, p.AgeMax)
+}
+
+// InvalidAgeFor genera edades fuera de [AgeMin, AgeMax], con los vecinos
+// inmediatos de cada límite como casos borde
+func InvalidAgeFor(p *domain.ValidationPolicy) *rapid.Generator[int] {
+	return rapid.OneOf(
+		rapid.Just(p.AgeMin-1),
+		rapid.Just(p.AgeMax+1),
+		rapid.IntRange(p.AgeMin-100, p.AgeMin-1), // Por debajo
+		rapid.IntRange(p.AgeMax+1, p.AgeMax+350), // Por encima
+	)
+}
+
+func acceptedBy(candidates []string, keep func(string) bool) []string {
+	var kept []string
+	for _, c := range candidates {
+		if keep(c) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"pgregory.net/rapid"
+
+	"property-based/internal/domain"
 )
 
 type ValidUserData struct {
@@ -66,57 +68,81 @@ func InvalidUserStruct() *rapid.Generator[InvalidUserData] {
 	})
 }
 
+// ValidUserPatch genera un parche VÁLIDO con un subconjunto aleatorio de campos
+func ValidUserPatch() *rapid.Generator[domain.UserPatch] {
+	return rapid.Custom(func(t *rapid.T) domain.UserPatch {
+		var patch domain.UserPatch
+		if rapid.Bool().Draw(t, "patch_name") {
+			name := ValidName().Draw(t, "name")
+			patch.Name = &name
+		}
+		if rapid.Bool().Draw(t, "patch_email") {
+			email := ValidEmail().Draw(t, "email")
+			patch.Email = &email
+		}
+		if rapid.Bool().Draw(t, "patch_age") {
+			age := ValidAge().Draw(t, "age")
+			patch.Age = &age
+		}
+		return patch
+	})
+}
+
 // ==================== GENERATORS ATÓMICOS ====================
 
 // ValidName genera nombres válidos (2-50 caracteres, sin espacios al inicio/final)
 func ValidName() *rapid.Generator[string] {
-	return rapid.SampledFrom([]string{
-		// Nombres de 2 caracteres (casos borde)
-		"AB", "CD", "Jo", "Al", "Bo", "Ed", "Li", "Ty", "Ma", "Lu",
-
-		// Nombres simples comunes
-		"John", "Jane", "Alice", "Bob", "Carlos", "Maria",
-		"Michael", "Jennifer", "Daniel", "Jessica", "David", "Sarah",
-		"Alexander", "Elizabeth", "Christopher", "Amanda",
-
-		// Nombres compuestos
-		"John Doe", "Jane Smith", "Alice Johnson", "Bob Wilson",
-		"Mary Jane", "Anna Maria", "John Paul", "Sarah Connor",
-		"James Bond", "Peter Parker", "Bruce Wayne", "Clark Kent",
-
-		// Nombres largos (cerca del límite de 50)
-		"Christopher Alexander Montgomery Wellington",
-	})
+	return rapid.SampledFrom(validNames)
+}
+
+var validNames = []string{
+	// Nombres de 2 caracteres (casos borde)
+	"AB", "CD", "Jo", "Al", "Bo", "Ed", "Li", "Ty", "Ma", "Lu",
+
+	// Nombres simples comunes
+	"John", "Jane", "Alice", "Bob", "Carlos", "Maria",
+	"Michael", "Jennifer", "Daniel", "Jessica", "David", "Sarah",
+	"Alexander", "Elizabeth", "Christopher", "Amanda",
+
+	// Nombres compuestos
+	"John Doe", "Jane Smith", "Alice Johnson", "Bob Wilson",
+	"Mary Jane", "Anna Maria", "John Paul", "Sarah Connor",
+	"James Bond", "Peter Parker", "Bruce Wayne", "Clark Kent",
+
+	// Nombres largos (cerca del límite de 50)
+	"Christopher Alexander Montgomery Wellington",
 }
 
 // InvalidName genera nombres REALMENTE inválidos (que fallan DESPUÉS del trim)
 func InvalidName() *rapid.Generator[string] {
-	return rapid.SampledFrom([]string{
-		// Vacío
-		"",
-
-		// Solo espacios (después del trim = vacío)
-		"   ",
-		"     ",
-
-		// Un solo carácter DESPUÉS del trim
-		"A",
-		"Z",
-
-		// Muy largo (> 50 caracteres)
-		"ABCDEFGHIJKLMNOPQRSTUVWXYZABCDEFGHIJKLMNOPQRSTUVWXYZ",
-		"Christopher Alexander Montgomery Wellington Johnson Smith",
-
-		// Caracteres no permitidos (números, símbolos)
-		"John123",
-		"Jane456",
-		"John@Doe",
-		"Jane#Smith",
-		"John_Doe",
-		"Jane-Smith",
-		"123John",
-		"@Alice",
-	})
+	return rapid.SampledFrom(invalidNames)
+}
+
+var invalidNames = []string{
+	// Vacío
+	"",
+
+	// Solo espacios (después del trim = vacío)
+	"   ",
+	"     ",
+
+	// Un solo carácter DESPUÉS del trim
+	"A",
+	"Z",
+
+	// Muy largo (> 50 caracteres)
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"Christopher Alexander Montgomery Wellington Johnson Smith",
+
+	// Caracteres no permitidos (números, símbolos)
+	"John123",
+	"Jane456",
+	"John@Doe",
+	"Jane#Smith",
+	"John_Doe",
+	"Jane-Smith",
+	"123John",
+	"@Alice",
 }
 
 // ValidEmail genera emails válidos con UNICIDAD garantizada
@@ -147,59 +173,57 @@ func ValidEmail() *rapid.Generator[string] {
 
 // InvalidEmail genera emails inválidos
 func InvalidEmail() *rapid.Generator[string] {
-	return rapid.SampledFrom([]string{
-		// Sin @
-		"invalidemail",
-		"userexample.com",
-
-		// Sin dominio
-		"user@",
-		"test@",
-
-		// Sin usuario
-		"@domain.com",
-		"@example.org",
-
-		// @ doble
-		"user@@domain.com",
-		"test@@example.org",
-
-		// Sin TLD
-		"user@domain",
-		"test@example",
-
-		// Vacío
-		"",
-
-		// Espacios
-		"user @domain.com",
-		"user@ domain.com",
-		"user@domain .com",
-
-		// Sin dominio después de @
-		"user@.com",
-		"test@.org",
-
-		// TLD vacío
-		"user@domain.",
-		"test@example.",
-
-		// Caracteres inválidos
-		"user name@domain.com",
-		"user@domain com",
-	})
+	return rapid.SampledFrom(invalidEmails)
+}
+
+var invalidEmails = []string{
+	// Sin @
+	"invalidemail",
+	"userexample.com",
+
+	// Sin dominio
+	"user@",
+	"test@",
+
+	// Sin usuario
+	"@domain.com",
+	"@example.org",
+
+	// @ doble
+	"user@@domain.com",
+	"test@@example.org",
+
+	// Sin TLD
+	"user@domain",
+	"test@example",
+
+	// Vacío
+	"",
+
+	// Espacios
+	"user @domain.com",
+	"user@ domain.com",
+	"user@domain .com",
+
+	// Sin dominio después de @
+	"user@.com",
+	"test@.org",
+
+	// TLD vacío
+	"user@domain.",
+	"test@example.",
+
+	// Caracteres inválidos
+	"user name@domain.com",
+	"user@domain com",
 }
 
-// ValidAge genera edades válidas (1-150)
+// ValidAge genera edades válidas según la política por defecto (1-150)
 func ValidAge() *rapid.Generator[int] {
-	return rapid.IntRange(1, 150)
+	return ValidAgeFor(domain.DefaultValidationPolicy)
 }
 
-// InvalidAge genera edades inválidas
+// InvalidAge genera edades inválidas según la política por defecto
 func InvalidAge() *rapid.Generator[int] {
-	return rapid.OneOf(
-		rapid.Just(0),            // Cero
-		rapid.IntRange(-100, -1), // Negativo
-		rapid.IntRange(151, 500), // Muy alto
-	)
+	return InvalidAgeFor(domain.DefaultValidationPolicy)
 }
@@ -1,6 +1,8 @@
 package helpers
 
 import (
+	"errors"
+
 	"property-based/internal/domain"
 )
 
@@ -26,6 +28,9 @@ func AssertUserEquals(t TestingT, expected, actual *domain.User, context string)
 	if actual.Age != expected.Age {
 		t.Fatalf("%s: Age mismatch - expected %d, got %d", context, expected.Age, actual.Age)
 	}
+	if !actual.Attributes.Equal(expected.Attributes) {
+		t.Fatalf("%s: Attributes mismatch - expected %v, got %v", context, expected.Attributes, actual.Attributes)
+	}
 }
 
 // AssertNoError verifica que no haya error
@@ -44,13 +49,13 @@ func AssertError(t TestingT, err error, context string) {
 	}
 }
 
-// AssertErrorIs verifica que el error sea de un tipo específico
+// AssertErrorIs verifica que el error sea (o envuelva) el centinela esperado
 func AssertErrorIs(t TestingT, err, expectedErr error, context string) {
 	t.Helper()
 	if err == nil {
 		t.Fatalf("%s: expected error %v, got nil", context, expectedErr)
 	}
-	if err != expectedErr {
+	if !errors.Is(err, expectedErr) {
 		t.Fatalf("%s: expected error %v, got %v", context, expectedErr, err)
 	}
 }